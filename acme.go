@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ACMECertificate pairs a domain with the certificate file the ACME client
+// renews for it.
+type ACMECertificate struct {
+	Domain string
+	Path   string
+}
+
+// ParseACMECertificate parses "<domain>:<certificate>", e.g.
+// "example.com:/etc/letsencrypt/live/example.com/cert.pem".
+func ParseACMECertificate(value string) (ACMECertificate, error) {
+	domain, path, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(domain) == "" || strings.TrimSpace(path) == "" {
+		return ACMECertificate{}, fmt.Errorf("expected <domain>:<certificate>")
+	}
+	return ACMECertificate{Domain: strings.TrimSpace(domain), Path: strings.TrimSpace(path)}, nil
+}
+
+// servedCertificate returns the raw leaf certificate served locally on
+// port 443 for the given server name.
+func servedCertificate(domain string) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", "127.0.0.1:443", &tls.Config{
+		ServerName: domain,
+		// The certificate is compared against the one on disk, not verified
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certificates := conn.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no certificate served for %s", domain)
+	}
+	return certificates[0].Raw, nil
+}
+
+// unitActive reports whether a systemd unit is loaded and active.
+func unitActive(ctx context.Context, unit string) (bool, string, error) {
+	output, err := runCommand(ctx, "systemctl", "show", "--property=LoadState", "--property=ActiveState", unit)
+	if err != nil {
+		return false, "", err
+	}
+
+	state := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			state[key] = value
+		}
+	}
+	if state["LoadState"] != "loaded" {
+		return false, state["LoadState"], nil
+	}
+	return state["ActiveState"] == "active", state["ActiveState"], nil
+}
+
+// checkACME verifies that renewal timers are active and that each served
+// certificate is the newest one on disk and not overdue for renewal.
+// ACME clients renew once a third of the lifetime is left, a certificate
+// with less than a quarter left means renewals are failing.
+func (s *SystemMonitor) checkACME(ctx context.Context) error {
+	for _, unit := range s.config.ACMETimers {
+		active, state, err := unitActive(ctx, unit)
+		if err != nil {
+			s.log.Error("Failed to get state of %s: %v", unit, err)
+			continue
+		}
+
+		status := "pass"
+		value := 1.0
+		if !active {
+			status = "fail"
+			value = 0
+			s.log.Warn("Renewal unit %s is %s", unit, state)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "acme",
+			Title:     fmt.Sprintf("Certificate Renewal %s - %s", unit, s.hostname),
+			Cause:     fmt.Sprintf("%s is %s", unit, state),
+			AlertID:   fmt.Sprintf("acme-unit-%s-%s", valueName(unit), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     1,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"unit": unit},
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, acme := range s.config.ACMECertificates {
+		certificate, err := readCertificate(acme.Path)
+		if err != nil {
+			s.log.Error("Failed to read certificate of %s: %v", acme.Domain, err)
+			continue
+		}
+
+		var problems []string
+		lifetime := certificate.NotAfter.Sub(certificate.NotBefore)
+		left := time.Until(certificate.NotAfter)
+		if left < lifetime/4 {
+			problems = append(problems, fmt.Sprintf("certificate on disk was not renewed and expires on %s", certificate.NotAfter.Format("2006-01-02")))
+		}
+
+		served, err := servedCertificate(acme.Domain)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("failed to get served certificate: %v", err))
+		} else if !bytes.Equal(served, certificate.Raw) {
+			problems = append(problems, "served certificate is not the one on disk, reload the web server")
+		}
+
+		status := "pass"
+		cause := fmt.Sprintf("Serving the certificate from %s, expires on %s", acme.Path, certificate.NotAfter.Format("2006-01-02"))
+		value := 0.0
+		if len(problems) > 0 {
+			status = "fail"
+			cause = strings.Join(problems, ", ")
+			value = 1
+			s.log.Warn("Certificate renewal of %s: %s", acme.Domain, cause)
+		} else {
+			s.log.Log("Certificate renewal of %s: ok", acme.Domain)
+		}
+		s.recordValue(valueName("acme", acme.Domain, "stuck"), value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "acme",
+			Title:     fmt.Sprintf("Certificate Renewal %s - %s", acme.Domain, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("acme-%s-%s", valueName(acme.Domain), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"domain": acme.Domain},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// agentUsage is the resource usage of the agent process itself.
+type agentUsage struct {
+	RSSMB      float64
+	HeapMB     float64
+	Goroutines int
+}
+
+func readAgentUsage() (agentUsage, error) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	usage := agentUsage{
+		HeapMB:     float64(memStats.HeapAlloc) / (1024 * 1024),
+		Goroutines: runtime.NumGoroutine(),
+	}
+
+	p, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return usage, fmt.Errorf("failed to open own process: %v", err)
+	}
+	memory, err := p.MemoryInfo()
+	if err != nil {
+		return usage, fmt.Errorf("failed to get own memory usage: %v", err)
+	}
+	usage.RSSMB = float64(memory.RSS) / (1024 * 1024)
+	return usage, nil
+}
+
+// checkAgent reports on the agent itself, so a monitor that leaks,
+// slows down or fails to deliver is noticed like any other problem.
+// Cycle duration and check errors are those of the previous cycle.
+func (s *SystemMonitor) checkAgent(ctx context.Context) error {
+	usage, err := readAgentUsage()
+	if err != nil {
+		return err
+	}
+
+	s.recordValue("agent.rss_mb", usage.RSSMB)
+	s.recordValue("agent.heap_mb", usage.HeapMB)
+	s.recordValue("agent.goroutines", float64(usage.Goroutines))
+	if s.cycleDuration > 0 {
+		s.recordValue("agent.cycle_duration_ms", float64(s.cycleDuration.Milliseconds()))
+	}
+	s.recordValue("agent.check_errors", float64(s.cycleErrors))
+
+	snapshot := s.deliveries.Snapshot()
+	for sink, stats := range snapshot {
+		s.recordValue(valueName("agent", "sinks", sink, "latency_ms"), float64(stats.AverageLatency().Milliseconds()))
+		s.recordValue(valueName("agent", "sinks", sink, "failed"), float64(stats.Failed))
+	}
+
+	s.log.Log("Agent: RSS %.1f MB, heap %.1f MB, %d goroutines, previous cycle %s with %d check errors",
+		usage.RSSMB, usage.HeapMB, usage.Goroutines, s.cycleDuration.Round(time.Millisecond), s.cycleErrors)
+	s.watchdog(usage)
+
+	checks := []struct {
+		kind  string
+		title string
+		value float64
+		limit float64
+		unit  string
+	}{
+		{"memory", "Agent Memory", usage.RSSMB, s.config.AgentMemoryLimitMB, UnitMB},
+		{"goroutines", "Agent Goroutines", float64(usage.Goroutines), s.config.AgentGoroutinesLimit, UnitCount},
+	}
+	for _, check := range checks {
+		if check.limit <= 0 {
+			continue
+		}
+		status := s.getStatus(check.value, check.limit)
+		if status == "fail" {
+			s.log.Warn("%s %.0f exceeds limit of %.0f", check.title, check.value, check.limit)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "agent",
+			Title:     fmt.Sprintf("%s - %s", check.title, s.hostname),
+			Cause:     "Agent self-monitoring check",
+			AlertID:   fmt.Sprintf("agent-%s-%s", check.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     check.value,
+			Limit:     check.limit,
+			Unit:      check.unit,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, check.value, 0),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// watchdogExitCode is returned when the watchdog stops a leaking agent,
+// for the supervisor (Docker restart policy, systemd Restart=) to start
+// a fresh one.
+const watchdogExitCode = 3
+
+// watchdog exits the agent once its memory or goroutine count grows
+// beyond the configured bounds, so a leak in the agent cannot starve the
+// host it is supposed to watch. The agent alerts (--agent-memory-limit,
+// --agent-goroutines-limit) fire first at their lower default limits.
+func (s *SystemMonitor) watchdog(usage agentUsage) {
+	reason := ""
+	if s.config.WatchdogMemoryMB > 0 && usage.RSSMB > s.config.WatchdogMemoryMB {
+		reason = fmt.Sprintf("memory %.0f MB exceeds %.0f MB", usage.RSSMB, s.config.WatchdogMemoryMB)
+	}
+	if s.config.WatchdogGoroutines > 0 && usage.Goroutines > s.config.WatchdogGoroutines {
+		reason = fmt.Sprintf("%d goroutines exceed %d", usage.Goroutines, s.config.WatchdogGoroutines)
+	}
+	if reason == "" {
+		return
+	}
+
+	s.log.Error("Watchdog: %s, exiting to be restarted", reason)
+	os.Exit(watchdogExitCode)
+}
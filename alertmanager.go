@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var alertmanagerLabelSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// AlertmanagerSink posts alerts to the v2 API of Prometheus Alertmanager,
+// so its routing, grouping, inhibition and silences apply.
+type AlertmanagerSink struct {
+	httpClient     *http.Client
+	url            string
+	resolveTimeout time.Duration
+	log            *Logger
+
+	mu     sync.Mutex
+	firing map[string]alertmanagerAlert
+}
+
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    string            `json:"startsAt"`
+	EndsAt      string            `json:"endsAt"`
+}
+
+// NewAlertmanagerSink creates a sink for the Alertmanager at baseURL.
+// Firing alerts end after three check intervals, at least five minutes,
+// unless they are sent again, so alerts resolve if the agent stops.
+func NewAlertmanagerSink(baseURL string, interval time.Duration) (*AlertmanagerSink, error) {
+	parsed, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Alertmanager URL %q, expected e.g. http://alertmanager:9093", baseURL)
+	}
+
+	resolveTimeout := 3 * interval
+	if resolveTimeout < 5*time.Minute {
+		resolveTimeout = 5 * time.Minute
+	}
+
+	return &AlertmanagerSink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		url:            parsed.String() + "/api/v2/alerts",
+		resolveTimeout: resolveTimeout,
+		log:            New(),
+		firing:         map[string]alertmanagerAlert{},
+	}, nil
+}
+
+func (a *AlertmanagerSink) Name() string {
+	return "alertmanager"
+}
+
+// alertmanagerLabel turns a name into a valid Prometheus label name.
+func alertmanagerLabel(name string) string {
+	name = alertmanagerLabelSanitizer.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// alertmanagerLabels returns the labels of a firing alert.
+func alertmanagerLabels(metric Metric) map[string]string {
+	labels := map[string]string{}
+	for name, value := range metric.Labels {
+		labels[alertmanagerLabel(name)] = value
+	}
+	alertname := metric.Name
+	if alertname == "" {
+		alertname = "monitoring"
+	}
+	labels["alertname"] = alertname
+	labels["alert_id"] = metric.AlertID
+	labels["instance"] = metric.Labels["host"]
+	if metric.Severity != "" {
+		labels["severity"] = metric.Severity
+	}
+	return labels
+}
+
+func equalLabels(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Send posts a failing metric as a firing alert and a passing one as
+// resolved. Passing metrics of alerts that weren't firing are skipped, so
+// Alertmanager only receives alerts that matter.
+func (a *AlertmanagerSink) Send(metric Metric) error {
+	now := time.Now()
+	annotations := map[string]string{
+		"summary":     metric.Title,
+		"description": metric.Cause,
+		"value":       fmt.Sprintf("%.2f", metric.Value),
+		"limit":       fmt.Sprintf("%.2f", metric.Limit),
+		"unit":        metric.Unit,
+	}
+
+	// Alertmanager identifies alerts by their labels, so an alert is
+	// resolved with the labels it fired with. When they change, e.g. from
+	// warning to critical, the old alert is resolved and a new one fires.
+	a.mu.Lock()
+	alert, firing := a.firing[metric.AlertID]
+	var alerts []alertmanagerAlert
+	if metric.Status == "fail" {
+		labels := alertmanagerLabels(metric)
+		if firing && !equalLabels(alert.Labels, labels) {
+			previous := alert
+			previous.EndsAt = now.UTC().Format(rfc3339Milli)
+			alerts = append(alerts, previous)
+			firing = false
+		}
+		if !firing {
+			alert = alertmanagerAlert{Labels: labels, StartsAt: now.UTC().Format(rfc3339Milli)}
+		}
+		alert.Annotations = annotations
+		alert.EndsAt = now.Add(a.resolveTimeout).UTC().Format(rfc3339Milli)
+		a.firing[metric.AlertID] = alert
+	} else {
+		alert.Annotations = annotations
+		alert.EndsAt = now.UTC().Format(rfc3339Milli)
+	}
+	a.mu.Unlock()
+	if metric.Status != "fail" && !firing {
+		return nil
+	}
+
+	body, err := json.Marshal(append(alerts, alert))
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.url, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	if err := deliver(a.httpClient, req, a.Name(), a.log); err != nil {
+		return err
+	}
+	if metric.Status != "fail" {
+		a.mu.Lock()
+		delete(a.firing, metric.AlertID)
+		a.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type alertState struct {
+	FirstFailure time.Time
+	Escalations  int
+	Acknowledged bool
+	LastMetric   Metric
+}
+
+// AlertStatus describes a failing or snoozed alert for the API.
+type AlertStatus struct {
+	AlertID      string    `json:"alert_id"`
+	Title        string    `json:"title"`
+	Status       string    `json:"status"`
+	Value        float64   `json:"value"`
+	Limit        float64   `json:"limit"`
+	FailingSince time.Time `json:"failing_since,omitempty"`
+	Acknowledged bool      `json:"acknowledged"`
+	SnoozedUntil time.Time `json:"snoozed_until,omitempty"`
+}
+
+// alertTracker remembers since when each alert has been failing, across
+// check cycles, and whether it was acknowledged or snoozed.
+type alertTracker struct {
+	mu       sync.Mutex
+	alerts   map[string]*alertState
+	snoozes  map[string]time.Time
+	failures map[string]int
+}
+
+func newAlertTracker() *alertTracker {
+	return &alertTracker{
+		alerts:   map[string]*alertState{},
+		snoozes:  map[string]time.Time{},
+		failures: map[string]int{},
+	}
+}
+
+// Observe records the metric and returns the state of its alert. A
+// passing metric clears the state; the state before clearing is returned
+// so the recovery can reach the sinks the failure was escalated to.
+func (t *alertTracker) Observe(metric Metric) alertState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.alerts[metric.AlertID]
+	if metric.Status != "fail" {
+		if !ok {
+			return alertState{}
+		}
+		delete(t.alerts, metric.AlertID)
+		return *state
+	}
+
+	if !ok {
+		state = &alertState{FirstFailure: time.Now()}
+		t.alerts[metric.AlertID] = state
+		t.failures[metric.AlertID]++
+	}
+	state.LastMetric = metric
+	return *state
+}
+
+// Failing returns whether the alert is currently failing.
+func (t *alertTracker) Failing(alertID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.alerts[alertID]
+	return ok
+}
+
+// TakeFailures returns how often each alert started failing since the
+// last call.
+func (t *alertTracker) TakeFailures() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures := t.failures
+	t.failures = map[string]int{}
+	return failures
+}
+
+func (t *alertTracker) SetEscalations(alertID string, escalations int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, ok := t.alerts[alertID]; ok {
+		state.Escalations = escalations
+	}
+}
+
+// Acknowledge silences a failing alert until it recovers.
+func (t *alertTracker) Acknowledge(alertID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.alerts[alertID]
+	if !ok {
+		return fmt.Errorf("alert %s is not failing", alertID)
+	}
+	state.Acknowledged = true
+	return nil
+}
+
+// Snooze silences an alert for the given duration, whether or not it is
+// currently failing. Once the snooze expires a still failing alert is
+// notified again.
+func (t *alertTracker) Snooze(alertID string, duration time.Duration) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	until := time.Now().Add(duration)
+	t.snoozes[alertID] = until
+	return until
+}
+
+func (t *alertTracker) Unsnooze(alertID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.snoozes, alertID)
+}
+
+// Suppression returns why notifications for an alert are suppressed, or
+// an empty string if they are not.
+func (t *alertTracker) Suppression(alertID string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if until, ok := t.snoozes[alertID]; ok {
+		if time.Now().Before(until) {
+			return fmt.Sprintf("snoozed until %s", until.Format("2006-01-02 15:04:05"))
+		}
+		delete(t.snoozes, alertID)
+	}
+	if state, ok := t.alerts[alertID]; ok && state.Acknowledged {
+		return "acknowledged"
+	}
+	return ""
+}
+
+func (t *alertTracker) List() []AlertStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := map[string]*AlertStatus{}
+	for id, state := range t.alerts {
+		statuses[id] = &AlertStatus{
+			AlertID:      id,
+			Title:        state.LastMetric.Title,
+			Status:       state.LastMetric.Status,
+			Value:        state.LastMetric.Value,
+			Limit:        state.LastMetric.Limit,
+			FailingSince: state.FirstFailure,
+			Acknowledged: state.Acknowledged,
+		}
+	}
+	for id, until := range t.snoozes {
+		if time.Now().After(until) {
+			continue
+		}
+		status, ok := statuses[id]
+		if !ok {
+			status = &AlertStatus{AlertID: id}
+			statuses[id] = status
+		}
+		status.SnoozedUntil = until
+	}
+
+	list := make([]AlertStatus, 0, len(statuses))
+	for _, status := range statuses {
+		list = append(list, *status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].AlertID < list[j].AlertID
+	})
+	return list
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIServer exposes the agent's alert state over HTTP.
+type APIServer struct {
+	monitor *SystemMonitor
+	token   string
+	log     *Logger
+}
+
+func NewAPIServer(monitor *SystemMonitor, token string) *APIServer {
+	return &APIServer{
+		monitor: monitor,
+		token:   token,
+		log:     New(),
+	}
+}
+
+func (a *APIServer) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/alerts", a.authorize(a.handleAlerts))
+	mux.HandleFunc("/alerts/", a.authorize(a.handleAlert))
+	mux.HandleFunc("/heartbeat/", a.authorize(a.handleHeartbeat))
+	mux.HandleFunc("/metrics", a.authorize(a.handleMetrics))
+	mux.HandleFunc("/uptime", a.authorize(a.handleUptime))
+	mux.HandleFunc("/stats", a.authorize(a.handleStats))
+	mux.HandleFunc("/dead-letters", a.authorize(a.handleDeadLetters))
+	mux.HandleFunc("/dead-letters/redeliver", a.authorize(a.handleRedeliver))
+	mux.HandleFunc("/checks", a.authorize(a.handleChecks))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	a.log.Info("API listening on %s", addr)
+	return server.ListenAndServe()
+}
+
+// loopbackAddress reports whether the API listens on addr only for local
+// clients. An empty host listens on every interface.
+func loopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (a *APIServer) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// GET /alerts
+func (a *APIServer) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, a.monitor.alerts.List())
+}
+
+// POST /alerts/{id}/ack
+// POST /alerts/{id}/snooze?duration=1h
+// DELETE /alerts/{id}/snooze
+func (a *APIServer) handleAlert(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/alerts/")
+	separator := strings.LastIndex(path, "/")
+	if separator <= 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	alertID, action := path[:separator], path[separator+1:]
+
+	switch {
+	case action == "ack" && r.Method == http.MethodPost:
+		if err := a.monitor.alerts.Acknowledge(alertID); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		a.log.Info("Alert %s acknowledged", alertID)
+		writeJSON(w, http.StatusOK, map[string]string{"alert_id": alertID, "status": "acknowledged"})
+
+	case action == "snooze" && r.Method == http.MethodPost:
+		duration, err := time.ParseDuration(r.URL.Query().Get("duration"))
+		if err != nil || duration <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "duration must be a positive duration such as 30m or 2h"})
+			return
+		}
+		until := a.monitor.alerts.Snooze(alertID, duration)
+		a.log.Info("Alert %s snoozed until %s", alertID, until.Format("2006-01-02 15:04:05"))
+		writeJSON(w, http.StatusOK, map[string]string{"alert_id": alertID, "status": "snoozed", "snoozed_until": until.Format(time.RFC3339)})
+
+	case action == "snooze" && r.Method == http.MethodDelete:
+		a.monitor.alerts.Unsnooze(alertID)
+		a.log.Info("Alert %s unsnoozed", alertID)
+		writeJSON(w, http.StatusOK, map[string]string{"alert_id": alertID, "status": "unsnoozed"})
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+// GET|POST /heartbeat/{name}
+func (a *APIServer) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/heartbeat/")
+	if err := a.monitor.heartbeats.Ping(name); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	a.log.Log("Heartbeat %s received", name)
+	if err := a.monitor.heartbeats.Save(); err != nil {
+		a.log.Error("Failed to save heartbeats: %v", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"heartbeat": name, "status": "received"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
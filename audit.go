@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAuditFile = "/var/lib/monitoring/audit.jsonl"
+
+// auditEntry records what was decided for one evaluation of an alert.
+// Decision is delivered, failed, suppressed, held or unrouted.
+type auditEntry struct {
+	Time     string   `json:"time"`
+	EventID  string   `json:"event_id"`
+	AlertID  string   `json:"alert_id"`
+	Name     string   `json:"name"`
+	Status   string   `json:"status"`
+	Severity string   `json:"severity,omitempty"`
+	Value    *float64 `json:"value,omitempty"`
+	Limit    float64  `json:"limit"`
+	Decision string   `json:"decision"`
+	Reason   string   `json:"reason,omitempty"`
+	Notified []string `json:"notified,omitempty"`
+	Failed   []string `json:"failed,omitempty"`
+	Held     []string `json:"held,omitempty"`
+}
+
+// auditLog appends alert decisions to a JSON lines file. It is never
+// rewritten by the agent; rotate it with logrotate. Without a path,
+// nothing is recorded.
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newAuditLog(path string) *auditLog {
+	return &auditLog{path: path}
+}
+
+func (a *auditLog) Record(entry auditEntry) error {
+	if a.path == "" {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// recordDecision adds the decision taken for a metric to the audit log.
+func (s *SystemMonitor) recordDecision(metric Metric, decision, reason string, notified, failed, held []string) {
+	if !s.config.Audit {
+		return
+	}
+	entry := auditEntry{
+		Time:     metric.Time,
+		EventID:  metric.EventID,
+		AlertID:  metric.AlertID,
+		Name:     metric.Name,
+		Status:   metric.Status,
+		Severity: metric.Severity,
+		Limit:    metric.Limit,
+		Decision: decision,
+		Reason:   reason,
+		Notified: notified,
+		Failed:   failed,
+		Held:     held,
+	}
+	// JSON can't represent them
+	if !math.IsNaN(metric.Value) && !math.IsInf(metric.Value, 0) {
+		value := metric.Value
+		entry.Value = &value
+	}
+	if err := s.audit.Record(entry); err != nil {
+		s.log.Error("Failed to write audit log: %v", err)
+	}
+}
+
+func runAuditCommand(args []string) {
+	log := New()
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	file := fs.String("file", defaultAuditFile, "Audit log of the agent")
+	fromValue := fs.String("from", "24h", "Start of the range, as an RFC 3339 time, a date or a duration before now")
+	toValue := fs.String("to", "0s", "End of the range, as an RFC 3339 time, a date or a duration before now")
+	alert := fs.String("alert", "", "Only show alert IDs matching this glob pattern, e.g. \"cpu-*\"")
+	failing := fs.Bool("failing", false, "Only show failing evaluations")
+	asJSON := fs.Bool("json", false, "Print the entries as JSON lines")
+	fs.Parse(args)
+
+	now := time.Now()
+	from, err := parseHistoryTime(*fromValue, now)
+	if err != nil {
+		log.Fatal("Invalid --from %q: %v", *fromValue, err)
+	}
+	to, err := parseHistoryTime(*toValue, now)
+	if err != nil {
+		log.Fatal("Invalid --to %q: %v", *toValue, err)
+	}
+	if _, err := path.Match(*alert, ""); err != nil {
+		log.Fatal("Invalid --alert %q: %v", *alert, err)
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		log.Fatal("Failed to read audit log: %v", err)
+	}
+	defer input.Close()
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry auditEntry
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, entry.Time)
+		if err != nil || at.Before(from) || at.After(to) {
+			continue
+		}
+		if *alert != "" {
+			if ok, _ := path.Match(*alert, entry.AlertID); !ok {
+				continue
+			}
+		}
+		if *failing && entry.Status != "fail" {
+			continue
+		}
+
+		if *asJSON {
+			fmt.Println(scanner.Text())
+			continue
+		}
+		value := "-"
+		if entry.Value != nil {
+			value = fmt.Sprintf("%.2f", *entry.Value)
+		}
+		detail := entry.Reason
+		if len(entry.Notified) > 0 {
+			detail = strings.TrimSpace(detail + " notified " + strings.Join(entry.Notified, ","))
+		}
+		if len(entry.Failed) > 0 {
+			detail = strings.TrimSpace(detail + " failed " + strings.Join(entry.Failed, ","))
+		}
+		if len(entry.Held) > 0 {
+			detail = strings.TrimSpace(detail + " held " + strings.Join(entry.Held, ","))
+		}
+		fmt.Printf("%s %-40s %-4s %10s/%-8.2f %-10s %s\n", at.Local().Format("2006-01-02 15:04:05"), entry.AlertID, entry.Status, value, entry.Limit, entry.Decision, detail)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal("Failed to read audit log: %v", err)
+	}
+}
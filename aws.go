@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	awsContainerCredentialsHost = "http://169.254.170.2"
+	awsInstanceMetadataHost     = "http://169.254.169.254"
+)
+
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// awsCredentialChain resolves credentials the same way the AWS SDKs do:
+// environment variables, the shared credentials file, the ECS container
+// endpoint and finally the EC2 instance metadata service (IMDSv2).
+type awsCredentialChain struct {
+	httpClient *http.Client
+	mu         sync.Mutex
+	cached     *awsCredentials
+}
+
+// maxCredentialDuration is the longest the credential chain takes to
+// resolve credentials: the container credentials request and the three
+// instance metadata requests, each timing out after 2 seconds.
+const maxCredentialDuration = 4 * 2 * time.Second
+
+func newAWSCredentialChain() *awsCredentialChain {
+	return &awsCredentialChain{
+		httpClient: &http.Client{
+			Timeout: 2 * time.Second,
+		},
+	}
+}
+
+func (c *awsCredentialChain) Retrieve() (awsCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Refresh temporary credentials a few minutes before they expire
+	if c.cached != nil && (c.cached.Expires.IsZero() || time.Until(c.cached.Expires) > 5*time.Minute) {
+		return *c.cached, nil
+	}
+
+	providers := []func() (*awsCredentials, error){
+		c.fromEnvironment,
+		c.fromSharedFile,
+		c.fromContainer,
+		c.fromInstanceMetadata,
+	}
+
+	var errs []string
+	for _, provider := range providers {
+		creds, err := provider()
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if creds != nil {
+			c.cached = creds
+			return *creds, nil
+		}
+	}
+
+	if len(errs) > 0 {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials found: %s", strings.Join(errs, "; "))
+	}
+	return awsCredentials{}, fmt.Errorf("no AWS credentials found")
+}
+
+func (c *awsCredentialChain) fromEnvironment() (*awsCredentials, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, nil
+	}
+
+	return &awsCredentials{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}, nil
+}
+
+func (c *awsCredentialChain) fromSharedFile() (*awsCredentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open shared credentials file: %v", err)
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read shared credentials file: %v", err)
+	}
+
+	if values["aws_access_key_id"] == "" || values["aws_secret_access_key"] == "" {
+		return nil, nil
+	}
+
+	return &awsCredentials{
+		AccessKeyID:     values["aws_access_key_id"],
+		SecretAccessKey: values["aws_secret_access_key"],
+		SessionToken:    values["aws_session_token"],
+	}, nil
+}
+
+func (c *awsCredentialChain) fromContainer() (*awsCredentials, error) {
+	url := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		url = awsContainerCredentialsHost + relative
+	}
+	if url == "" {
+		return nil, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container credentials request: %v", err)
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	return c.fetchCredentials(req)
+}
+
+func (c *awsCredentialChain) fromInstanceMetadata() (*awsCredentials, error) {
+	req, err := http.NewRequest(http.MethodPut, awsInstanceMetadataHost+"/latest/api/token", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata token request: %v", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Not running on EC2
+		return nil, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata token request failed with status: %d", resp.StatusCode)
+	}
+	token, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata token: %v", err)
+	}
+
+	rolesURL := awsInstanceMetadataHost + "/latest/meta-data/iam/security-credentials/"
+	req, err = http.NewRequest(http.MethodGet, rolesURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instance role request: %v", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+
+	resp, err = c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance role: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// No instance profile attached
+		return nil, nil
+	}
+	roles, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instance role: %v", err)
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return nil, nil
+	}
+
+	req, err = http.NewRequest(http.MethodGet, rolesURL+role, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instance credentials request: %v", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+
+	return c.fetchCredentials(req)
+}
+
+func (c *awsCredentialChain) fetchCredentials(req *http.Request) (*awsCredentials, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch credentials from %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("credentials request to %s failed with status: %d", req.URL.Host, resp.StatusCode)
+	}
+
+	var result struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials: %v", err)
+	}
+
+	return &awsCredentials{
+		AccessKeyID:     result.AccessKeyID,
+		SecretAccessKey: result.SecretAccessKey,
+		SessionToken:    result.Token,
+		Expires:         result.Expiration,
+	}, nil
+}
+
+// signAWSRequest signs req with AWS Signature Version 4.
+func signAWSRequest(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{}
+	var names []string
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower != "host" && lower != "content-type" && !strings.HasPrefix(lower, "x-amz-") {
+			continue
+		}
+		headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		names = append(names, lower)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{day, region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
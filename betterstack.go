@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type BetterStackSink struct {
+	httpClient *http.Client
+	url        string
+	schema     int
+	log        *Logger
+}
+
+func NewBetterStackSink(url string) *BetterStackSink {
+	return &BetterStackSink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		url:    url,
+		schema: payloadSchemaV1,
+		log:    New(),
+	}
+}
+
+func (b *BetterStackSink) Name() string {
+	return "betterstack"
+}
+
+func (b *BetterStackSink) SetPayloadSchema(version int) {
+	b.schema = version
+}
+
+func (b *BetterStackSink) Send(metric Metric) error {
+	body, err := encodePayload(metric, b.schema)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metric: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.url, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+	if metric.EventID != "" {
+		req.Header.Set("Idempotency-Key", metric.EventID)
+	}
+
+	return deliver(b.httpClient, req, b.Name(), b.log)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bond is an aggregated interface, either a kernel bond or a teamd team.
+type bond struct {
+	Name     string
+	Up       bool
+	Active   string
+	Slaves   []string
+	Down     []string
+	Failures float64
+}
+
+// parseBond parses /proc/net/bonding/<bond>. Keys before the first
+// "Slave Interface" describe the bond, the following ones each slave.
+func parseBond(name string, data []byte) bond {
+	b := bond{Name: name}
+	slave := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "Slave Interface":
+			slave = value
+			b.Slaves = append(b.Slaves, slave)
+		case "MII Status":
+			if slave == "" {
+				b.Up = value == "up"
+			} else if value != "up" {
+				b.Down = append(b.Down, slave)
+			}
+		case "Currently Active Slave":
+			b.Active = value
+		case "Link Failure Count":
+			count, _ := strconv.ParseFloat(value, 64)
+			b.Failures += count
+		}
+	}
+	return b
+}
+
+// kernelBonds returns all bonds of the bonding driver.
+func kernelBonds() ([]bond, error) {
+	paths, err := filepath.Glob("/proc/net/bonding/*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var bonds []bond
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		bonds = append(bonds, parseBond(filepath.Base(path), data))
+	}
+	return bonds, nil
+}
+
+// teamBond reads the state of a teamd team from "teamdctl <team> state dump".
+func teamBond(ctx context.Context, name string) (bond, error) {
+	output, err := runCommand(ctx, "teamdctl", name, "state", "dump")
+	if err != nil {
+		return bond{}, err
+	}
+
+	var state struct {
+		Ports map[string]struct {
+			Link struct {
+				Up bool `json:"up"`
+			} `json:"link"`
+		} `json:"ports"`
+		Runner struct {
+			ActivePort string `json:"active_port"`
+		} `json:"runner"`
+	}
+	if err := json.Unmarshal(output, &state); err != nil {
+		return bond{}, fmt.Errorf("failed to parse teamd state: %v", err)
+	}
+
+	b := bond{Name: name, Active: state.Runner.ActivePort}
+	for port, info := range state.Ports {
+		b.Slaves = append(b.Slaves, port)
+		if info.Link.Up {
+			b.Up = true
+		} else {
+			b.Down = append(b.Down, port)
+		}
+	}
+	sort.Strings(b.Slaves)
+	sort.Strings(b.Down)
+	return b, nil
+}
+
+// checkBonding alerts when a bond is down, when it lost redundancy
+// because slaves are down although traffic still flows, and for one cycle
+// after a failover or link failure.
+func (s *SystemMonitor) checkBonding(ctx context.Context) error {
+	bonds, err := kernelBonds()
+	if err != nil {
+		return err
+	}
+	for _, team := range s.config.TeamInterfaces {
+		b, err := teamBond(ctx, team)
+		if err != nil {
+			return fmt.Errorf("failed to read team %s: %v", team, err)
+		}
+		bonds = append(bonds, b)
+	}
+
+	previous := s.bonds
+	s.bonds = map[string]bond{}
+
+	for _, b := range bonds {
+		s.bonds[b.Name] = b
+		active := float64(len(b.Slaves) - len(b.Down))
+		s.recordValue(valueName("bond", b.Name, "active_slaves"), active)
+		labels := map[string]string{"bond": b.Name}
+
+		type check struct {
+			kind   string
+			title  string
+			cause  string
+			status string
+			value  float64
+		}
+		status := "pass"
+		cause := fmt.Sprintf("%.0f of %d slaves up (%s)", active, len(b.Slaves), strings.Join(b.Slaves, ", "))
+		if !b.Up || len(b.Down) > 0 {
+			status = "fail"
+			if len(b.Down) > 0 {
+				cause = fmt.Sprintf("%.0f of %d slaves up, down: %s", active, len(b.Slaves), strings.Join(b.Down, ", "))
+			}
+		}
+		checks := []check{{"slaves", "Redundancy", cause, status, active}}
+
+		if last, ok := previous[b.Name]; ok {
+			var events []string
+			if b.Active != last.Active && last.Active != "" {
+				events = append(events, fmt.Sprintf("active slave changed from %s to %s", last.Active, b.Active))
+			}
+			if failures := b.Failures - last.Failures; failures > 0 {
+				events = append(events, fmt.Sprintf("%.0f link failures", failures))
+			}
+			status := "pass"
+			cause := "No failover since the previous check"
+			if len(events) > 0 {
+				status = "fail"
+				cause = "Failover since the previous check: " + strings.Join(events, ", ")
+			}
+			checks = append(checks, check{"failover", "Failover", cause, status, float64(len(events))})
+		}
+
+		for _, check := range checks {
+			// Losing all slaves is an outage, losing some only redundancy
+			severity := s.getSeverity(check.status, check.value, 0)
+			if check.status == "fail" && (b.Up || check.kind == "failover") {
+				severity = SeverityWarning
+			}
+			if check.status == "fail" {
+				s.log.Warn("Bond %s: %s", b.Name, check.cause)
+			} else {
+				s.log.Log("Bond %s: %s", b.Name, check.cause)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "bond",
+				Title:     fmt.Sprintf("Bond %s %s - %s", b.Name, check.title, s.hostname),
+				Cause:     check.cause,
+				AlertID:   fmt.Sprintf("bond-%s-%s-%s", check.kind, b.Name, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    check.status,
+				Value:     check.value,
+				Limit:     float64(len(b.Slaves)),
+				Unit:      UnitCount,
+				Type:      TypeGauge,
+				Severity:  severity,
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned instead of attempting a delivery to a sink
+// whose circuit is open.
+var errCircuitOpen = errors.New("circuit open")
+
+// Circuit states of a sink
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// ParseSinkTimeout parses "[<sink>=]<duration>", e.g. "30s" for every
+// sink or "twilio=1m". An empty sink applies to every sink without a
+// timeout of its own.
+func ParseSinkTimeout(value string, sinks []Sink) (string, time.Duration, error) {
+	sink, duration := "", value
+	if name, rest, ok := strings.Cut(value, "="); ok {
+		sink, duration = strings.TrimSpace(name), rest
+
+		known := false
+		for _, s := range sinks {
+			known = known || s.Name() == sink
+		}
+		if !known {
+			return "", 0, fmt.Errorf("unknown sink %q", sink)
+		}
+	}
+
+	timeout, err := time.ParseDuration(strings.TrimSpace(duration))
+	if err != nil {
+		return "", 0, err
+	}
+	if timeout < maxDeliveryDuration {
+		return "", 0, fmt.Errorf("timeout must be at least %s, the longest delivery with its retries", maxDeliveryDuration)
+	}
+	return sink, timeout, nil
+}
+
+// minSinkTimeout is the longest a delivery to the sink takes with its
+// retries, the default and shortest timeout of the sink. Twilio delivers
+// to every recipient in turn, SNS may resolve credentials first.
+func minSinkTimeout(sink Sink) time.Duration {
+	switch sink := sink.(type) {
+	case *TwilioSink:
+		return time.Duration(len(sink.to)) * maxDeliveryDuration
+	case *SNSSink:
+		return maxCredentialDuration + maxDeliveryDuration
+	}
+	return maxDeliveryDuration
+}
+
+// breakerSink bounds every delivery to a sink by a timeout and stops
+// attempting deliveries after consecutive failures, so a sink that is
+// down doesn't delay every check cycle and log an error per alert. After
+// the cooldown one delivery is let through (half-open): if it succeeds
+// the circuit closes, otherwise it opens for another cooldown.
+type breakerSink struct {
+	Sink
+	timeout  time.Duration
+	failures int
+	cooldown time.Duration
+	log      *Logger
+
+	mu          sync.Mutex
+	state       string
+	consecutive int
+	openedAt    time.Time
+}
+
+func newBreakerSink(sink Sink, timeout time.Duration, failures int, cooldown time.Duration) *breakerSink {
+	return &breakerSink{
+		Sink:     sink,
+		timeout:  timeout,
+		failures: failures,
+		cooldown: cooldown,
+		log:      New(),
+		state:    circuitClosed,
+	}
+}
+
+// State returns the state of the circuit, for /stats.
+func (b *breakerSink) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Accepts forwards the filter of the wrapped sink, if it has one.
+func (b *breakerSink) Accepts(metric Metric) bool {
+	return accepts(b.Sink, metric)
+}
+
+func (b *breakerSink) Send(metric Metric) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	// The delivery keeps running in the background after the timeout,
+	// until the HTTP client of the sink gives up
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Sink.Send(metric)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(b.timeout):
+		err = fmt.Errorf("timed out after %s", b.timeout)
+	}
+
+	b.record(err)
+	return err
+}
+
+// allow rejects deliveries while the circuit is open, and lets one
+// through once the cooldown has passed.
+func (b *breakerSink) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		retry := b.openedAt.Add(b.cooldown)
+		if time.Now().Before(retry) {
+			return fmt.Errorf("%w after %d failures, retrying at %s", errCircuitOpen, b.consecutive, retry.Format("15:04:05"))
+		}
+		b.state = circuitHalfOpen
+		b.log.Log("Circuit of %s half-open, trying one delivery", b.Name())
+	case circuitHalfOpen:
+		return fmt.Errorf("%w, waiting for the trial delivery", errCircuitOpen)
+	}
+	return nil
+}
+
+func (b *breakerSink) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if b.state != circuitClosed {
+			b.log.Success("Circuit of %s closed, deliveries resumed", b.Name())
+		}
+		b.state = circuitClosed
+		b.consecutive = 0
+		return
+	}
+
+	b.consecutive++
+	if b.state == circuitHalfOpen || b.consecutive >= b.failures {
+		if b.state == circuitClosed {
+			b.log.Warn("Circuit of %s open after %d consecutive failures, pausing deliveries for %s", b.Name(), b.consecutive, b.cooldown)
+		}
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+	}
+}
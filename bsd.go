@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// bsdFilesystems are the local filesystems checked for usage on FreeBSD
+// and OpenBSD. nullfs is left out, in jails it mounts directories of
+// filesystems that are checked already.
+var bsdFilesystems = map[string]bool{
+	"ufs":     true,
+	"ffs":     true,
+	"zfs":     true,
+	"msdosfs": true,
+	"ext2fs":  true,
+}
+
+// isBSD reports whether the agent runs on FreeBSD or OpenBSD.
+func isBSD() bool {
+	return runtime.GOOS == "freebsd" || runtime.GOOS == "openbsd"
+}
+
+// dataMounts returns the mounted filesystems checked besides the root
+// filesystem. On Linux these are the directories in /mnt. The BSDs mount
+// data volumes and ZFS datasets anywhere, so every local filesystem is
+// checked there. ZFS datasets share the space of their pool, so only one
+// dataset per pool is checked, and none of the pool of the root filesystem.
+func dataMounts() ([]string, error) {
+	if !isBSD() {
+		return filepath.Glob("/mnt/*")
+	}
+
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return len(partitions[i].Mountpoint) < len(partitions[j].Mountpoint)
+	})
+
+	pools := map[string]bool{}
+	var mounts []string
+	for _, partition := range partitions {
+		if !bsdFilesystems[partition.Fstype] {
+			continue
+		}
+		if partition.Fstype == "zfs" {
+			pool := strings.SplitN(partition.Device, "/", 2)[0]
+			if pools[pool] {
+				continue
+			}
+			pools[pool] = true
+		}
+		if partition.Mountpoint != "/" {
+			mounts = append(mounts, partition.Mountpoint)
+		}
+	}
+	sort.Strings(mounts)
+	return mounts, nil
+}
+
+// linuxOnlyChecks disables the checks that read /proc, /sys or use Linux
+// tools when the agent doesn't run on Linux, so they are skipped with a
+// warning at startup instead of failing every cycle.
+func linuxOnlyChecks(config *Config, log *Logger) {
+	if runtime.GOOS == "linux" {
+		return
+	}
+	checks := []struct {
+		flag    string
+		enabled *bool
+	}{
+		{"--lvm", &config.LVM},
+		{"--btrfs", &config.Btrfs},
+		{"--nvme", &config.NVMe},
+		{"--io-errors", &config.IOErrors},
+		{"--reboot-required", &config.RebootRequired},
+		{"--systemd", &config.Systemd},
+		{"--firewall", &config.Firewall},
+		{"--mac", &config.MAC},
+		{"--gateway", &config.Gateway},
+		{"--link", &config.Link},
+		{"--bonding", &config.Bonding},
+		{"--ebpf", &config.EBPF},
+		{"--hugepages", &config.Hugepages},
+		{"--cgroups", &config.Cgroups},
+	}
+	for _, check := range checks {
+		if *check.enabled {
+			log.Warn("%s requires Linux, skipped on %s", check.flag, runtime.GOOS)
+			*check.enabled = false
+		}
+	}
+	if config.JournalErrorLimit > 0 {
+		log.Warn("--journal-error-limit requires Linux, skipped on %s", runtime.GOOS)
+		config.JournalErrorLimit = 0
+	}
+	if config.UpdatesInterval > 0 {
+		log.Warn("--updates-interval requires Linux, skipped on %s", runtime.GOOS)
+		config.UpdatesInterval = 0
+	}
+	if config.TCPRetransmitLimit > 0 || config.TCPConnectLatencyLimit > 0 {
+		log.Warn("--tcp-retransmit-limit and --tcp-connect-latency-limit require Linux, skipped on %s", runtime.GOOS)
+		config.TCPRetransmitLimit = 0
+		config.TCPConnectLatencyLimit = 0
+	}
+}
+
+// temperatures returns the temperature sensors in °C. gopsutil reads
+// hwmon on Linux but has no sensors on the BSDs, where they are sysctls:
+// "dev.cpu.0.temperature: 45.0C" on FreeBSD with coretemp or amdtemp
+// loaded, and "hw.sensors.cpu0.temp0=45.00 degC" on OpenBSD.
+func temperatures(ctx context.Context) (map[string]float64, error) {
+	sensors := map[string]float64{}
+	switch runtime.GOOS {
+	case "freebsd":
+		// A missing OID fails sysctl, but the others are still printed
+		output, err := runCommand(ctx, "sysctl", "dev.cpu", "hw.acpi.thermal")
+		for _, line := range strings.Split(string(output), "\n") {
+			name, value, ok := strings.Cut(line, ": ")
+			if !ok || !strings.HasSuffix(name, "temperature") || !strings.HasSuffix(value, "C") {
+				continue
+			}
+			if celsius, err := strconv.ParseFloat(strings.TrimSuffix(value, "C"), 64); err == nil {
+				sensors[strings.TrimSuffix(name, ".temperature")] = celsius
+			}
+		}
+		if len(sensors) == 0 && err != nil {
+			return nil, err
+		}
+	case "openbsd":
+		output, err := runCommand(ctx, "sysctl", "hw.sensors")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			name, value, ok := strings.Cut(line, "=")
+			if !ok || !strings.Contains(value, "degC") {
+				continue
+			}
+			fields := strings.Fields(value)
+			if celsius, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				sensors[strings.TrimPrefix(name, "hw.sensors.")] = celsius
+			}
+		}
+	default:
+		stats, err := host.SensorsTemperatures()
+		if err != nil && len(stats) == 0 {
+			return nil, err
+		}
+		for _, stat := range stats {
+			if stat.Temperature > 0 {
+				sensors[stat.SensorKey] = stat.Temperature
+			}
+		}
+	}
+	return sensors, nil
+}
+
+// checkTemperature alerts when the hottest sensor exceeds the limit. One
+// alert covers all sensors, they rise together when cooling fails.
+func (s *SystemMonitor) checkTemperature(ctx context.Context) error {
+	sensors, err := temperatures(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read temperature sensors: %v", err)
+	}
+	if len(sensors) == 0 {
+		s.log.Log("No temperature sensors found")
+		return nil
+	}
+
+	hottest, value := "", 0.0
+	for sensor, celsius := range sensors {
+		s.recordValue(valueName("temperature", sensor), celsius)
+		if hottest == "" || celsius > value || (celsius == value && sensor < hottest) {
+			hottest, value = sensor, celsius
+		}
+	}
+	s.recordValue("temperature.max", value)
+
+	status := s.getStatus(value, s.config.TemperatureLimit)
+	cause := fmt.Sprintf("Hottest sensor %s at %.1f°C (limit: %.1f°C)", hottest, value, s.config.TemperatureLimit)
+	if status == "fail" {
+		s.log.Warn("%s", cause)
+	} else {
+		s.log.Log("%s", cause)
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "temperature",
+		Title:     fmt.Sprintf("Temperature - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("temperature-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     s.config.TemperatureLimit,
+		Unit:      UnitCelsius,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+		Labels:    map[string]string{"sensor": hottest},
+	})
+}
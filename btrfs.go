@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// btrfsAllocation parses "btrfs filesystem usage -b", returning the
+// device size and the bytes allocated to chunks. Once all space is
+// allocated, writes fail with ENOSPC although df still reports free space.
+func btrfsAllocation(ctx context.Context, mount string) (size, allocated float64, err error) {
+	output, err := runCommand(ctx, "btrfs", "filesystem", "usage", "-b", mount)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		switch key {
+		case "Device size":
+			size, _ = strconv.ParseFloat(fields[0], 64)
+		case "Device allocated":
+			allocated, _ = strconv.ParseFloat(fields[0], 64)
+		}
+	}
+
+	if size == 0 {
+		return 0, 0, fmt.Errorf("failed to parse btrfs filesystem usage of %s", mount)
+	}
+	return size, allocated, nil
+}
+
+// btrfsDeviceErrors sums the error counters of "btrfs device stats" per
+// device, e.g. "[/dev/sda1].write_io_errs    0".
+func btrfsDeviceErrors(ctx context.Context, mount string) (map[string]int64, error) {
+	output, err := runCommand(ctx, "btrfs", "device", "stats", mount)
+	if err != nil {
+		return nil, err
+	}
+
+	errors := map[string]int64{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[0], "[") {
+			continue
+		}
+		end := strings.Index(fields[0], "]")
+		if end < 0 {
+			continue
+		}
+		count, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		errors[fields[0][1:end]] += count
+	}
+	return errors, nil
+}
+
+func (s *SystemMonitor) checkBtrfs(ctx context.Context) error {
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return fmt.Errorf("failed to list partitions: %v", err)
+	}
+
+	for _, partition := range partitions {
+		if partition.Fstype != "btrfs" {
+			continue
+		}
+		mount := partition.Mountpoint
+		labels := map[string]string{"mount": mount}
+
+		size, allocated, err := btrfsAllocation(ctx, mount)
+		if err != nil {
+			s.log.Error("Failed to get btrfs allocation for %s: %v", mount, err)
+		} else {
+			value := allocated / size * 100
+			s.recordValue(valueName("btrfs", mount, "allocated_percent"), value)
+
+			discrepancy := ""
+			if usage, err := disk.Usage(mount); err == nil {
+				discrepancy = fmt.Sprintf(", df reports %.2f%% used", usage.UsedPercent)
+			}
+
+			status := s.getStatus(value, s.config.BtrfsAllocationLimit)
+			if status == "fail" {
+				s.log.Warn("Btrfs allocation for %s %.2f%% exceeds limit of %.2f%%%s", mount, value, s.config.BtrfsAllocationLimit, discrepancy)
+			} else {
+				s.log.Log("Btrfs allocation for %s: %.2f%% (limit: %.2f%%)%s", mount, value, s.config.BtrfsAllocationLimit, discrepancy)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "btrfs",
+				Title:     fmt.Sprintf("Btrfs Allocation %s - %s", mount, s.hostname),
+				Cause:     fmt.Sprintf("%.0f of %.0f MB allocated to chunks%s", allocated/(1024*1024), size/(1024*1024), discrepancy),
+				AlertID:   fmt.Sprintf("btrfs-allocation-%s-%s", valueName(mount), s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     value,
+				Limit:     s.config.BtrfsAllocationLimit,
+				Unit:      UnitPercent,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(status, value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+
+		errors, err := btrfsDeviceErrors(ctx, mount)
+		if err != nil {
+			s.log.Error("Failed to get btrfs device stats for %s: %v", mount, err)
+			continue
+		}
+
+		var total int64
+		var failing []string
+		for device, count := range errors {
+			total += count
+			if count > 0 {
+				failing = append(failing, fmt.Sprintf("%s: %d", device, count))
+			}
+		}
+		sort.Strings(failing)
+		s.recordValue(valueName("btrfs", mount, "device_errors"), float64(total))
+
+		status := "pass"
+		cause := "Btrfs device error counters"
+		if total > 0 {
+			status = "fail"
+			cause = "Device errors: " + strings.Join(failing, ", ")
+			s.log.Warn("Btrfs device errors on %s: %s", mount, strings.Join(failing, ", "))
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "btrfs",
+			Title:     fmt.Sprintf("Btrfs Device Errors %s - %s", mount, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("btrfs-errors-%s-%s", valueName(mount), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     float64(total),
+			Limit:     0,
+			Unit:      UnitCount,
+			Type:      TypeCounter,
+			Severity:  s.getSeverity(status, float64(total), 0),
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
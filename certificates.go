@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// readCertificate parses the first certificate of a PEM file, which is the
+// leaf certificate in chain files such as Let's Encrypt's fullchain.pem.
+func readCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found in %s", path)
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate in %s: %v", path, err)
+		}
+		return certificate, nil
+	}
+}
+
+// certificateFiles expands the configured certificate paths and glob
+// patterns, e.g. "/etc/letsencrypt/live/*/cert.pem".
+func (s *SystemMonitor) certificateFiles() ([]string, error) {
+	var files []string
+	for _, pattern := range s.config.Certificates {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate pattern %q: %v", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// checkCertificates alerts when a certificate on disk expires within
+// CertificateExpiryDays, whether or not it is currently being served.
+func (s *SystemMonitor) checkCertificates(ctx context.Context) error {
+	files, err := s.certificateFiles()
+	if err != nil {
+		return err
+	}
+
+	limit := float64(s.config.CertificateExpiryDays)
+	for _, file := range files {
+		certificate, err := readCertificate(file)
+		if err != nil {
+			s.log.Error("Failed to read certificate: %v", err)
+			continue
+		}
+
+		days := time.Until(certificate.NotAfter).Hours() / 24
+		s.recordValue(valueName("certificate", file, "days_left"), days)
+
+		status := "pass"
+		cause := fmt.Sprintf("%s expires on %s", certificate.Subject.CommonName, certificate.NotAfter.Format("2006-01-02"))
+		if days < limit {
+			status = "fail"
+			s.log.Warn("Certificate %s (%s) expires in %.1f days", file, certificate.Subject.CommonName, days)
+		} else {
+			s.log.Log("Certificate %s (%s) expires in %.0f days", file, certificate.Subject.CommonName, days)
+		}
+		if days < 0 {
+			cause = fmt.Sprintf("%s expired on %s", certificate.Subject.CommonName, certificate.NotAfter.Format("2006-01-02"))
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "certificate",
+			Title:     fmt.Sprintf("Certificate Expiry %s - %s", file, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("certificate-%s-%s", valueName(file), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     days,
+			Limit:     limit,
+			Unit:      UnitDays,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, days, 0),
+			Labels:    map[string]string{"file": file},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
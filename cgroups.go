@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	// Top cgroups listed in failing CPU and memory alerts
+	topCgroups = 5
+)
+
+// cgroupUsage is the CPU or memory usage of a cgroup, in percent of the
+// host.
+type cgroupUsage struct {
+	Path  string
+	Name  string
+	Usage float64
+}
+
+// listCgroups returns the top-level cgroups (system.slice, user.slice,
+// init.scope, docker) and the units below them (services, users, scopes
+// of containers), relative to the cgroup v2 root.
+func listCgroups() ([]string, []string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, nil, fmt.Errorf("cgroup v2 is not mounted at %s", cgroupRoot)
+	}
+	entries, err := os.ReadDir(cgroupRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var slices, units []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		slices = append(slices, entry.Name())
+		children, err := os.ReadDir(filepath.Join(cgroupRoot, entry.Name()))
+		if err != nil {
+			continue
+		}
+		leaf := true
+		for _, child := range children {
+			if child.IsDir() {
+				leaf = false
+				units = append(units, entry.Name()+"/"+child.Name())
+			}
+		}
+		if leaf {
+			units = append(units, entry.Name())
+		}
+	}
+	return slices, units, nil
+}
+
+// cgroupName returns a readable name of a cgroup, e.g. "user alice" for
+// "user.slice/user-1000.slice", "docker 3f2a9c1b7d4e" for
+// "system.slice/docker-3f2a9c1b7d4e….scope" and "nginx.service" for
+// "system.slice/nginx.service".
+func cgroupName(path string) string {
+	base := filepath.Base(path)
+	if uid := strings.TrimSuffix(strings.TrimPrefix(base, "user-"), ".slice"); uid != base && strings.HasPrefix(path, "user.slice/") {
+		if account, err := user.LookupId(uid); err == nil {
+			return "user " + account.Username
+		}
+		return "user " + uid
+	}
+	id := ""
+	switch {
+	case strings.HasPrefix(base, "docker-") && strings.HasSuffix(base, ".scope"):
+		id = strings.TrimSuffix(strings.TrimPrefix(base, "docker-"), ".scope")
+	case strings.HasPrefix(path, "docker/"):
+		id = base
+	}
+	if id != "" {
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		return "docker " + id
+	}
+	return base
+}
+
+// readCgroupCPU returns the CPU time used by a cgroup in microseconds.
+func readCgroupCPU(path string) (float64, error) {
+	data, err := os.ReadFile(filepath.Join(cgroupRoot, path, "cpu.stat"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "usage_usec ") {
+			return strconv.ParseFloat(strings.TrimPrefix(line, "usage_usec "), 64)
+		}
+	}
+	return 0, fmt.Errorf("no usage_usec in cpu.stat of %s", path)
+}
+
+// readCgroupMemory returns the memory used by a cgroup in bytes, without
+// its inactive page cache, which the kernel reclaims first.
+func readCgroupMemory(path string) (float64, error) {
+	data, err := os.ReadFile(filepath.Join(cgroupRoot, path, "memory.current"))
+	if err != nil {
+		return 0, err
+	}
+	current, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, err
+	}
+	if stat, err := readProcValues(filepath.Join(cgroupRoot, path, "memory.stat")); err == nil && stat["inactive_file"] < current {
+		current -= stat["inactive_file"]
+	}
+	return current, nil
+}
+
+// cgroupUsages returns the usage of resource ("cpu" or "memory") of every
+// cgroup in paths, highest first. CPU usage is measured over a one second
+// window, like for top processes.
+func cgroupUsages(ctx context.Context, resource string, paths []string) ([]cgroupUsage, error) {
+	usages := make([]cgroupUsage, 0, len(paths))
+	switch resource {
+	case "cpu":
+		before := map[string]float64{}
+		for _, path := range paths {
+			if usec, err := readCgroupCPU(path); err == nil {
+				before[path] = usec
+			}
+		}
+
+		start := time.Now()
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		elapsed := float64(time.Since(start).Microseconds()) * float64(runtime.NumCPU())
+
+		for _, path := range paths {
+			previous, ok := before[path]
+			if !ok {
+				continue
+			}
+			usec, err := readCgroupCPU(path)
+			if err != nil {
+				continue
+			}
+			usages = append(usages, cgroupUsage{Path: path, Usage: (usec - previous) / elapsed * 100})
+		}
+
+	case "memory":
+		vmStat, err := mem.VirtualMemory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get memory stats: %v", err)
+		}
+		for _, path := range paths {
+			bytes, err := readCgroupMemory(path)
+			if err != nil {
+				continue
+			}
+			usages = append(usages, cgroupUsage{Path: path, Usage: bytes / float64(vmStat.Total) * 100})
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown resource %q", resource)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Usage > usages[j].Usage
+	})
+	for i := range usages {
+		usages[i].Name = cgroupName(usages[i].Path)
+	}
+	return usages, nil
+}
+
+// topCgroupsCause appends the usage of the top-level cgroups and the top
+// consuming services, users and containers to cause, so responders can
+// tell Appwrite being busy apart from an unknown process of a user.
+func (s *SystemMonitor) topCgroupsCause(ctx context.Context, cause, resource string) string {
+	if !s.config.Cgroups {
+		return cause
+	}
+
+	slices, units, err := listCgroups()
+	if err != nil {
+		s.log.Error("Failed to list cgroups: %v", err)
+		return cause
+	}
+	// One window for both, CPU usage is measured over a second
+	usages, err := cgroupUsages(ctx, resource, append(slices, units...))
+	if err != nil {
+		s.log.Error("Failed to get cgroup usage: %v", err)
+		return cause
+	}
+
+	isSlice := map[string]bool{}
+	for _, slice := range slices {
+		isSlice[slice] = true
+	}
+	var sliceParts, unitParts []string
+	for _, usage := range usages {
+		part := fmt.Sprintf("%s %.1f%%", usage.Name, usage.Usage)
+		if isSlice[usage.Path] {
+			sliceParts = append(sliceParts, part)
+		} else if len(unitParts) < topCgroups {
+			unitParts = append(unitParts, part)
+		}
+	}
+	s.log.Warn("Top cgroups by %s: %s", resource, strings.Join(unitParts, ", "))
+
+	return fmt.Sprintf("%s. Cgroups: %s. Top cgroups: %s", cause, strings.Join(sliceParts, ", "), strings.Join(unitParts, ", "))
+}
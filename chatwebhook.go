@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type chatAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type chatAttachment struct {
+	Fallback string                `json:"fallback"`
+	Color    string                `json:"color"`
+	Title    string                `json:"title"`
+	Text     string                `json:"text"`
+	Fields   []chatAttachmentField `json:"fields"`
+}
+
+type chatWebhookPayload struct {
+	Username    string           `json:"username,omitempty"`
+	Text        string           `json:"text,omitempty"`
+	Attachments []chatAttachment `json:"attachments"`
+}
+
+// ChatWebhookSink posts Slack-style attachment messages to incoming
+// webhooks. Mattermost and Rocket.Chat both accept this format.
+type ChatWebhookSink struct {
+	httpClient *http.Client
+	name       string
+	url        string
+	log        *Logger
+}
+
+func NewMattermostSink(url string) *ChatWebhookSink {
+	return newChatWebhookSink("mattermost", url)
+}
+
+func NewRocketChatSink(url string) *ChatWebhookSink {
+	return newChatWebhookSink("rocketchat", url)
+}
+
+func newChatWebhookSink(name, url string) *ChatWebhookSink {
+	return &ChatWebhookSink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		name: name,
+		url:  url,
+		log:  New(),
+	}
+}
+
+func (c *ChatWebhookSink) Name() string {
+	return c.name
+}
+
+// Accepts only failures, resolutions and digests.
+func (c *ChatWebhookSink) Accepts(metric Metric) bool {
+	return notifiable(metric)
+}
+
+func (c *ChatWebhookSink) Send(metric Metric) error {
+	severity := metric.Severity
+	if metric.Status != "fail" {
+		severity = "resolved"
+	}
+
+	payload := chatWebhookPayload{
+		Username: "Appwrite Monitoring",
+		Attachments: []chatAttachment{
+			{
+				Fallback: formatMetricText(metric),
+				Color:    statusColor(metric),
+				Title:    fmt.Sprintf("[%s] %s", strings.ToUpper(metric.Status), metric.Title),
+				Text:     metric.Cause,
+				Fields: []chatAttachmentField{
+					{Title: "Value", Value: formatValue(metric.Value, metric.Unit), Short: true},
+					{Title: "Limit", Value: formatValue(metric.Limit, metric.Unit), Short: true},
+					{Title: "Severity", Value: severity, Short: true},
+					{Title: "Alert ID", Value: metric.AlertID, Short: true},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	return deliver(c.httpClient, req, c.Name(), c.log)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// errCheckPanicked marks errors recovered from a panicking check.
+type errCheckPanicked struct {
+	value interface{}
+}
+
+func (e errCheckPanicked) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// safeCheck runs check and turns a panic, e.g. an index out of range
+// while parsing an unexpected /proc format, into an error, so one broken
+// collector cannot take down the agent.
+func (s *SystemMonitor) safeCheck(ctx context.Context, name string, check func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Error("Check %s panicked: %v\n%s", name, r, debug.Stack())
+			err = errCheckPanicked{r}
+		}
+	}()
+	return check(ctx)
+}
+
+// runCheck runs a check with a hard deadline of CheckTimeout. A check
+// that misses it is reported as timed out and the cycle moves on. Its
+// context is cancelled, which kills the commands it runs and aborts its
+// requests and sampling windows, and it is skipped until it returns,
+// which keeps a check wedged on, say, a hung mount from piling up or
+// stalling every other check.
+func (s *SystemMonitor) runCheck(name string, check func(context.Context) error) {
+	if s.config.CheckTimeout <= 0 {
+		start := time.Now()
+		err := s.safeCheck(context.Background(), name, check)
+		s.recordValue(valueName("agent", "checks", name, "duration_ms"), float64(time.Since(start).Milliseconds()))
+		s.checkCompleted(name, err)
+		return
+	}
+
+	s.checksMu.Lock()
+	if s.running[name] {
+		s.checksMu.Unlock()
+		s.checkFailed("timeout", name, "Check is still running since a previous cycle")
+		return
+	}
+	s.running[name] = true
+	s.checksMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.CheckTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		start := time.Now()
+		err := s.safeCheck(ctx, name, check)
+		s.recordValue(valueName("agent", "checks", name, "duration_ms"), float64(time.Since(start).Milliseconds()))
+		s.checksMu.Lock()
+		delete(s.running, name)
+		s.checksMu.Unlock()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		s.checkCompleted(name, err)
+	case <-ctx.Done():
+		s.checkFailed("timeout", name, fmt.Sprintf("Check did not complete within %s", s.config.CheckTimeout))
+	}
+}
+
+// checkCompleted logs the error of a finished check and resolves its
+// timeout or panic alert, if one is open.
+func (s *SystemMonitor) checkCompleted(name string, err error) {
+	if panicked, ok := err.(errCheckPanicked); ok {
+		s.checkFailed("panic", name, fmt.Sprintf("Check panicked: %v", panicked.value))
+		return
+	}
+	if err != nil {
+		s.cycleErrors++
+		s.log.Error("Error checking %s: %v", name, err)
+	}
+
+	for _, kind := range []string{"timeout", "panic"} {
+		if s.failing[kind+"-"+name] {
+			delete(s.failing, kind+"-"+name)
+			s.sendCheckFailure(kind, name, "pass", "Check completed")
+		}
+	}
+}
+
+func (s *SystemMonitor) checkFailed(kind, name, cause string) {
+	s.cycleErrors++
+	s.log.Error("Check %s failed: %s", name, cause)
+	s.failing[kind+"-"+name] = true
+	s.sendCheckFailure(kind, name, "fail", cause)
+}
+
+func (s *SystemMonitor) sendCheckFailure(kind, name, status, cause string) {
+	title := "Timed Out"
+	if kind == "panic" {
+		title = "Panicked"
+	}
+	value := 0.0
+	severity := SeverityInfo
+	if status == "fail" {
+		value = 1
+		// Nothing is known to be broken, only unmonitored
+		severity = SeverityWarning
+	}
+
+	if err := s.sendMetric(Metric{
+		Name:      "check-" + kind,
+		Title:     fmt.Sprintf("Check %s %s - %s", name, title, s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("check-%s-%s-%s", kind, name, s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     0,
+		Unit:      UnitBoolean,
+		Type:      TypeGauge,
+		Severity:  severity,
+		Labels:    map[string]string{"check": name},
+	}); err != nil {
+		s.log.Error("Failed to send check %s: %v", kind, err)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// commands are subcommands run instead of the monitor, e.g. "monitoring ack cpu-host1".
+var commands = map[string]func(args []string){
+	"alerts":      runAlertsCommand,
+	"ack":         runAckCommand,
+	"snooze":      runSnoozeCommand,
+	"self-update": runSelfUpdateCommand,
+	"export":      runExportCommand,
+	"history":     runHistoryCommand,
+	"redeliver":   runRedeliverCommand,
+	"audit":       runAuditCommand,
+	"top":         runTopCommand,
+}
+
+type apiClient struct {
+	httpClient *http.Client
+	url        string
+	token      string
+}
+
+func newAPIClientFlags(name string) (*flag.FlagSet, *string, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	api := fs.String("api", "http://127.0.0.1:9100", "URL of the agent API")
+	token := fs.String("api-token", "", "API token of the agent")
+	return fs, api, token
+}
+
+func newAPIClient(api, token string) *apiClient {
+	return &apiClient{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		url:   strings.TrimRight(api, "/"),
+		token: token,
+	}
+}
+
+func (c *apiClient) Do(method, path string, result interface{}) error {
+	body, err := c.request(method, path, maxResponseBodySize)
+	if err != nil {
+		return err
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(body, result); err != nil {
+			return fmt.Errorf("failed to decode response: %v", err)
+		}
+	}
+	return nil
+}
+
+// request sends a request to the agent API and returns at most limit
+// bytes of the response body.
+func (c *apiClient) request(method, path string, limit int64) ([]byte, error) {
+	req, err := http.NewRequest(method, c.url+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach agent API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode >= 400 {
+		var apiError struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiError) == nil && apiError.Error != "" {
+			return nil, fmt.Errorf("%s", apiError.Error)
+		}
+		return nil, fmt.Errorf("request failed with status: %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+func runAlertsCommand(args []string) {
+	log := New()
+	fs, api, token := newAPIClientFlags("alerts")
+	fs.Parse(args)
+
+	var alerts []AlertStatus
+	if err := newAPIClient(*api, *token).Do(http.MethodGet, "/alerts", &alerts); err != nil {
+		log.Fatal("Failed to list alerts: %v", err)
+	}
+
+	if len(alerts) == 0 {
+		fmt.Println("No failing or snoozed alerts")
+		return
+	}
+	for _, alert := range alerts {
+		state := strings.ToUpper(alert.Status)
+		if alert.Acknowledged {
+			state += " (acknowledged)"
+		}
+		if !alert.SnoozedUntil.IsZero() {
+			state += " (snoozed until " + alert.SnoozedUntil.Format("2006-01-02 15:04:05") + ")"
+		}
+		fmt.Printf("%-40s %s\n", alert.AlertID, state)
+	}
+}
+
+func runAckCommand(args []string) {
+	log := New()
+	fs, api, token := newAPIClientFlags("ack")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s ack [options] <alert-id>\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	alertID := fs.Arg(0)
+	if err := newAPIClient(*api, *token).Do(http.MethodPost, "/alerts/"+url.PathEscape(alertID)+"/ack", nil); err != nil {
+		log.Fatal("Failed to acknowledge %s: %v", alertID, err)
+	}
+	log.Success("Alert %s acknowledged", alertID)
+}
+
+func runSnoozeCommand(args []string) {
+	log := New()
+	fs, api, token := newAPIClientFlags("snooze")
+	cancel := fs.Bool("cancel", false, "Cancel an active snooze")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s snooze [options] <alert-id> <duration>\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	client := newAPIClient(*api, *token)
+	if *cancel {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		alertID := fs.Arg(0)
+		if err := client.Do(http.MethodDelete, "/alerts/"+url.PathEscape(alertID)+"/snooze", nil); err != nil {
+			log.Fatal("Failed to unsnooze %s: %v", alertID, err)
+		}
+		log.Success("Alert %s unsnoozed", alertID)
+		return
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	alertID, duration := fs.Arg(0), fs.Arg(1)
+	if _, err := time.ParseDuration(duration); err != nil {
+		log.Fatal("Invalid duration %q: %v", duration, err)
+	}
+
+	var result map[string]string
+	path := "/alerts/" + url.PathEscape(alertID) + "/snooze?duration=" + url.QueryEscape(duration)
+	if err := client.Do(http.MethodPost, path, &result); err != nil {
+		log.Fatal("Failed to snooze %s: %v", alertID, err)
+	}
+	log.Success("Alert %s snoozed until %s", alertID, result["snoozed_until"])
+}
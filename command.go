@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const commandTimeout = 30 * time.Second
+
+// runCommand runs an external tool with a timeout and returns its
+// standard output. Standard error is included in the returned error. The
+// tool is killed when ctx is done, e.g. when its check timed out.
+func runCommand(parent context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(parent, commandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if parent.Err() != nil {
+			return nil, fmt.Errorf("%s cancelled: %v", name, parent.Err())
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%s timed out after %s", name, commandTimeout)
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return stdout.Bytes(), fmt.Errorf("%s failed: %v: %s", name, err, detail)
+		}
+		return stdout.Bytes(), fmt.Errorf("%s failed: %v", name, err)
+	}
+
+	return stdout.Bytes(), nil
+}
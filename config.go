@@ -0,0 +1,194 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Interval                    int
+	CPULimit                    float64
+	MemoryLimit                 float64
+	DiskLimit                   float64
+	MemoryMinAvailableMB        float64
+	DiskMinFreeMB               float64
+	TopProcesses                int
+	Cgroups                     bool
+	CheckTimeout                time.Duration
+	AgentMemoryLimitMB          float64
+	AgentGoroutinesLimit        float64
+	WatchdogMemoryMB            float64
+	WatchdogGoroutines          int
+	History                     bool
+	HistoryFile                 string
+	HistoryRetention            time.Duration
+	DeadLetterFile              string
+	Audit                       bool
+	AuditFile                   string
+	Location                    *time.Location
+	Digest                      string
+	DigestHour                  int
+	DigestEmails                []string
+	DigestFrom                  string
+	UptimeFile                  string
+	HeartbeatFile               string
+	MountTimeout                time.Duration
+	ExpectedMounts              []string
+	DockerSocket                string
+	DockerImagesLimitMB         float64
+	DockerContainersLimitMB     float64
+	DockerVolumeLimitMB         float64
+	DockerBuildCacheLimitMB     float64
+	ExecutorContainer           string
+	ExecutorRuntimesLimit       float64
+	ExecutorMemoryLimitMB       float64
+	ExecutorOrphansLimit        float64
+	LVM                         bool
+	LVMThinDataLimit            float64
+	LVMThinMetadataLimit        float64
+	LVMSnapshotLimit            float64
+	ZFS                         bool
+	ZFSCapacityLimit            float64
+	ZFSFragmentationLimit       float64
+	ZFSScrubMaxAge              time.Duration
+	Btrfs                       bool
+	BtrfsAllocationLimit        float64
+	NVMe                        bool
+	NVMeWearLimit               float64
+	NVMeTemperatureLimit        float64
+	TemperatureLimit            float64
+	IPMI                        bool
+	Hugepages                   bool
+	HugepagesUnusedLimit        float64
+	THPMode                     string
+	THPStallLimit               float64
+	IOErrors                    bool
+	UpdatesInterval             time.Duration
+	UpdatesLimit                float64
+	SecurityUpdatesLimit        float64
+	RebootRequired              bool
+	Systemd                     bool
+	JournalErrorLimit           float64
+	JournalUnits                []string
+	SSHFailedLoginLimit         float64
+	Fail2ban                    bool
+	Fail2banBanLimit            float64
+	Firewall                    bool
+	ClosedPorts                 []int
+	MAC                         bool
+	MACDenialLimit              float64
+	Certificates                []string
+	CertificateExpiryDays       int
+	ACMECertificates            []ACMECertificate
+	ACMETimers                  []string
+	Domains                     []string
+	DomainExpiryDays            int
+	DNSBLZones                  []string
+	DNSBLIP                     string
+	MailDomains                 []MailDomain
+	SMTPAddress                 string
+	SMTPUsername                string
+	SMTPPassword                string
+	SMTPLatencyLimit            float64
+	PostgresURI                 string
+	PostgresLatencyLimit        float64
+	PostgresConnectionsLimit    float64
+	PostgresTransactionAgeLimit time.Duration
+	PostgresReplicationLagLimit time.Duration
+	MongoURI                    string
+	MongoLatencyLimit           float64
+	MongoConnectionsLimit       float64
+	MongoOplogWindowLimit       time.Duration
+	ElasticsearchURL            string
+	ElasticsearchHeapLimit      float64
+	RabbitMQURL                 string
+	RabbitMQQueueLimit          float64
+	RabbitMQUnackedLimit        float64
+	RabbitMQQueues              []RabbitMQQueue
+	TraefikURL                  string
+	TraefikErrorRateLimit       float64
+	TraefikConnectionsLimit     float64
+	NginxStatusURL              string
+	ApacheStatusURL             string
+	WebConnectionsLimit         float64
+	WebRequestRateLimit         float64
+	WebWorkersLimit             float64
+	PHPFPMStatusURL             string
+	PHPFPMQueueLimit            float64
+	HAProxyStats                string
+	HAProxyQueueLimit           float64
+	HAProxyErrorRateLimit       float64
+	HTTPChecks                  []HTTPCheck
+	HTTPTimeout                 time.Duration
+	HTTPSamples                 int
+	HTTPP50Limit                float64
+	HTTPP95Limit                float64
+	SyntheticChecks             []SyntheticCheck
+	Probes                      []Probe
+	ProbeOnly                   bool
+	RemoteHosts                 []RemoteHost
+	UPS                         []UPS
+	UPSBatteryLimit             float64
+	UPSLoadLimit                float64
+	SSHKey                      string
+	SSHKnownHosts               string
+	PerfCounters                []PerfCounter
+	Traceroute                  bool
+	SpeedtestTarget             string
+	SpeedtestInterval           time.Duration
+	SpeedtestMinDownload        float64
+	SpeedtestMinUpload          float64
+	PublicIP                    bool
+	PublicIPURL                 string
+	GeoIP                       bool
+	PublicIPDomain              string
+	Gateway                     bool
+	GatewayUpstream             string
+	GatewayMAC                  string
+	WireGuard                   bool
+	WireGuardHandshakeLimit     time.Duration
+	OpenVPNManagement           string
+	TCPRetransmitLimit          float64
+	TCPConnectLatencyLimit      float64
+	EBPF                        bool
+	Link                        bool
+	LinkInterfaces              []string
+	LinkMinSpeed                float64
+	Bonding                     bool
+	TeamInterfaces              []string
+	MTUTargets                  []string
+	MTUMin                      int
+	PingTargets                 []string
+	PingWindow                  int
+	PingLossLimit               float64
+	PingJitterLimit             float64
+	WeekOverWeek                []WeekOverWeek
+	WeekOverWeekFactor          float64
+	Smoothing                   map[string]Smoothing
+	Forecasts                   []Forecast
+	ForecastHorizon             time.Duration
+	ForecastWindow              time.Duration
+	CPUCriticalLimit            float64
+	MemoryCriticalLimit         float64
+	DiskCriticalLimit           float64
+	Routes                      Routes
+	Escalations                 Escalations
+	QuietHours                  QuietSchedule
+	ThresholdSchedules          []ThresholdSchedule
+	Rules                       []Rule
+	FileCounts                  []FileCount
+	FileAges                    []FileAge
+	Heartbeats                  []Heartbeat
+}
+
+// stringSliceFlag collects the values of a flag that may be repeated.
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Samples kept per check for sparklines, and status changes kept for the
+// recent alerts of the dashboard.
+const (
+	dashboardSamples = 60
+	dashboardEvents  = 20
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// CheckStatus is the latest result of a check with its recent values.
+type CheckStatus struct {
+	AlertID string    `json:"alert_id"`
+	Title   string    `json:"title"`
+	Status  string    `json:"status"`
+	Value   float64   `json:"value"`
+	Limit   float64   `json:"limit"`
+	Unit    string    `json:"unit"`
+	Time    time.Time `json:"time"`
+	Samples []float64 `json:"samples"`
+}
+
+// CheckEvent is a check changing between passing and failing.
+type CheckEvent struct {
+	Time    time.Time `json:"time"`
+	AlertID string    `json:"alert_id"`
+	Title   string    `json:"title"`
+	Status  string    `json:"status"`
+	Value   float64   `json:"value"`
+}
+
+// Checks is the response of GET /checks.
+type Checks struct {
+	Checks []CheckStatus `json:"checks"`
+	Events []CheckEvent  `json:"events"`
+}
+
+// checkHistory keeps the latest results of every check in memory, for
+// the dashboard of the top command.
+type checkHistory struct {
+	mu     sync.Mutex
+	checks map[string]*CheckStatus
+	events []CheckEvent
+}
+
+func newCheckHistory() *checkHistory {
+	return &checkHistory{checks: map[string]*CheckStatus{}}
+}
+
+func (h *checkHistory) Observe(metric Metric, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	check, ok := h.checks[metric.AlertID]
+	if !ok {
+		check = &CheckStatus{AlertID: metric.AlertID}
+		h.checks[metric.AlertID] = check
+	}
+	if ok && check.Status != metric.Status || !ok && metric.Status == "fail" {
+		h.events = append(h.events, CheckEvent{Time: now, AlertID: metric.AlertID, Title: metric.Title, Status: metric.Status, Value: metric.Value})
+		if len(h.events) > dashboardEvents {
+			h.events = h.events[len(h.events)-dashboardEvents:]
+		}
+	}
+
+	check.Title = metric.Title
+	check.Status = metric.Status
+	check.Value = metric.Value
+	check.Limit = metric.Limit
+	check.Unit = metric.Unit
+	check.Time = now
+	// JSON can't represent them
+	if !math.IsNaN(metric.Value) && !math.IsInf(metric.Value, 0) {
+		check.Samples = append(check.Samples, metric.Value)
+		if len(check.Samples) > dashboardSamples {
+			check.Samples = check.Samples[len(check.Samples)-dashboardSamples:]
+		}
+	}
+}
+
+func (h *checkHistory) List() Checks {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := Checks{Checks: make([]CheckStatus, 0, len(h.checks)), Events: []CheckEvent{}}
+	for _, check := range h.checks {
+		status := *check
+		status.Samples = append([]float64(nil), check.Samples...)
+		if math.IsNaN(status.Value) || math.IsInf(status.Value, 0) {
+			status.Value = 0
+		}
+		result.Checks = append(result.Checks, status)
+	}
+	sort.Slice(result.Checks, func(i, j int) bool {
+		return result.Checks[i].AlertID < result.Checks[j].AlertID
+	})
+	for i := len(h.events) - 1; i >= 0; i-- {
+		event := h.events[i]
+		if math.IsNaN(event.Value) || math.IsInf(event.Value, 0) {
+			event.Value = 0
+		}
+		result.Events = append(result.Events, event)
+	}
+	return result
+}
+
+// GET /checks
+func (a *APIServer) handleChecks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, a.monitor.checks.List())
+}
+
+// sparkline draws the samples scaled from 0, or the lowest sample when
+// negative, to the highest sample or the limit, whichever is higher, so a
+// full bar means at or above the limit.
+func sparkline(samples []float64, limit float64, width int) string {
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	low, high := 0.0, limit
+	for _, sample := range samples {
+		low = math.Min(low, sample)
+		high = math.Max(high, sample)
+	}
+	var line strings.Builder
+	for _, sample := range samples {
+		index := 0
+		if high > low {
+			index = int((sample - low) / (high - low) * float64(len(sparkBlocks)-1))
+		}
+		line.WriteRune(sparkBlocks[index])
+	}
+	return line.String()
+}
+
+// truncate shortens text to width runes.
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text
+}
+
+// renderDashboard draws one frame of the top command for a terminal of
+// the given size. Failing checks are listed first.
+func renderDashboard(checks Checks, api string, fetchErr error, width, height int) string {
+	var frame strings.Builder
+	failing := 0
+	for _, check := range checks.Checks {
+		if check.Status == "fail" {
+			failing++
+		}
+	}
+
+	header := fmt.Sprintf("monitoring top - %s - %s - %d checks, %d failing", api, time.Now().Format("15:04:05"), len(checks.Checks), failing)
+	frame.WriteString(truncate(header, width) + "\n")
+	if fetchErr != nil {
+		frame.WriteString(colorRed + truncate(fmt.Sprintf("Failed to refresh: %v", fetchErr), width) + colorReset + "\n")
+	} else {
+		frame.WriteString("\n")
+	}
+
+	sort.SliceStable(checks.Checks, func(i, j int) bool {
+		return checks.Checks[i].Status == "fail" && checks.Checks[j].Status != "fail"
+	})
+
+	// STATUS CHECK VALUE LIMIT HISTORY
+	idWidth := 36
+	sparkWidth := width - idWidth - 6 - 2*12 - 4
+	if sparkWidth < 0 {
+		sparkWidth = 0
+	}
+	if sparkWidth > dashboardSamples {
+		sparkWidth = dashboardSamples
+	}
+	frame.WriteString(fmt.Sprintf("%-6s %-*s %11s %11s %s\n", "STATUS", idWidth, "CHECK", "VALUE", "LIMIT", "HISTORY"))
+
+	events := len(checks.Events)
+	if events > 5 {
+		events = 5
+	}
+	rows := height - 4
+	if events > 0 {
+		rows -= events + 2
+	}
+	for i, check := range checks.Checks {
+		if i >= rows {
+			frame.WriteString(fmt.Sprintf("… %d more\n", len(checks.Checks)-i))
+			break
+		}
+		color, status := colorGreen, "PASS"
+		if check.Status == "fail" {
+			color, status = colorRed, "FAIL"
+		}
+		frame.WriteString(fmt.Sprintf("%s%-6s%s %-*s %11s %11s %s%s%s\n",
+			color, status, colorReset,
+			idWidth, truncate(check.AlertID, idWidth),
+			truncate(formatValue(check.Value, check.Unit), 11), truncate(formatValue(check.Limit, check.Unit), 11),
+			colorCyan, sparkline(check.Samples, check.Limit, sparkWidth), colorReset))
+	}
+
+	if events > 0 {
+		frame.WriteString("\nRECENT ALERTS\n")
+		for _, event := range checks.Events[:events] {
+			color, status := colorGreen, "RESOLVED"
+			if event.Status == "fail" {
+				color, status = colorRed, "FAILING "
+			}
+			line := fmt.Sprintf("%s %s %s (%.2f)", event.Time.Local().Format("15:04:05"), status, event.Title, event.Value)
+			frame.WriteString(color + truncate(line, width) + colorReset + "\n")
+		}
+	}
+	return frame.String()
+}
+
+func runTopCommand(args []string) {
+	log := New()
+	fs, api, token := newAPIClientFlags("top")
+	interval := fs.Duration("interval", 2*time.Second, "How often the dashboard is refreshed")
+	fs.Parse(args)
+	if *interval < 100*time.Millisecond {
+		log.Fatal("Invalid --interval %s: at least 100ms is required", *interval)
+	}
+
+	client := newAPIClient(*api, *token)
+	var checks Checks
+	if err := client.Do(http.MethodGet, "/checks", &checks); err != nil {
+		log.Fatal("Failed to read checks: %v", err)
+	}
+
+	// Alternate screen and hidden cursor, restored on exit
+	fmt.Print("\033[?1049h\033[?25l")
+	restore := func() {
+		fmt.Print("\033[?25h\033[?1049l")
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	var fetchErr error
+	for {
+		width, height := terminalSize()
+		fmt.Print("\033[H\033[2J" + renderDashboard(checks, *api, fetchErr, width, height))
+
+		select {
+		case <-signals:
+			restore()
+			return
+		case <-ticker.C:
+		}
+		var latest Checks
+		if fetchErr = client.Do(http.MethodGet, "/checks", &latest); fetchErr == nil {
+			checks = latest
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// deadLetter is an alert a sink failed to deliver after its retries, with
+// the routing metadata needed to send it again.
+type deadLetter struct {
+	Time     time.Time         `json:"time"`
+	Sink     string            `json:"sink"`
+	Reason   string            `json:"reason"`
+	Attempts int               `json:"attempts"`
+	Name     string            `json:"name"`
+	Severity string            `json:"severity"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Resolved bool              `json:"resolved,omitempty"`
+	Payload  Metric            `json:"payload"`
+}
+
+// Metric returns the alert with its routing metadata restored.
+func (d deadLetter) Metric() Metric {
+	metric := d.Payload
+	metric.Name = d.Name
+	metric.Severity = d.Severity
+	metric.Labels = d.Labels
+	metric.Resolved = d.Resolved
+	return metric
+}
+
+// RedeliveryResult is the outcome of replaying the dead-letter queue.
+// Remaining includes the alerts of other sinks.
+type RedeliveryResult struct {
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	Remaining int `json:"remaining"`
+}
+
+// deadLetterQueue keeps undeliverable alerts in a JSON lines file until
+// they are redelivered. Without a path, failed deliveries are only logged.
+type deadLetterQueue struct {
+	mu   sync.Mutex
+	path string
+
+	// Sinks and alert IDs with queued alerts, nil until first read
+	pending map[string]bool
+}
+
+func newDeadLetterQueue(path string) *deadLetterQueue {
+	return &deadLetterQueue{path: path}
+}
+
+// Add queues an alert the sink failed to deliver. An alert already queued
+// for the sink with the same status is replaced by the newer one, so an
+// outage of a sink doesn't add a line per alert every cycle.
+func (q *deadLetterQueue) Add(sink string, metric Metric, reason error) error {
+	if q.path == "" {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	letters, err := q.read()
+	if err != nil {
+		return err
+	}
+	letter := deadLetter{
+		Time:     time.Now(),
+		Sink:     sink,
+		Reason:   reason.Error(),
+		Attempts: 1,
+		Name:     metric.Name,
+		Severity: metric.Severity,
+		Labels:   metric.Labels,
+		Resolved: metric.Resolved,
+		Payload:  metric,
+	}
+	replaced := false
+	for i, queued := range letters {
+		if queued.Sink == sink && queued.Payload.AlertID == metric.AlertID && queued.Payload.Status == metric.Status {
+			letter.Attempts += queued.Attempts
+			letters[i] = letter
+			replaced = true
+		}
+	}
+	if !replaced {
+		letters = append(letters, letter)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o755); err != nil {
+		return err
+	}
+	return q.write(letters)
+}
+
+// Delivered drops the queued alerts of an alert the sink has since
+// delivered, so a redelivery doesn't page again for a stale state.
+func (q *deadLetterQueue) Delivered(sink, alertID string) error {
+	if q.path == "" {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending == nil {
+		letters, err := q.read()
+		if err != nil {
+			return err
+		}
+		q.index(letters)
+	}
+	if !q.pending[sink+"\x00"+alertID] {
+		return nil
+	}
+
+	letters, err := q.read()
+	if err != nil {
+		return err
+	}
+	var remaining []deadLetter
+	for _, letter := range letters {
+		if letter.Sink != sink || letter.Payload.AlertID != alertID {
+			remaining = append(remaining, letter)
+		}
+	}
+	return q.write(remaining)
+}
+
+func (q *deadLetterQueue) index(letters []deadLetter) {
+	q.pending = map[string]bool{}
+	for _, letter := range letters {
+		q.pending[letter.Sink+"\x00"+letter.Payload.AlertID] = true
+	}
+}
+
+// List returns the queued alerts, oldest first.
+func (q *deadLetterQueue) List() ([]deadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.read()
+}
+
+func (q *deadLetterQueue) read() ([]deadLetter, error) {
+	if q.path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var letters []deadLetter
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var letter deadLetter
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err == nil {
+			letters = append(letters, letter)
+		}
+	}
+	return letters, scanner.Err()
+}
+
+func (q *deadLetterQueue) write(letters []deadLetter) error {
+	q.index(letters)
+	if len(letters) == 0 {
+		if err := os.Remove(q.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var data bytes.Buffer
+	for _, letter := range letters {
+		line, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+		data.Write(append(line, '\n'))
+	}
+	return writeFileAtomic(q.path, data.Bytes())
+}
+
+// Redeliver sends the queued alerts of the sink, or of every sink when
+// empty, and keeps those that fail again with the new reason. The queue is
+// saved after every delivery, so an interrupted redelivery doesn't send
+// alerts twice.
+func (q *deadLetterQueue) Redeliver(sink string, send func(deadLetter) error) (RedeliveryResult, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	letters, err := q.read()
+	if err != nil {
+		return RedeliveryResult{}, err
+	}
+
+	var result RedeliveryResult
+	for i := 0; i < len(letters); i++ {
+		letter := letters[i]
+		if sink != "" && letter.Sink != sink {
+			continue
+		}
+		if err := send(letter); err != nil {
+			letters[i].Reason = err.Error()
+			letters[i].Attempts++
+			result.Failed++
+			continue
+		}
+		result.Delivered++
+		letters = append(letters[:i], letters[i+1:]...)
+		i--
+		if err := q.write(letters); err != nil {
+			return result, err
+		}
+	}
+	result.Remaining = len(letters)
+	return result, q.write(letters)
+}
+
+// redeliver replays the dead-letter queue to the configured sinks.
+func (s *SystemMonitor) redeliver(sink string) (RedeliveryResult, error) {
+	return s.deadLetter.Redeliver(sink, func(letter deadLetter) error {
+		for _, target := range s.sinks {
+			if target.Name() != letter.Sink {
+				continue
+			}
+			metric := letter.Metric()
+			if !accepts(target, metric) {
+				// Queued before the sink filtered it out, nothing to send
+				s.log.Log("Dropping %s, %s no longer sends it", letter.Payload.AlertID, target.Name())
+				return nil
+			}
+			start := time.Now()
+			err := target.Send(metric)
+			if !errors.Is(err, errCircuitOpen) {
+				s.deliveries.Record(target.Name(), time.Since(start), err)
+			}
+			if err != nil {
+				s.log.Error("Failed to redeliver %s to %s: %v", letter.Payload.AlertID, target.Name(), err)
+				return err
+			}
+			s.log.Success("Redelivered %s to %s", letter.Payload.AlertID, target.Name())
+			return nil
+		}
+		return fmt.Errorf("sink %s is not configured", letter.Sink)
+	})
+}
+
+// GET /dead-letters
+func (a *APIServer) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	letters, err := a.monitor.deadLetter.List()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if letters == nil {
+		letters = []deadLetter{}
+	}
+	writeJSON(w, http.StatusOK, letters)
+}
+
+// POST /dead-letters/redeliver?sink={sink}
+func (a *APIServer) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.monitor.deadLetter.path == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dead-letter queue is disabled, see --dead-letter-file"})
+		return
+	}
+	result, err := a.monitor.redeliver(r.URL.Query().Get("sink"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	a.log.Info("Redelivered %d dead letters, %d failed, %d remaining", result.Delivered, result.Failed, result.Remaining)
+	writeJSON(w, http.StatusOK, result)
+}
+
+func runRedeliverCommand(args []string) {
+	log := New()
+	fs, api, token := newAPIClientFlags("redeliver")
+	sink := fs.String("sink", "", "Only redeliver alerts of this sink, e.g. \"slack\"")
+	list := fs.Bool("list", false, "List the dead letters instead of redelivering them")
+	fs.Parse(args)
+
+	client := newAPIClient(*api, *token)
+	if *list {
+		var letters []deadLetter
+		if err := client.Do(http.MethodGet, "/dead-letters", &letters); err != nil {
+			log.Fatal("Failed to list dead letters: %v", err)
+		}
+		if len(letters) == 0 {
+			fmt.Println("No dead letters")
+			return
+		}
+		for _, letter := range letters {
+			fmt.Printf("%s %-12s %-40s %dx %s\n", letter.Time.Format("2006-01-02 15:04:05"), letter.Sink, letter.Payload.AlertID, letter.Attempts, letter.Reason)
+		}
+		return
+	}
+
+	path := "/dead-letters/redeliver"
+	if *sink != "" {
+		path += "?sink=" + url.QueryEscape(*sink)
+	}
+	var result RedeliveryResult
+	if err := client.Do(http.MethodPost, path, &result); err != nil {
+		log.Fatal("Failed to redeliver: %v", err)
+	}
+	if result.Failed > 0 {
+		log.Warn("Redelivered %d alerts, %d failed again and remain queued", result.Delivered, result.Failed)
+		os.Exit(1)
+	}
+	log.Success("Redelivered %d alerts, %d remain queued", result.Delivered, result.Remaining)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxDeliveryAttempts = 3
+	maxResponseBodySize = 64 * 1024
+	maxRetryDelay       = 60 * time.Second
+
+	// maxDeliveryDuration is the longest a delivery with its retries
+	// takes, the requests of sinks time out after 5 seconds. Sink timeouts
+	// are at least this long, so a delivery the breaker gave up on and
+	// dead-lettered can't still succeed in the background.
+	maxDeliveryDuration = maxDeliveryAttempts*5*time.Second + (maxDeliveryAttempts-1)*maxRetryDelay
+
+	// RFC 3339 with millisecond precision, for the time of alerts
+	rfc3339Milli = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// newEventID returns a random UUID for an alert event.
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// Never expected, fall back to something unique enough
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// deliver sends req and reads at most maxResponseBodySize bytes of the
+// response. Rate limited (429) and unavailable (503) responses are retried
+// after the delay announced by the receiver, error bodies are logged so
+// rejected payloads can be diagnosed.
+func deliver(client *http.Client, req *http.Request, name string, log *Logger) error {
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return fmt.Errorf("failed to rewind request body: %v", err)
+			}
+			req.Body = body
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %v", err)
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+		resp.Body.Close()
+
+		log.Log("Response Status from %s: %s", name, resp.Status)
+
+		if remaining := rateLimitHeader(resp.Header, "Remaining"); remaining == "0" {
+			log.Warn("Rate limit of %s exhausted, resets in %s", name, retryDelay(resp.Header, attempt))
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			delay := retryDelay(resp.Header, attempt)
+			if attempt < maxDeliveryAttempts && delay <= maxRetryDelay {
+				log.Warn("%s responded with %d, retrying in %s (attempt %d of %d)", name, resp.StatusCode, delay, attempt, maxDeliveryAttempts)
+				time.Sleep(delay)
+				continue
+			}
+		}
+
+		if resp.StatusCode >= 400 {
+			detail := strings.TrimSpace(string(body))
+			if detail != "" {
+				log.Error("Response body from %s: %s", name, detail)
+			}
+			if len(detail) > 512 {
+				detail = detail[:512] + "..."
+			}
+			return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, detail)
+		}
+
+		return nil
+	}
+}
+
+// retryDelay reads Retry-After (seconds or HTTP date) or a rate limit
+// reset header, falling back to a linear backoff.
+func retryDelay(header http.Header, attempt int) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return time.Until(date).Round(time.Second)
+		}
+	}
+
+	if value := rateLimitHeader(header, "Reset"); value != "" {
+		if reset, err := strconv.ParseInt(value, 10, 64); err == nil {
+			// Some APIs send a Unix timestamp, others the seconds until reset
+			if reset > 1000000000 {
+				return time.Until(time.Unix(reset, 0)).Round(time.Second)
+			}
+			return time.Duration(reset) * time.Second
+		}
+	}
+
+	return time.Duration(attempt) * time.Second
+}
+
+func rateLimitHeader(header http.Header, name string) string {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-", "X-Rate-Limit-"} {
+		if value := header.Get(prefix + name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+type DeliveryStats struct {
+	Delivered    int
+	Failed       int
+	LastError    string
+	LastDelivery time.Time
+	TotalLatency time.Duration
+}
+
+func (d *DeliveryStats) AverageLatency() time.Duration {
+	total := d.Delivered + d.Failed
+	if total == 0 {
+		return 0
+	}
+	return d.TotalLatency / time.Duration(total)
+}
+
+// deliveryTracker keeps per-sink delivery statistics.
+type deliveryTracker struct {
+	mu    sync.Mutex
+	stats map[string]*DeliveryStats
+}
+
+func newDeliveryTracker() *deliveryTracker {
+	return &deliveryTracker{
+		stats: map[string]*DeliveryStats{},
+	}
+}
+
+func (d *deliveryTracker) Record(sink string, latency time.Duration, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats, ok := d.stats[sink]
+	if !ok {
+		stats = &DeliveryStats{}
+		d.stats[sink] = stats
+	}
+
+	stats.TotalLatency += latency
+	if err != nil {
+		stats.Failed++
+		stats.LastError = err.Error()
+		return
+	}
+	stats.Delivered++
+	stats.LastDelivery = time.Now()
+}
+
+func (d *deliveryTracker) Snapshot() map[string]DeliveryStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snapshot := make(map[string]DeliveryStats, len(d.stats))
+	for sink, stats := range d.stats {
+		snapshot[sink] = *stats
+	}
+	return snapshot
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digestHeadlines are the values summarized in digests delivered to sinks,
+// which only have room for a line or two. Emails list every value.
+var digestHeadlines = []string{"cpu.percent", "mem.used_percent", "disk.used_percent"}
+
+// digestStat summarizes the samples of a value over the digest period.
+type digestStat struct {
+	Name string
+	Max  float64
+	Avg  float64
+	P95  float64
+}
+
+// nextDigest returns when the digest after now is due: the next
+// DigestHour o'clock, on Mondays for weekly digests.
+func nextDigest(now time.Time, period string, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	days := 1
+	if period == "weekly" {
+		days = 7
+		next = next.AddDate(0, 0, (int(time.Monday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(now) {
+		next = next.AddDate(0, 0, days)
+	}
+	return next
+}
+
+func digestLength(period string) time.Duration {
+	if period == "weekly" {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// historyStats computes the max, average and 95th percentile of every
+// value in entries, sorted by name.
+func historyStats(entries []historyEntry) []digestStat {
+	samples := map[string][]float64{}
+	for _, entry := range entries {
+		for name, value := range entry.Values {
+			samples[name] = append(samples[name], value)
+		}
+	}
+
+	stats := make([]digestStat, 0, len(samples))
+	for name, values := range samples {
+		sort.Float64s(values)
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		stats = append(stats, digestStat{
+			Name: name,
+			Max:  values[len(values)-1],
+			Avg:  sum / float64(len(values)),
+			P95:  percentile(values, 95),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// noisiestAlerts returns the alert IDs that started failing most often,
+// at most limit of them.
+func noisiestAlerts(failures map[string]int, limit int) []string {
+	ids := make([]string, 0, len(failures))
+	for id := range failures {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if failures[ids[i]] != failures[ids[j]] {
+			return failures[ids[i]] > failures[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// buildDigest returns a one line summary for sinks and the full report
+// for email.
+func (s *SystemMonitor) buildDigest(from, to time.Time) (string, string, error) {
+	entries, err := readHistory(s.config.HistoryFile, from, to, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read history: %v", err)
+	}
+	stats := historyStats(entries)
+	failures := s.alerts.TakeFailures()
+	total := 0
+	for _, count := range failures {
+		total += count
+	}
+	noisy := noisiestAlerts(failures, 5)
+
+	var summary []string
+	alerts := fmt.Sprintf("%d alert failures", total)
+	if len(noisy) > 0 {
+		var top []string
+		for _, id := range noisy {
+			top = append(top, fmt.Sprintf("%s %dx", id, failures[id]))
+		}
+		alerts += fmt.Sprintf(" (noisiest: %s)", strings.Join(top, ", "))
+	}
+	summary = append(summary, alerts)
+
+	// Availability over the window matching the digest period
+	window := "24h"
+	if digestLength(s.config.Digest) > 24*time.Hour {
+		window = "7d"
+	}
+	var degraded []UptimeStatus
+	for _, status := range s.uptime.List(to) {
+		if status.Availability[window] < 100 {
+			degraded = append(degraded, status)
+		}
+	}
+	sort.SliceStable(degraded, func(i, j int) bool {
+		return degraded[i].Availability[window] < degraded[j].Availability[window]
+	})
+	held := s.held.Take()
+	if len(held) > 0 {
+		summary = append(summary, fmt.Sprintf("%d alerts held back during quiet hours", len(held)))
+	}
+	if len(degraded) > 0 {
+		summary = append(summary, fmt.Sprintf("lowest availability %s %.2f%%", degraded[0].AlertID, degraded[0].Availability[window]))
+	}
+	for _, stat := range stats {
+		for _, headline := range digestHeadlines {
+			if stat.Name == headline {
+				summary = append(summary, fmt.Sprintf("%s max %.1f, avg %.1f, p95 %.1f", stat.Name, stat.Max, stat.Avg, stat.P95))
+			}
+		}
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Health summary of %s from %s to %s.\n\n", s.hostname, from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&report, "Alerts: %d failures of %d alerts\n", total, len(failures))
+	for _, id := range noisy {
+		fmt.Fprintf(&report, "  %-50s %d\n", id, failures[id])
+	}
+	if len(held) > 0 {
+		report.WriteString("\nHeld back during quiet hours:\n")
+	}
+	for _, alert := range held {
+		fmt.Fprintf(&report, "  %s %s (%d times, last %s): %s\n", alert.First.Format("2006-01-02 15:04"), alert.Title, alert.Count, alert.Last.Format("15:04"), alert.Cause)
+	}
+	fmt.Fprintf(&report, "\nAvailability below 100%% over %s (24h / 7d / 30d):\n", window)
+	if len(degraded) == 0 {
+		report.WriteString("  Every check passed the whole period\n")
+	}
+	for _, status := range degraded {
+		fmt.Fprintf(&report, "  %-50s %11.3f%% %11.3f%% %11.3f%%\n", status.AlertID, status.Availability["24h"], status.Availability["7d"], status.Availability["30d"])
+	}
+	fmt.Fprintf(&report, "\nValues over %d cycles (max / avg / p95):\n", len(entries))
+	for _, stat := range stats {
+		fmt.Fprintf(&report, "  %-50s %12.2f %12.2f %12.2f\n", stat.Name, stat.Max, stat.Avg, stat.P95)
+	}
+
+	return strings.Join(summary, "; "), report.String(), nil
+}
+
+// checkDigest sends the daily or weekly health summary once it is due.
+// The first digest is sent at the first due time after startup.
+func (s *SystemMonitor) checkDigest(ctx context.Context) error {
+	now := s.now()
+	if s.digestDue.IsZero() {
+		s.digestDue = nextDigest(now, s.config.Digest, s.config.DigestHour)
+		return nil
+	}
+	if now.Before(s.digestDue) {
+		return nil
+	}
+	s.digestDue = nextDigest(now, s.config.Digest, s.config.DigestHour)
+
+	summary, report, err := s.buildDigest(now.Add(-digestLength(s.config.Digest)), now)
+	if err != nil {
+		return err
+	}
+	title := fmt.Sprintf("Daily Digest - %s", s.hostname)
+	if s.config.Digest == "weekly" {
+		title = fmt.Sprintf("Weekly Digest - %s", s.hostname)
+	}
+	s.log.Info("%s: %s", title, summary)
+
+	if len(s.config.DigestEmails) > 0 {
+		from := s.config.DigestFrom
+		if from == "" {
+			from = "monitoring@" + s.hostname
+		}
+		if err := s.sendMail(from, s.config.DigestEmails, title, report); err != nil {
+			s.log.Error("Failed to email digest: %v", err)
+		}
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "digest",
+		Title:     title,
+		Cause:     summary,
+		AlertID:   fmt.Sprintf("digest-%s", s.hostname),
+		Timestamp: now.Unix(),
+		Status:    "pass",
+		Value:     0,
+		Limit:     0,
+		Severity:  SeverityInfo,
+		Labels:    map[string]string{"period": s.config.Digest},
+	})
+}
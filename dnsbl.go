@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// dnsblCheckInterval keeps lookups well below the free usage limits of
+// the public blocklists.
+const dnsblCheckInterval = time.Hour
+
+// outboundIP returns the source address the host uses for outgoing
+// traffic. Connecting a UDP socket sends no packets.
+func outboundIP() (net.IP, error) {
+	conn, err := net.Dial("udp4", "1.1.1.1:53")
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine outbound IP: %v", err)
+	}
+	defer conn.Close()
+
+	ip := conn.LocalAddr().(*net.UDPAddr).IP
+	if ip.IsPrivate() || ip.IsLoopback() {
+		return nil, fmt.Errorf("outbound IP %s is private, set --dnsbl-ip to the public address", ip)
+	}
+	return ip, nil
+}
+
+// dnsblListed looks up an IPv4 address in a DNS blocklist zone, e.g.
+// 4.3.2.1.zen.spamhaus.org for 1.2.3.4. Listed addresses resolve to
+// 127.0.0.x, unlisted ones do not resolve.
+func dnsblListed(ip net.IP, zone string) (bool, string, error) {
+	octets := strings.Split(ip.To4().String(), ".")
+	query := fmt.Sprintf("%s.%s.%s.%s.%s", octets[3], octets[2], octets[1], octets[0], zone)
+
+	addresses, err := net.LookupHost(query)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+
+	for _, address := range addresses {
+		// Spamhaus answers 127.255.255.x to queries through public resolvers
+		if strings.HasPrefix(address, "127.255.255.") {
+			return false, "", fmt.Errorf("%s refused the query (%s), use a non-public resolver", zone, address)
+		}
+	}
+	return true, strings.Join(addresses, ", "), nil
+}
+
+// checkDNSBL alerts when the host's outbound IP is listed on one of the
+// configured blocklists, as email delivery quietly breaks once it is.
+func (s *SystemMonitor) checkDNSBL(ctx context.Context) error {
+	if time.Since(s.dnsblAt) < dnsblCheckInterval {
+		return nil
+	}
+	s.dnsblAt = time.Now()
+
+	ip := net.ParseIP(s.config.DNSBLIP)
+	if ip == nil {
+		var err error
+		if ip, err = outboundIP(); err != nil {
+			return err
+		}
+	}
+
+	for _, zone := range s.config.DNSBLZones {
+		listed, codes, err := dnsblListed(ip, zone)
+		if err != nil {
+			s.log.Error("Failed to look up %s in %s: %v", ip, zone, err)
+			continue
+		}
+
+		status := "pass"
+		cause := fmt.Sprintf("%s is not listed on %s", ip, zone)
+		value := 0.0
+		if listed {
+			status = "fail"
+			cause = fmt.Sprintf("%s is listed on %s (%s)", ip, zone, codes)
+			value = 1
+			s.log.Warn("%s", cause)
+		} else {
+			s.log.Log("%s", cause)
+		}
+		s.recordValue(valueName("dnsbl", zone, "listed"), value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "dnsbl",
+			Title:     fmt.Sprintf("DNS Blocklist %s - %s", zone, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("dnsbl-%s-%s", valueName(zone), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"zone": zone, "ip": ip.String()},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// dockerClient talks to the Docker Engine API over its unix socket.
+type dockerClient struct {
+	httpClient *http.Client
+}
+
+func newDockerClient(socket string) *dockerClient {
+	return &dockerClient{
+		httpClient: &http.Client{
+			// system/df walks every layer and volume and can be slow
+			Timeout: 60 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+func (d *dockerClient) Get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker"+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query Docker API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Docker API request %s failed with status: %d", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode Docker API response: %v", err)
+	}
+	return nil
+}
+
+type dockerDiskUsage struct {
+	LayersSize int64 `json:"LayersSize"`
+	Images     []struct {
+		ID         string   `json:"Id"`
+		RepoTags   []string `json:"RepoTags"`
+		Size       int64    `json:"Size"`
+		SharedSize int64    `json:"SharedSize"`
+	} `json:"Images"`
+	Containers []struct {
+		ID     string   `json:"Id"`
+		Names  []string `json:"Names"`
+		SizeRw int64    `json:"SizeRw"`
+	} `json:"Containers"`
+	Volumes []struct {
+		Name      string `json:"Name"`
+		UsageData struct {
+			Size     int64 `json:"Size"`
+			RefCount int64 `json:"RefCount"`
+		} `json:"UsageData"`
+	} `json:"Volumes"`
+	BuildCache []struct {
+		Size int64 `json:"Size"`
+	} `json:"BuildCache"`
+}
+
+func (s *SystemMonitor) checkDocker(ctx context.Context) error {
+	var usage dockerDiskUsage
+	if err := s.docker.Get(ctx, "/system/df", &usage); err != nil {
+		return err
+	}
+
+	var containersSize, buildCacheSize int64
+	for _, container := range usage.Containers {
+		containersSize += container.SizeRw
+	}
+	for _, cache := range usage.BuildCache {
+		buildCacheSize += cache.Size
+	}
+
+	imagesMB := float64(usage.LayersSize) / (1024 * 1024)
+	containersMB := float64(containersSize) / (1024 * 1024)
+	buildCacheMB := float64(buildCacheSize) / (1024 * 1024)
+	s.recordValue("docker.images_mb", imagesMB)
+	s.recordValue("docker.containers_mb", containersMB)
+	s.recordValue("docker.build_cache_mb", buildCacheMB)
+
+	s.log.Log("Docker disk usage: Images: %.0f MB (%d), Containers: %.0f MB (%d), Volumes: %d, Build cache: %.0f MB",
+		imagesMB, len(usage.Images), containersMB, len(usage.Containers), len(usage.Volumes), buildCacheMB)
+
+	totals := []struct {
+		kind  string
+		title string
+		value float64
+		limit float64
+	}{
+		{"images", "Docker Images", imagesMB, s.config.DockerImagesLimitMB},
+		{"containers", "Docker Containers", containersMB, s.config.DockerContainersLimitMB},
+		{"build-cache", "Docker Build Cache", buildCacheMB, s.config.DockerBuildCacheLimitMB},
+	}
+	for _, total := range totals {
+		if total.limit <= 0 {
+			continue
+		}
+		if err := s.sendDockerMetric(total.kind, total.title, total.value, total.limit, nil); err != nil {
+			return err
+		}
+	}
+
+	// Largest volumes first, so they show up first in the log
+	volumes := usage.Volumes
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].UsageData.Size > volumes[j].UsageData.Size
+	})
+	for _, volume := range volumes {
+		// Size is -1 when Docker couldn't compute it
+		if volume.UsageData.Size < 0 {
+			continue
+		}
+		sizeMB := float64(volume.UsageData.Size) / (1024 * 1024)
+		s.recordValue(valueName("docker", "volumes", volume.Name, "mb"), sizeMB)
+
+		if s.config.DockerVolumeLimitMB <= 0 {
+			continue
+		}
+		title := fmt.Sprintf("Docker Volume %s", volume.Name)
+		if err := s.sendDockerMetric("volume-"+volume.Name, title, sizeMB, s.config.DockerVolumeLimitMB, map[string]string{"volume": volume.Name}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SystemMonitor) sendDockerMetric(kind, title string, value, limit float64, labels map[string]string) error {
+	status := s.getStatus(value, limit)
+	if status == "fail" {
+		s.log.Warn("%s usage %.0f MB exceeds limit of %.0f MB", title, value, limit)
+	} else {
+		s.log.Log("%s usage: %.0f MB (limit: %.0f MB)", title, value, limit)
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "docker",
+		Title:     fmt.Sprintf("%s Disk Usage - %s", title, s.hostname),
+		Cause:     "Docker disk usage monitoring check",
+		AlertID:   fmt.Sprintf("docker-%s-%s", kind, s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     limit,
+		Unit:      UnitMB,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+		Labels:    labels,
+	})
+}
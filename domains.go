@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	rdapBootstrapURL = "https://data.iana.org/rdap/dns.json"
+	// Registration data changes rarely and RDAP servers rate limit
+	domainCheckInterval = 12 * time.Hour
+)
+
+// rdapClient looks up domain registrations via RDAP, finding the server
+// responsible for a TLD through the IANA bootstrap registry.
+type rdapClient struct {
+	httpClient *http.Client
+	servers    map[string]string
+}
+
+func newRDAPClient() *rdapClient {
+	return &rdapClient{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (c *rdapClient) server(tld string) (string, error) {
+	if c.servers == nil {
+		var bootstrap struct {
+			Services [][][]string `json:"services"`
+		}
+		if err := getJSON(c.httpClient, rdapBootstrapURL, &bootstrap); err != nil {
+			return "", err
+		}
+
+		c.servers = map[string]string{}
+		for _, service := range bootstrap.Services {
+			if len(service) != 2 || len(service[1]) == 0 {
+				continue
+			}
+			for _, name := range service[0] {
+				c.servers[name] = strings.TrimSuffix(service[1][0], "/")
+			}
+		}
+	}
+
+	server, ok := c.servers[tld]
+	if !ok {
+		return "", fmt.Errorf("no RDAP server for .%s", tld)
+	}
+	return server, nil
+}
+
+// Expiration returns the registration expiry date of a domain.
+func (c *rdapClient) Expiration(domain string) (time.Time, error) {
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	server, err := c.server(tld)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var registration struct {
+		Events []struct {
+			Action string    `json:"eventAction"`
+			Date   time.Time `json:"eventDate"`
+		} `json:"events"`
+	}
+	if err := getJSON(c.httpClient, server+"/domain/"+domain, &registration); err != nil {
+		return time.Time{}, err
+	}
+
+	for _, event := range registration.Events {
+		if event.Action == "expiration" {
+			return event.Date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no expiration date in registration of %s", domain)
+}
+
+// checkDomains alerts when a domain registration expires within
+// DomainExpiryDays. An expired domain takes the deployment down no matter
+// how healthy the host is.
+func (s *SystemMonitor) checkDomains(ctx context.Context) error {
+	if time.Since(s.domainsAt) < domainCheckInterval {
+		return nil
+	}
+	s.domainsAt = time.Now()
+
+	limit := float64(s.config.DomainExpiryDays)
+	for _, domain := range s.config.Domains {
+		expiration, err := s.rdap.Expiration(domain)
+		if err != nil {
+			s.log.Error("Failed to look up registration of %s: %v", domain, err)
+			continue
+		}
+
+		days := time.Until(expiration).Hours() / 24
+		s.recordValue(valueName("domain", domain, "days_left"), days)
+
+		status := "pass"
+		if days < limit {
+			status = "fail"
+			s.log.Warn("Domain %s expires in %.1f days", domain, days)
+		} else {
+			s.log.Log("Domain %s expires in %.0f days", domain, days)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "domain",
+			Title:     fmt.Sprintf("Domain Expiry %s - %s", domain, s.hostname),
+			Cause:     fmt.Sprintf("Registration expires on %s", expiration.Format("2006-01-02")),
+			AlertID:   fmt.Sprintf("domain-%s-%s", valueName(domain), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     days,
+			Limit:     limit,
+			Unit:      UnitDays,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, days, 0),
+			Labels:    map[string]string{"domain": domain},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
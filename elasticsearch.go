@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type clusterHealth struct {
+	ClusterName      string  `json:"cluster_name"`
+	Status           string  `json:"status"`
+	UnassignedShards float64 `json:"unassigned_shards"`
+}
+
+type nodesJVMStats struct {
+	Nodes map[string]struct {
+		Name string `json:"name"`
+		JVM  struct {
+			Mem struct {
+				HeapUsedPercent float64 `json:"heap_used_percent"`
+			} `json:"mem"`
+		} `json:"jvm"`
+	} `json:"nodes"`
+}
+
+// checkElasticsearch reads the cluster health and the JVM heap usage of
+// each node. Elasticsearch and OpenSearch share both APIs.
+func (s *SystemMonitor) checkElasticsearch(ctx context.Context) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	base := strings.TrimSuffix(s.config.ElasticsearchURL, "/")
+
+	var health clusterHealth
+	if err := getJSON(client, base+"/_cluster/health", &health); err != nil {
+		s.log.Warn("Elasticsearch is not responding: %v", err)
+		return s.sendMetric(Metric{
+			Name:      "elasticsearch",
+			Title:     fmt.Sprintf("Elasticsearch Cluster Health - %s", s.hostname),
+			Cause:     fmt.Sprintf("Failed to get cluster health: %v", err),
+			AlertID:   fmt.Sprintf("elasticsearch-health-%s", s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    "fail",
+			Value:     0,
+			Limit:     0,
+			Unit:      UnitCount,
+			Type:      TypeGauge,
+			Severity:  SeverityCritical,
+		})
+	}
+
+	status := "pass"
+	severity := SeverityInfo
+	switch health.Status {
+	case "green":
+	case "yellow":
+		status = "fail"
+		severity = SeverityWarning
+	default:
+		status = "fail"
+		severity = SeverityCritical
+	}
+	s.recordValue("elasticsearch.unassigned_shards", health.UnassignedShards)
+	cause := fmt.Sprintf("Cluster %s is %s, %.0f unassigned shards", health.ClusterName, health.Status, health.UnassignedShards)
+	if status == "fail" {
+		s.log.Warn("%s", cause)
+	} else {
+		s.log.Log("%s", cause)
+	}
+
+	if err := s.sendMetric(Metric{
+		Name:      "elasticsearch",
+		Title:     fmt.Sprintf("Elasticsearch Cluster Health - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("elasticsearch-health-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     health.UnassignedShards,
+		Limit:     0,
+		Unit:      UnitCount,
+		Type:      TypeGauge,
+		Severity:  severity,
+		Labels:    map[string]string{"cluster": health.ClusterName},
+	}); err != nil {
+		return err
+	}
+
+	var stats nodesJVMStats
+	if err := getJSON(client, base+"/_nodes/stats/jvm", &stats); err != nil {
+		return fmt.Errorf("failed to get node stats: %v", err)
+	}
+
+	for _, node := range stats.Nodes {
+		value := node.JVM.Mem.HeapUsedPercent
+		s.recordValue(valueName("elasticsearch", node.Name, "heap_percent"), value)
+		status := s.getStatus(value, s.config.ElasticsearchHeapLimit)
+		if status == "fail" {
+			s.log.Warn("Elasticsearch node %s heap %.0f%% exceeds limit of %.0f%%", node.Name, value, s.config.ElasticsearchHeapLimit)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "elasticsearch",
+			Title:     fmt.Sprintf("Elasticsearch Heap %s - %s", node.Name, s.hostname),
+			Cause:     fmt.Sprintf("JVM heap of node %s is %.0f%% used", node.Name, value),
+			AlertID:   fmt.Sprintf("elasticsearch-heap-%s-%s", valueName(node.Name), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     s.config.ElasticsearchHeapLimit,
+			Unit:      UnitPercent,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"cluster": health.ClusterName, "node": node.Name},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
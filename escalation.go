@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Escalation re-sends alerts that keep failing for longer than Delay to
+// additional sinks. Sinks used for escalation only receive escalated alerts.
+type Escalation struct {
+	Delay time.Duration
+	sinks map[string]bool
+}
+
+type Escalations []Escalation
+
+// ParseEscalation parses a policy of the form "<delay>:<sinks>", for
+// example "15m:pushover,twilio".
+func ParseEscalation(value string, sinks []Sink) (Escalation, error) {
+	delay, names, ok := strings.Cut(value, ":")
+	if !ok {
+		return Escalation{}, fmt.Errorf("expected <delay>:<sinks>")
+	}
+
+	duration, err := time.ParseDuration(strings.TrimSpace(delay))
+	if err != nil {
+		return Escalation{}, fmt.Errorf("invalid delay: %v", err)
+	}
+	if duration <= 0 {
+		return Escalation{}, fmt.Errorf("delay must be greater than 0")
+	}
+
+	known := map[string]bool{}
+	for _, sink := range sinks {
+		known[sink.Name()] = true
+	}
+
+	escalation := Escalation{
+		Delay: duration,
+		sinks: map[string]bool{},
+	}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return Escalation{}, fmt.Errorf("unknown sink %q", name)
+		}
+		escalation.sinks[name] = true
+	}
+	if len(escalation.sinks) == 0 {
+		return Escalation{}, fmt.Errorf("no sinks given")
+	}
+
+	return escalation, nil
+}
+
+func (e Escalations) IsEscalationSink(name string) bool {
+	for _, escalation := range e {
+		if escalation.sinks[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// Reached returns the escalations whose delay has passed.
+func (e Escalations) Reached(failingFor time.Duration) Escalations {
+	var reached Escalations
+	for _, escalation := range e {
+		if failingFor >= escalation.Delay {
+			reached = append(reached, escalation)
+		}
+	}
+	return reached
+}
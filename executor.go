@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runtimeLabel is set by the open-runtimes executor on every runtime
+// container it starts, with the executor's hostname as value.
+const runtimeLabel = "openruntimes-executor"
+
+type dockerContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+}
+
+func (c dockerContainer) Name() string {
+	if len(c.Names) == 0 {
+		return c.ID[:12]
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+type dockerContainerInspect struct {
+	RestartCount int `json:"RestartCount"`
+	Config       struct {
+		Hostname string `json:"Hostname"`
+	} `json:"Config"`
+	State struct {
+		Status  string `json:"Status"`
+		Running bool   `json:"Running"`
+		Health  *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+	} `json:"State"`
+}
+
+type dockerContainerStats struct {
+	MemoryStats struct {
+		Usage float64 `json:"usage"`
+		Stats struct {
+			InactiveFile float64 `json:"inactive_file"`
+		} `json:"stats"`
+	} `json:"memory_stats"`
+}
+
+// checkExecutor monitors the open-runtimes executor of Appwrite Functions
+// and the runtime containers it manages: executor health, runtime count
+// and memory, runtimes stuck restarting and runtimes left behind.
+func (s *SystemMonitor) checkExecutor(ctx context.Context) error {
+	name := s.config.ExecutorContainer
+
+	var executor dockerContainerInspect
+	executorErr := s.docker.Get(ctx, "/containers/"+url.PathEscape(name)+"/json", &executor)
+
+	status := "pass"
+	cause := fmt.Sprintf("Executor is %s, %d restarts", executor.State.Status, executor.RestartCount)
+	switch {
+	case executorErr != nil:
+		status = "fail"
+		cause = fmt.Sprintf("Failed to inspect executor container %s: %v", name, executorErr)
+	case !executor.State.Running:
+		status = "fail"
+	case executor.State.Health != nil && executor.State.Health.Status == "unhealthy":
+		status = "fail"
+		cause = fmt.Sprintf("Executor is unhealthy, %d restarts", executor.RestartCount)
+	}
+	if status == "fail" {
+		s.log.Warn("Functions executor: %s", cause)
+	}
+
+	if err := s.sendMetric(Metric{
+		Name:      "executor",
+		Title:     fmt.Sprintf("Functions Executor - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("executor-health-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     float64(executor.RestartCount),
+		Limit:     0,
+		Unit:      UnitCount,
+		Type:      TypeCounter,
+		Severity:  SeverityCritical,
+		Labels:    map[string]string{"container": name},
+	}); err != nil {
+		return err
+	}
+
+	filters := url.QueryEscape(fmt.Sprintf(`{"label":[%q]}`, runtimeLabel))
+	var runtimes []dockerContainer
+	if err := s.docker.Get(ctx, "/containers/json?all=true&filters="+filters, &runtimes); err != nil {
+		return err
+	}
+
+	var running, memory float64
+	var restarting, orphaned []string
+	for _, runtime := range runtimes {
+		switch {
+		case runtime.State == "restarting":
+			restarting = append(restarting, runtime.Name())
+		case runtime.State != "running":
+			// Stopped runtimes are never reused by the executor
+			orphaned = append(orphaned, runtime.Name())
+		case executorErr == nil && runtime.Labels[runtimeLabel] != executor.Config.Hostname:
+			// Started by a previous executor container, e.g. before an upgrade
+			orphaned = append(orphaned, runtime.Name())
+		}
+
+		if runtime.State != "running" {
+			continue
+		}
+		running++
+		var stats dockerContainerStats
+		if err := s.docker.Get(ctx, "/containers/"+runtime.ID+"/stats?stream=false&one-shot=true", &stats); err != nil {
+			s.log.Error("Failed to get stats of runtime %s: %v", runtime.Name(), err)
+			continue
+		}
+		memory += (stats.MemoryStats.Usage - stats.MemoryStats.Stats.InactiveFile) / (1024 * 1024)
+	}
+	sort.Strings(restarting)
+	sort.Strings(orphaned)
+
+	s.recordValue("executor.runtimes", running)
+	s.recordValue("executor.runtimes_memory_mb", memory)
+	s.recordValue("executor.restarting", float64(len(restarting)))
+	s.recordValue("executor.orphaned", float64(len(orphaned)))
+	s.log.Log("Functions executor: %.0f runtimes using %.0f MB, %d restarting, %d orphaned", running, memory, len(restarting), len(orphaned))
+
+	restartingCause := "No runtimes restarting"
+	if len(restarting) > 0 {
+		restartingCause = "Restarting: " + strings.Join(restarting, ", ")
+	}
+	orphanedCause := "No orphaned runtimes"
+	if len(orphaned) > 0 {
+		orphanedCause = "Orphaned: " + strings.Join(orphaned, ", ")
+	}
+
+	// Runtime count and memory are only checked with a limit
+	checks := []struct {
+		kind     string
+		title    string
+		cause    string
+		value    float64
+		limit    float64
+		unit     string
+		optional bool
+	}{
+		{"runtimes", "Runtimes", fmt.Sprintf("%.0f runtime containers running", running), running, s.config.ExecutorRuntimesLimit, UnitCount, true},
+		{"memory", "Runtime Memory", fmt.Sprintf("Runtime containers use %.0f MB", memory), memory, s.config.ExecutorMemoryLimitMB, UnitMB, true},
+		{"restarting", "Restarting Runtimes", restartingCause, float64(len(restarting)), 0, UnitCount, false},
+		{"orphaned", "Orphaned Runtimes", orphanedCause, float64(len(orphaned)), s.config.ExecutorOrphansLimit, UnitCount, false},
+	}
+	for _, check := range checks {
+		if check.optional && check.limit <= 0 {
+			continue
+		}
+		status := s.getStatus(check.value, check.limit)
+		if status == "fail" {
+			s.log.Warn("Functions executor %s %.0f exceeds limit of %.0f", check.kind, check.value, check.limit)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "executor",
+			Title:     fmt.Sprintf("Functions %s - %s", check.title, s.hostname),
+			Cause:     check.cause,
+			AlertID:   fmt.Sprintf("executor-%s-%s", check.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     check.value,
+			Limit:     check.limit,
+			Unit:      check.unit,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, check.value, 0),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed threshold expression over collected values,
+// such as "mem.available_mb < 512 && swap.used_percent > 50". Booleans are
+// represented as 1 (true) and 0 (false).
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+type exprNode interface {
+	eval(values map[string]float64) (float64, error)
+}
+
+type exprNumber float64
+
+type exprVariable string
+
+type exprUnary struct {
+	op      string
+	operand exprNode
+}
+
+type exprBinary struct {
+	op          string
+	left, right exprNode
+}
+
+func ParseExpression(source string) (*Expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+func (e *Expression) Evaluate(values map[string]float64) (float64, error) {
+	return e.root.eval(values)
+}
+
+func tokenizeExpression(source string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(source) && (unicode.IsDigit(rune(source[j])) || source[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, source[i:j])
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(source) && (unicode.IsLetter(rune(source[j])) || unicode.IsDigit(rune(source[j])) || source[j] == '_' || source[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, source[i:j])
+			i = j
+		default:
+			if i+1 < len(source) {
+				switch two := source[i : i+2]; two {
+				case "&&", "||", "<=", ">=", "==", "!=":
+					tokens = append(tokens, two)
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/<>!()", c) {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) parseBinary(next func() (exprNode, error), ops ...string) (exprNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		matched := false
+		for _, candidate := range ops {
+			if op == candidate {
+				matched = true
+			}
+		}
+		if !matched {
+			return left, nil
+		}
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	return p.parseBinary(p.parseAdditive, "<", "<=", ">", ">=", "==", "!=")
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	return p.parseBinary(p.parseUnary, "*", "/")
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op := p.peek(); op == "!" || op == "-" {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return exprUnary{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token := p.peek()
+	if token == "" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	if token == "(" {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	if unicode.IsDigit(rune(token[0])) || token[0] == '.' {
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token)
+		}
+		return exprNumber(value), nil
+	}
+
+	if unicode.IsLetter(rune(token[0])) || token[0] == '_' {
+		switch token {
+		case "true":
+			return exprNumber(1), nil
+		case "false":
+			return exprNumber(0), nil
+		}
+		return exprVariable(token), nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q", token)
+}
+
+func (n exprNumber) eval(values map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (n exprVariable) eval(values map[string]float64) (float64, error) {
+	value, ok := values[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("unknown value %q", string(n))
+	}
+	return value, nil
+}
+
+func (n exprUnary) eval(values map[string]float64) (float64, error) {
+	operand, err := n.operand.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "!" {
+		return boolValue(operand == 0), nil
+	}
+	return -operand, nil
+}
+
+func (n exprBinary) eval(values map[string]float64) (float64, error) {
+	left, err := n.left.eval(values)
+	if err != nil {
+		return 0, err
+	}
+
+	// Short-circuit logical operators
+	switch n.op {
+	case "&&":
+		if left == 0 {
+			return 0, nil
+		}
+	case "||":
+		if left != 0 {
+			return 1, nil
+		}
+	}
+
+	right, err := n.right.eval(values)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return boolValue(right != 0), nil
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	case "<":
+		return boolValue(left < right), nil
+	case "<=":
+		return boolValue(left <= right), nil
+	case ">":
+		return boolValue(left > right), nil
+	case ">=":
+		return boolValue(left >= right), nil
+	case "==":
+		return boolValue(left == right), nil
+	case "!=":
+		return boolValue(left != right), nil
+	}
+	return 0, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
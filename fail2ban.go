@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseFail2banStatus parses the tree printed by "fail2ban-client status",
+// e.g. "|- Number of jail:	2" or "   `- Currently banned:	1", into a map.
+func parseFail2banStatus(output []byte) map[string]string {
+	status := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimLeft(line, " |`-")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		status[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return status
+}
+
+// fail2banJails returns the currently and total banned addresses per jail.
+// fail2ban-client talks to the server through its socket and fails when the
+// server is not running.
+func fail2banJails(ctx context.Context) (map[string][2]float64, error) {
+	output, err := runCommand(ctx, "fail2ban-client", "status")
+	if err != nil {
+		return nil, err
+	}
+
+	jails := map[string][2]float64{}
+	for _, jail := range strings.Split(parseFail2banStatus(output)["Jail list"], ",") {
+		jail = strings.TrimSpace(jail)
+		if jail == "" {
+			continue
+		}
+		output, err := runCommand(ctx, "fail2ban-client", "status", jail)
+		if err != nil {
+			return nil, err
+		}
+		status := parseFail2banStatus(output)
+		current, _ := strconv.ParseFloat(status["Currently banned"], 64)
+		total, _ := strconv.ParseFloat(status["Total banned"], 64)
+		jails[jail] = [2]float64{current, total}
+	}
+	return jails, nil
+}
+
+// checkFail2ban alerts when fail2ban is not running and when more
+// addresses were banned during a cycle than Fail2banBanLimit.
+func (s *SystemMonitor) checkFail2ban(ctx context.Context) error {
+	jails, err := fail2banJails(ctx)
+
+	status := "pass"
+	cause := fmt.Sprintf("fail2ban is running with %d jails", len(jails))
+	value := 1.0
+	if err != nil {
+		status = "fail"
+		cause = fmt.Sprintf("fail2ban is not responding: %v", err)
+		value = 0
+		s.log.Warn("%s", cause)
+	}
+	s.recordValue("fail2ban.up", value)
+
+	if err := s.sendMetric(Metric{
+		Name:      "fail2ban",
+		Title:     fmt.Sprintf("Fail2ban Status - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("fail2ban-status-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     1,
+		Unit:      UnitBoolean,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+	}); err != nil {
+		return err
+	}
+	if jails == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(jails))
+	for name := range jails {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Total banned counts since fail2ban started, a lower count than in the
+	// previous cycle means it was restarted.
+	previous := s.fail2banBans
+	s.fail2banBans = map[string]float64{}
+	var banned, newBans float64
+	var active []string
+	for _, name := range names {
+		current, total := jails[name][0], jails[name][1]
+		s.fail2banBans[name] = total
+		s.recordValue(valueName("fail2ban", name, "banned"), current)
+		banned += current
+
+		if before, ok := previous[name]; ok && total >= before {
+			newBans += total - before
+			if total > before {
+				active = append(active, fmt.Sprintf("%s: %.0f", name, total-before))
+			}
+		}
+	}
+	s.recordValue("fail2ban.banned", banned)
+	s.recordValue("fail2ban.new_bans", newBans)
+	s.log.Log("Fail2ban: %.0f addresses banned, %.0f new bans", banned, newBans)
+	if previous == nil {
+		return nil
+	}
+
+	status = s.getStatus(newBans, s.config.Fail2banBanLimit)
+	cause = fmt.Sprintf("%.0f new bans since the last check, %.0f addresses currently banned", newBans, banned)
+	if len(active) > 0 {
+		cause += " (" + strings.Join(active, ", ") + ")"
+	}
+	if status == "fail" {
+		s.log.Warn("%s", cause)
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "fail2ban",
+		Title:     fmt.Sprintf("Fail2ban Ban Activity - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("fail2ban-bans-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     newBans,
+		Limit:     s.config.Fail2banBanLimit,
+		Unit:      UnitCount,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, newBans, 0),
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileCount limits the number of entries in a directory.
+type FileCount struct {
+	Path  string
+	Limit int
+}
+
+// ParseFileCount parses "<directory>:<limit>", e.g. "/var/spool/mail:5000".
+func ParseFileCount(value string) (FileCount, error) {
+	separator := strings.LastIndex(value, ":")
+	if separator <= 0 {
+		return FileCount{}, fmt.Errorf("expected <directory>:<limit>")
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(value[separator+1:]))
+	if err != nil || limit < 0 {
+		return FileCount{}, fmt.Errorf("invalid limit %q", value[separator+1:])
+	}
+
+	return FileCount{Path: value[:separator], Limit: limit}, nil
+}
+
+// countEntries counts directory entries in batches, so directories with
+// millions of files are not loaded into memory at once.
+func countEntries(path string) (int, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+
+	count := 0
+	for {
+		names, err := dir.Readdirnames(1024)
+		count += len(names)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+func (s *SystemMonitor) checkFileCounts(ctx context.Context) error {
+	for _, check := range s.config.FileCounts {
+		count, err := countEntries(check.Path)
+		if err != nil {
+			s.log.Error("Failed to count files in %s: %v", check.Path, err)
+			continue
+		}
+
+		value := float64(count)
+		s.recordValue(valueName("files", check.Path, "count"), value)
+		status := s.getStatus(value, float64(check.Limit))
+		if status == "fail" {
+			s.log.Warn("File count in %s %d exceeds limit of %d", check.Path, count, check.Limit)
+		} else {
+			s.log.Log("File count in %s: %d (limit: %d)", check.Path, count, check.Limit)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "files",
+			Title:     fmt.Sprintf("File Count %s - %s", check.Path, s.hostname),
+			Cause:     "File count monitoring check",
+			AlertID:   fmt.Sprintf("files-%s-%s", valueName(check.Path), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     float64(check.Limit),
+			Unit:      UnitCount,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"path": check.Path},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// firewallRules returns the number of rules in the active ruleset, which
+// backend it was read from and whether Docker's chains are present.
+func firewallRules(ctx context.Context) (rules int, backend string, docker bool, err error) {
+	if _, err := exec.LookPath("ufw"); err == nil {
+		output, err := runCommand(ctx, "ufw", "status")
+		if err != nil {
+			return 0, "", false, err
+		}
+		if !strings.HasPrefix(strings.TrimSpace(string(output)), "Status: active") {
+			return 0, "ufw", false, nil
+		}
+	}
+
+	if _, err := exec.LookPath("nft"); err == nil {
+		output, err := runCommand(ctx, "nft", "list", "ruleset")
+		if err != nil {
+			return 0, "", false, err
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "chain DOCKER") {
+				docker = true
+			}
+			if line == "" || line == "}" || strings.HasPrefix(line, "table ") || strings.HasPrefix(line, "chain ") || strings.HasPrefix(line, "type ") || strings.HasPrefix(line, "policy ") {
+				continue
+			}
+			rules++
+		}
+		// Docker configured for iptables-legacy keeps its chains out of the
+		// nftables ruleset
+		if !docker {
+			if _, err := exec.LookPath("iptables-legacy"); err == nil {
+				_, docker, _ = iptablesRules(ctx, "iptables-legacy")
+			}
+		}
+		return rules, "nftables", docker, nil
+	}
+
+	rules, docker, err = iptablesRules(ctx, "iptables")
+	if err != nil {
+		return 0, "", false, err
+	}
+	return rules, "iptables", docker, nil
+}
+
+// iptablesRules returns the number of rules listed by "iptables -S", or
+// iptables-legacy, and whether Docker's chains are present.
+func iptablesRules(ctx context.Context, command string) (rules int, docker bool, err error) {
+	output, err := runCommand(ctx, command, "-S")
+	if err != nil {
+		return 0, false, err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "-N DOCKER") {
+			docker = true
+		}
+		if strings.HasPrefix(line, "-A ") {
+			rules++
+		}
+	}
+	return rules, docker, nil
+}
+
+// publicListeners returns the TCP ports listening on a non-loopback
+// address, mapped to that address. Firewall rules are not considered, a
+// port dropped by the ruleset still listens publicly.
+func publicListeners() (map[uint32]string, error) {
+	connections, err := psnet.Connections("tcp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list connections: %v", err)
+	}
+
+	listeners := map[uint32]string{}
+	for _, connection := range connections {
+		if connection.Status != "LISTEN" {
+			continue
+		}
+		ip := connection.Laddr.IP
+		if ip == "127.0.0.1" || ip == "::1" || strings.HasPrefix(ip, "127.") {
+			continue
+		}
+		listeners[connection.Laddr.Port] = ip
+	}
+	return listeners, nil
+}
+
+func (s *SystemMonitor) checkFirewall(ctx context.Context) error {
+	rules, backend, docker, err := firewallRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	status := "pass"
+	cause := fmt.Sprintf("%s ruleset with %d rules", backend, rules)
+	if rules == 0 {
+		status = "fail"
+		cause = fmt.Sprintf("%s ruleset is empty or inactive", backend)
+		s.log.Warn("Firewall is not active: %s", cause)
+	} else {
+		s.log.Log("Firewall active: %s", cause)
+	}
+	s.recordValue("firewall.rules", float64(rules))
+
+	if err := s.sendMetric(Metric{
+		Name:      "firewall",
+		Title:     fmt.Sprintf("Firewall Active - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("firewall-active-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     float64(rules),
+		Limit:     1,
+		Unit:      UnitCount,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, float64(rules), 0),
+	}); err != nil {
+		return err
+	}
+
+	// Docker publishes ports through its own chains, a flushed ruleset
+	// breaks container networking.
+	if s.docker != nil {
+		status := "pass"
+		cause := "Docker chains are present"
+		value := 1.0
+		if !docker {
+			status = "fail"
+			cause = fmt.Sprintf("Docker chains are missing from the %s ruleset", backend)
+			value = 0
+			s.log.Warn("%s", cause)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "firewall",
+			Title:     fmt.Sprintf("Firewall Docker Chains - %s", s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("firewall-docker-%s", s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     1,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(s.config.ClosedPorts) == 0 {
+		return nil
+	}
+	listeners, err := publicListeners()
+	if err != nil {
+		return err
+	}
+
+	ports := append([]int(nil), s.config.ClosedPorts...)
+	sort.Ints(ports)
+	for _, port := range ports {
+		address, listening := listeners[uint32(port)]
+
+		status := "pass"
+		cause := fmt.Sprintf("Port %d is not listening on a public address", port)
+		value := 0.0
+		if listening {
+			status = "fail"
+			cause = fmt.Sprintf("Port %d is listening on %s", port, address)
+			value = 1
+			s.log.Warn("%s", cause)
+		}
+		s.recordValue(valueName("firewall", "port", strconv.Itoa(port), "listening_publicly"), value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "firewall",
+			Title:     fmt.Sprintf("Port %d Listening Publicly - %s", port, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("firewall-port-%d-%s", port, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"port": strconv.Itoa(port)},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
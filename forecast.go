@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Holt-Winters forecasts use hourly averages of the last week with a
+// daily season, and fixed smoothing factors for level, trend and season.
+const (
+	holtWintersWindow = 7 * 24 * time.Hour
+	holtWintersSeason = 24
+	holtWintersAlpha  = 0.3
+	holtWintersBeta   = 0.05
+	holtWintersGamma  = 0.2
+)
+
+// Forecast projects the values matching Pattern ahead and alerts before
+// they reach Limit. Method is "linear" for a least squares trend, or
+// "holt-winters" for a trend with a daily season.
+type Forecast struct {
+	Pattern string
+	Limit   float64
+	Method  string
+}
+
+// ParseForecast parses "<value>:<limit>[:<method>]" where value may contain
+// glob patterns, e.g. "mem.used_percent:90" or "cpu.percent:95:holt-winters".
+func ParseForecast(value string) (Forecast, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Forecast{}, fmt.Errorf("expected <value>:<limit>[:<method>]")
+	}
+
+	forecast := Forecast{Pattern: strings.TrimSpace(parts[0]), Method: "linear"}
+	if forecast.Pattern == "" {
+		return Forecast{}, fmt.Errorf("expected <value>:<limit>[:<method>]")
+	}
+	if _, err := path.Match(forecast.Pattern, ""); err != nil {
+		return Forecast{}, fmt.Errorf("invalid pattern %q: %v", forecast.Pattern, err)
+	}
+	limit, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return Forecast{}, fmt.Errorf("invalid limit %q", parts[1])
+	}
+	forecast.Limit = limit
+	if len(parts) == 3 {
+		forecast.Method = strings.TrimSpace(parts[2])
+		if forecast.Method != "linear" && forecast.Method != "holt-winters" {
+			return Forecast{}, fmt.Errorf("unknown method %q, expected linear or holt-winters", forecast.Method)
+		}
+	}
+	return forecast, nil
+}
+
+// forecastPoint is a sample at hours relative to now.
+type forecastPoint struct {
+	Hours float64
+	Value float64
+}
+
+// linearForecast fits a least squares line through the points and returns
+// the fitted value now and the change per hour. It needs samples spanning
+// at least an hour.
+func linearForecast(points []forecastPoint) (float64, float64, bool) {
+	if len(points) < 2 || points[len(points)-1].Hours-points[0].Hours < 1 {
+		return 0, 0, false
+	}
+	var sumX, sumY float64
+	for _, point := range points {
+		sumX += point.Hours
+		sumY += point.Value
+	}
+	n := float64(len(points))
+	meanX, meanY := sumX/n, sumY/n
+
+	var covariance, variance float64
+	for _, point := range points {
+		covariance += (point.Hours - meanX) * (point.Value - meanY)
+		variance += (point.Hours - meanX) * (point.Hours - meanX)
+	}
+	slope := covariance / variance
+	return meanY - slope*meanX, slope, true
+}
+
+// hourlyAverages averages the points per hour, oldest first. Hours without
+// samples repeat the previous average.
+func hourlyAverages(points []forecastPoint) []float64 {
+	if len(points) == 0 {
+		return nil
+	}
+	first := int(math.Floor(points[0].Hours))
+	last := int(math.Floor(points[len(points)-1].Hours))
+	sums := make([]float64, last-first+1)
+	counts := make([]int, last-first+1)
+	for _, point := range points {
+		hour := int(math.Floor(point.Hours)) - first
+		sums[hour] += point.Value
+		counts[hour]++
+	}
+
+	averages := make([]float64, len(sums))
+	for i := range sums {
+		switch {
+		case counts[i] > 0:
+			averages[i] = sums[i] / float64(counts[i])
+		case i > 0:
+			averages[i] = averages[i-1]
+		}
+	}
+	return averages
+}
+
+// holtWintersForecast runs additive triple exponential smoothing over
+// hourly averages and returns the forecast for each of the next hours.
+// It needs at least two full seasons.
+func holtWintersForecast(series []float64, hours int) ([]float64, bool) {
+	m := holtWintersSeason
+	if len(series) < 2*m {
+		return nil, false
+	}
+
+	var first, second float64
+	for i := 0; i < m; i++ {
+		first += series[i]
+		second += series[m+i]
+	}
+	level := first / float64(m)
+	trend := (second - first) / float64(m*m)
+	seasonal := make([]float64, m)
+	for i := 0; i < m; i++ {
+		seasonal[i] = series[i] - level
+	}
+
+	for t := m; t < len(series); t++ {
+		previous := level
+		level = holtWintersAlpha*(series[t]-seasonal[t%m]) + (1-holtWintersAlpha)*(level+trend)
+		trend = holtWintersBeta*(level-previous) + (1-holtWintersBeta)*trend
+		seasonal[t%m] = holtWintersGamma*(series[t]-level) + (1-holtWintersGamma)*seasonal[t%m]
+	}
+
+	forecast := make([]float64, hours)
+	for h := 1; h <= hours; h++ {
+		forecast[h-1] = level + float64(h)*trend + seasonal[(len(series)-1+h)%m]
+	}
+	return forecast, true
+}
+
+// projectLimit returns in how many hours the points are projected to reach
+// the limit, if they do within the horizon, and the projected value at the
+// horizon. ok is false when there are too few samples.
+func projectLimit(method string, points []forecastPoint, limit float64, horizon time.Duration) (float64, float64, bool, bool) {
+	hours := horizon.Hours()
+	if method == "holt-winters" {
+		series := hourlyAverages(points)
+		forecast, ok := holtWintersForecast(series, int(math.Ceil(hours)))
+		if !ok {
+			return 0, 0, false, false
+		}
+		for h, value := range forecast {
+			if value >= limit {
+				return float64(h + 1), forecast[len(forecast)-1], true, true
+			}
+		}
+		return 0, forecast[len(forecast)-1], false, true
+	}
+
+	now, slope, ok := linearForecast(points)
+	if !ok {
+		return 0, 0, false, false
+	}
+	projected := now + slope*hours
+	if slope <= 0 || projected < limit {
+		return 0, projected, false, true
+	}
+	until := (limit - now) / slope
+	if until < 0 {
+		until = 0
+	}
+	return until, projected, true, true
+}
+
+// formatHours formats a lead time for alerts, e.g. "~3h" or "~40m".
+func formatHours(hours float64) string {
+	if hours < 1 {
+		return fmt.Sprintf("~%.0fm", math.Max(1, hours*60))
+	}
+	return fmt.Sprintf("~%.0fh", hours)
+}
+
+// checkForecasts alerts on values projected to reach their limit within
+// the forecast horizon, giving lead time before the limit is crossed.
+// Values with too little history are skipped.
+func (s *SystemMonitor) checkForecasts(ctx context.Context) error {
+	now := time.Now()
+	values := s.collectedValues()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	window := s.config.ForecastWindow
+	for _, forecast := range s.config.Forecasts {
+		if forecast.Method == "holt-winters" && window < holtWintersWindow {
+			window = holtWintersWindow
+		}
+	}
+	match := func(name string) bool {
+		for _, forecast := range s.config.Forecasts {
+			if ok, _ := path.Match(forecast.Pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	entries, err := readHistory(s.config.HistoryFile, now.Add(-window), now, match)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read history: %v", err)
+	}
+
+	checked := map[string]bool{}
+	for _, forecast := range s.config.Forecasts {
+		from := now.Add(-s.config.ForecastWindow)
+		if forecast.Method == "holt-winters" {
+			from = now.Add(-holtWintersWindow)
+		}
+
+		for _, name := range names {
+			if checked[name] {
+				continue
+			}
+			if ok, _ := path.Match(forecast.Pattern, name); !ok {
+				continue
+			}
+
+			// The values of this cycle are written to the history after the checks
+			var points []forecastPoint
+			for _, entry := range entries {
+				at := time.Unix(entry.Timestamp, 0)
+				if value, ok := entry.Values[name]; ok && !at.Before(from) {
+					points = append(points, forecastPoint{Hours: at.Sub(now).Hours(), Value: value})
+				}
+			}
+			current := values[name]
+			points = append(points, forecastPoint{Hours: 0, Value: current})
+
+			until, projected, crossing, ok := projectLimit(forecast.Method, points, forecast.Limit, s.config.ForecastHorizon)
+			if !ok {
+				continue
+			}
+			checked[name] = true
+
+			status := "pass"
+			severity := SeverityInfo
+			cause := fmt.Sprintf("%s is %.2f, projected %.2f in %s (limit: %.2f)", name, current, projected, formatHours(s.config.ForecastHorizon.Hours()), forecast.Limit)
+			if crossing {
+				status = "fail"
+				severity = SeverityWarning
+				cause = fmt.Sprintf("%s projected to hit %.2f in %s (now %.2f, %s forecast)", name, forecast.Limit, formatHours(until), current, forecast.Method)
+				s.log.Warn("%s", cause)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "forecast",
+				Title:     fmt.Sprintf("Forecast %s - %s", name, s.hostname),
+				Cause:     cause,
+				AlertID:   fmt.Sprintf("forecast-%s-%s", valueName(name), s.hostname),
+				Timestamp: now.Unix(),
+				Status:    status,
+				Value:     projected,
+				Limit:     forecast.Limit,
+				Unit:      valueUnit(name),
+				Type:      TypeGauge,
+				Severity:  severity,
+				Labels:    map[string]string{"value": name, "method": forecast.Method},
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
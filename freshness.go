@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileAge limits how old a file, or the newest file in a directory or
+// glob pattern, may get.
+type FileAge struct {
+	Path   string
+	MaxAge time.Duration
+}
+
+// ParseFileAge parses "<path>:<max-age>", e.g. "/backups/db:26h".
+func ParseFileAge(value string) (FileAge, error) {
+	separator := strings.LastIndex(value, ":")
+	if separator <= 0 {
+		return FileAge{}, fmt.Errorf("expected <path>:<max-age>")
+	}
+
+	maxAge, err := time.ParseDuration(strings.TrimSpace(value[separator+1:]))
+	if err != nil || maxAge <= 0 {
+		return FileAge{}, fmt.Errorf("invalid maximum age %q", value[separator+1:])
+	}
+
+	return FileAge{Path: value[:separator], MaxAge: maxAge}, nil
+}
+
+// newestFile returns the most recently modified regular file matching
+// path. Directories are searched one level deep.
+func newestFile(path string) (string, time.Time, error) {
+	var candidates []string
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		candidates = matches
+	} else {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if !info.IsDir() {
+			return path, info.ModTime(), nil
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		for _, entry := range entries {
+			candidates = append(candidates, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	var newest string
+	var newestTime time.Time
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if info.ModTime().After(newestTime) {
+			newest = candidate
+			newestTime = info.ModTime()
+		}
+	}
+
+	return newest, newestTime, nil
+}
+
+func (s *SystemMonitor) checkFileAges(ctx context.Context) error {
+	for _, check := range s.config.FileAges {
+		file, modified, err := newestFile(check.Path)
+		if err != nil {
+			s.log.Error("Failed to check file age of %s: %v", check.Path, err)
+			continue
+		}
+
+		status := "pass"
+		cause := "File freshness monitoring check"
+		value := time.Since(modified).Hours()
+		if file == "" {
+			status = "fail"
+			cause = fmt.Sprintf("No files found in %s", check.Path)
+			value = 0
+			s.log.Warn("No files found in %s", check.Path)
+		} else if time.Since(modified) > check.MaxAge {
+			status = "fail"
+			cause = fmt.Sprintf("Newest file %s was modified at %s", file, modified.Format("2006-01-02 15:04:05"))
+			s.log.Warn("Newest file %s is %.1f hours old, exceeds limit of %.1f hours", file, value, check.MaxAge.Hours())
+		} else {
+			s.log.Log("Newest file %s: %.1f hours old (limit: %.1f hours)", file, value, check.MaxAge.Hours())
+		}
+		s.recordValue(valueName("file_age", check.Path, "hours"), value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "file_age",
+			Title:     fmt.Sprintf("File Age %s - %s", check.Path, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("file-age-%s-%s", valueName(check.Path), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     check.MaxAge.Hours(),
+			Unit:      UnitHours,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"path": check.Path},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
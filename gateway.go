@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var packetLoss = regexp.MustCompile(`([0-9.]+)% packet loss`)
+
+// defaultGateway returns the IPv4 default gateway and its interface from
+// /proc/net/route, where addresses are little-endian hex.
+func defaultGateway() (net.IP, string, error) {
+	data, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read routes: %v", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		return ip, fields[0], nil
+	}
+	return nil, "", fmt.Errorf("no default route")
+}
+
+// arpAddress returns the MAC address of ip from the kernel's ARP table,
+// empty if the entry is missing or incomplete.
+func arpAddress(ip net.IP) (string, error) {
+	data, err := os.ReadFile("/proc/net/arp")
+	if err != nil {
+		return "", fmt.Errorf("failed to read ARP table: %v", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != ip.String() {
+			continue
+		}
+		if fields[2] == "0x0" || fields[3] == "00:00:00:00:00:00" {
+			return "", nil
+		}
+		return strings.ToLower(fields[3]), nil
+	}
+	return "", nil
+}
+
+// pingLoss pings host a few times and returns the packet loss percentage.
+func pingLoss(ctx context.Context, host string) (float64, error) {
+	output, err := runCommand(ctx, "ping", "-c", "3", "-W", "1", host)
+	if match := packetLoss.FindSubmatch(output); match != nil {
+		return strconv.ParseFloat(string(match[1]), 64)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("failed to parse ping output")
+}
+
+// checkGateway pings the default gateway and an upstream address, so
+// alerts tell a broken LAN from a broken uplink, and watches the
+// gateway's MAC address for router swaps and ARP spoofing.
+func (s *SystemMonitor) checkGateway(ctx context.Context) error {
+	gateway, device, err := defaultGateway()
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{"gateway": gateway.String(), "device": device}
+
+	type check struct {
+		kind   string
+		title  string
+		cause  string
+		status string
+		value  float64
+		unit   string
+	}
+	var checks []check
+
+	loss, err := pingLoss(ctx, gateway.String())
+	if err != nil {
+		return fmt.Errorf("failed to ping gateway %s: %v", gateway, err)
+	}
+	s.recordValue("gateway.packet_loss_percent", loss)
+	gatewayDown := loss >= 100
+	cause := fmt.Sprintf("Default gateway %s on %s: %.0f%% packet loss", gateway, device, loss)
+	if gatewayDown {
+		cause = fmt.Sprintf("Default gateway %s on %s is unreachable, the local network is down", gateway, device)
+	}
+	status := "pass"
+	if gatewayDown {
+		status = "fail"
+	}
+	checks = append(checks, check{"reachability", "Gateway Reachability", cause, status, loss, UnitPercent})
+
+	// With the gateway down, the upstream is unreachable too and would
+	// only duplicate the alert
+	if !gatewayDown && s.config.GatewayUpstream != "" {
+		upstreamLoss, err := pingLoss(ctx, s.config.GatewayUpstream)
+		if err != nil {
+			return fmt.Errorf("failed to ping upstream %s: %v", s.config.GatewayUpstream, err)
+		}
+		s.recordValue("gateway.upstream_packet_loss_percent", upstreamLoss)
+		status := "pass"
+		cause := fmt.Sprintf("Upstream %s: %.0f%% packet loss", s.config.GatewayUpstream, upstreamLoss)
+		if upstreamLoss >= 100 {
+			status = "fail"
+			cause = fmt.Sprintf("Upstream %s is unreachable while gateway %s responds, the problem is beyond the local network", s.config.GatewayUpstream, gateway)
+		}
+		checks = append(checks, check{"upstream", "Upstream Reachability", cause, status, upstreamLoss, UnitPercent})
+	}
+
+	// The ping above refreshes the ARP entry
+	mac, err := arpAddress(gateway)
+	if err != nil {
+		return err
+	}
+	if mac != "" {
+		// Without a configured address the first one seen is the baseline,
+		// and a change only fails for one cycle
+		expected := s.config.GatewayMAC
+		if expected == "" {
+			expected = s.gatewayMAC
+			s.gatewayMAC = mac
+		}
+
+		status := "pass"
+		value := 0.0
+		cause := fmt.Sprintf("Gateway %s has MAC address %s", gateway, mac)
+		if expected != "" && !strings.EqualFold(mac, expected) {
+			status = "fail"
+			value = 1
+			cause = fmt.Sprintf("Gateway %s MAC address changed from %s to %s, the router was replaced or ARP is spoofed", gateway, expected, mac)
+		}
+		checks = append(checks, check{"mac", "Gateway MAC Address", cause, status, value, UnitBoolean})
+	}
+
+	for _, check := range checks {
+		if check.status == "fail" {
+			s.log.Warn("%s", check.cause)
+		} else {
+			s.log.Log("%s", check.cause)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "gateway",
+			Title:     fmt.Sprintf("%s - %s", check.title, s.hostname),
+			Cause:     check.cause,
+			AlertID:   fmt.Sprintf("gateway-%s-%s", check.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    check.status,
+			Value:     check.value,
+			Limit:     0,
+			Unit:      check.unit,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(check.status, check.value, 0),
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long the origin of a target is cached. Failed lookups are retried
+// sooner.
+const (
+	geoTTL      = 24 * time.Hour
+	geoRetryTTL = time.Hour
+)
+
+// geoInfo is the origin AS and country of an address, from the IP to ASN
+// mapping of Team Cymru, e.g. AS16509 "AMAZON-02, US" in "US".
+type geoInfo struct {
+	ASN     string
+	Name    string
+	Country string
+}
+
+type geoEntry struct {
+	info    geoInfo
+	err     error
+	expires time.Time
+}
+
+// geoCache caches the origin of targets by host, so every target is looked
+// up once a day.
+type geoCache struct {
+	log     *Logger
+	mu      sync.Mutex
+	entries map[string]geoEntry
+}
+
+func newGeoCache() *geoCache {
+	return &geoCache{log: New(), entries: map[string]geoEntry{}}
+}
+
+// cymruFields splits a TXT record of Team Cymru, e.g.
+// "16509 | 3.5.0.0/19 | US | arin | 2017-10-06".
+func cymruFields(record string) []string {
+	fields := strings.Split(record, "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+// cymruName returns the origin query of an address, the reversed octets
+// of IPv4 or nibbles of IPv6 below origin.asn.cymru.com or
+// origin6.asn.cymru.com.
+func cymruName(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", v4[3], v4[2], v4[1], v4[0])
+	}
+	const hex = "0123456789abcdef"
+	var name strings.Builder
+	for i := len(ip) - 1; i >= 0; i-- {
+		name.WriteByte(hex[ip[i]&0x0f])
+		name.WriteByte('.')
+		name.WriteByte(hex[ip[i]>>4])
+		name.WriteByte('.')
+	}
+	name.WriteString("origin6.asn.cymru.com")
+	return name.String()
+}
+
+// lookupGeo looks up the origin AS of an address and the name of the AS
+// over DNS.
+func lookupGeo(ctx context.Context, ip net.IP) (geoInfo, error) {
+	records, err := net.DefaultResolver.LookupTXT(ctx, cymruName(ip))
+	if err != nil || len(records) == 0 {
+		return geoInfo{}, fmt.Errorf("no origin for %s: %v", ip, err)
+	}
+	// "<asn> [<asn>...] | <prefix> | <country> | <registry> | <allocated>"
+	fields := cymruFields(records[0])
+	if len(fields) < 3 || fields[0] == "" {
+		return geoInfo{}, fmt.Errorf("unexpected origin of %s: %q", ip, records[0])
+	}
+	info := geoInfo{
+		ASN:     "AS" + strings.Fields(fields[0])[0],
+		Country: fields[2],
+	}
+
+	// "<asn> | <country> | <registry> | <allocated> | <name>"
+	records, err = net.DefaultResolver.LookupTXT(ctx, info.ASN+".asn.cymru.com")
+	if err == nil && len(records) > 0 {
+		if fields := cymruFields(records[0]); len(fields) >= 5 {
+			info.Name = fields[4]
+		}
+	}
+	return info, nil
+}
+
+// targetHost returns the host of a target, which is a URL, "<host>:<port>"
+// or a host.
+func targetHost(target string) string {
+	if strings.Contains(target, "://") {
+		if parsed, err := url.Parse(target); err == nil {
+			return parsed.Hostname()
+		}
+	}
+	target = strings.TrimPrefix(target, "//")
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return strings.Trim(target, "[]")
+}
+
+// Lookup resolves the host of a target and returns the origin of its
+// first public address.
+func (c *geoCache) Lookup(target string) (geoInfo, error) {
+	host := targetHost(target)
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.info, entry.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry = geoEntry{expires: time.Now().Add(geoTTL)}
+	addresses, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		entry.err = fmt.Errorf("failed to resolve %s: %v", host, err)
+	} else {
+		entry.err = fmt.Errorf("%s has no public address", host)
+		for _, ip := range addresses {
+			if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				continue
+			}
+			entry.info, entry.err = lookupGeo(ctx, ip)
+			break
+		}
+	}
+	if entry.err != nil {
+		c.log.Log("No GeoIP data for %s: %v", target, entry.err)
+		entry.expires = time.Now().Add(geoRetryTTL)
+	}
+
+	c.mu.Lock()
+	c.entries[host] = entry
+	c.mu.Unlock()
+	return entry.info, entry.err
+}
+
+// geoLabels adds the origin AS and country of target to labels, so alerts
+// of a fleet spread over providers and regions can be routed and grouped
+// by them. Targets without a public address are left as they are.
+func (s *SystemMonitor) geoLabels(labels map[string]string, target string) map[string]string {
+	if !s.config.GeoIP {
+		return labels
+	}
+
+	info, err := s.geo.Lookup(target)
+	if err != nil {
+		return labels
+	}
+	labels["asn"] = info.ASN
+	labels["country"] = info.Country
+	if info.Name != "" {
+		labels["as_name"] = info.Name
+	}
+	return labels
+}
@@ -2,7 +2,10 @@ module github.com/appwrite/monitoring
 
 go 1.19
 
-require github.com/shirou/gopsutil/v3 v3.24.1
+require (
+	github.com/shirou/gopsutil/v3 v3.24.1
+	golang.org/x/sys v0.16.0
+)
 
 require (
 	github.com/go-ole/go-ole v1.2.6 // indirect
@@ -12,5 +15,4 @@ require (
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.3 // indirect
-	golang.org/x/sys v0.16.0 // indirect
 )
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type GoogleChatSink struct {
+	httpClient *http.Client
+	url        string
+	log        *Logger
+}
+
+func NewGoogleChatSink(webhookURL string) (*GoogleChatSink, error) {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Google Chat webhook URL: %v", err)
+	}
+
+	// Reply into the thread of the same alert so a failure and its
+	// recovery stay together in the space.
+	query := parsed.Query()
+	query.Set("messageReplyOption", "REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD")
+	parsed.RawQuery = query.Encode()
+
+	return &GoogleChatSink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		url: parsed.String(),
+		log: New(),
+	}, nil
+}
+
+func (g *GoogleChatSink) Name() string {
+	return "googlechat"
+}
+
+// Accepts only failures, resolutions and digests.
+func (g *GoogleChatSink) Accepts(metric Metric) bool {
+	return notifiable(metric)
+}
+
+func (g *GoogleChatSink) Send(metric Metric) error {
+	severity := metric.Severity
+	if metric.Status != "fail" {
+		severity = "resolved"
+	}
+
+	decorated := func(label, text string) map[string]interface{} {
+		return map[string]interface{}{
+			"decoratedText": map[string]interface{}{
+				"topLabel": label,
+				"text":     text,
+			},
+		}
+	}
+
+	payload := map[string]interface{}{
+		"text": formatMetricText(metric),
+		"thread": map[string]string{
+			"threadKey": metric.AlertID,
+		},
+		"cardsV2": []map[string]interface{}{
+			{
+				"cardId": metric.AlertID,
+				"card": map[string]interface{}{
+					"header": map[string]string{
+						"title":    metric.Title,
+						"subtitle": metric.Cause,
+					},
+					"sections": []map[string]interface{}{
+						{
+							"widgets": []map[string]interface{}{
+								decorated("Status", fmt.Sprintf(`<font color="%s"><b>%s</b></font>`, statusColor(metric), strings.ToUpper(metric.Status))),
+								decorated("Value", html.EscapeString(fmt.Sprintf("%s (limit: %s)", formatValue(metric.Value, metric.Unit), formatValue(metric.Limit, metric.Unit)))),
+								decorated("Severity", severity),
+								decorated("Alert ID", html.EscapeString(metric.AlertID)),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.url, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	return deliver(g.httpClient, req, g.Name(), g.log)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type GotifySink struct {
+	httpClient *http.Client
+	url        string
+	token      string
+	log        *Logger
+}
+
+func NewGotifySink(serverURL, token string) (*GotifySink, error) {
+	if token == "" {
+		return nil, fmt.Errorf("Gotify application token is required")
+	}
+
+	return &GotifySink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		url:   strings.TrimRight(serverURL, "/") + "/message",
+		token: token,
+		log:   New(),
+	}, nil
+}
+
+func (g *GotifySink) Name() string {
+	return "gotify"
+}
+
+// gotifyPriority maps severities to Gotify priorities (0-10). Clients
+// only pop up notifications from priority 4 and play a sound from 8.
+func gotifyPriority(metric Metric) int {
+	if metric.Status != "fail" {
+		return 2
+	}
+	switch metric.Severity {
+	case SeverityCritical:
+		return 8
+	case SeverityWarning:
+		return 5
+	default:
+		return 4
+	}
+}
+
+// Accepts only failures, resolutions and digests.
+func (g *GotifySink) Accepts(metric Metric) bool {
+	return notifiable(metric)
+}
+
+func (g *GotifySink) Send(metric Metric) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"title":    metric.Title,
+		"message":  formatMetricText(metric),
+		"priority": gotifyPriority(metric),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.url, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("X-Gotify-Key", g.token)
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	return deliver(g.httpClient, req, g.Name(), g.log)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readHAProxyStats returns the CSV statistics of HAProxy, from the stats
+// socket ("show stat") or from a stats page URL ending in ";csv".
+func readHAProxyStats(source string) ([]map[string]string, error) {
+	var reader io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status from %s: %s", source, resp.Status)
+		}
+		reader = resp.Body
+	} else {
+		conn, err := net.DialTimeout("unix", source, 5*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to stats socket: %v", err)
+		}
+		defer conn.Close()
+		if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+			return nil, err
+		}
+		if _, err := conn.Write([]byte("show stat\n")); err != nil {
+			return nil, fmt.Errorf("failed to write to stats socket: %v", err)
+		}
+		reader = conn
+	}
+
+	// The header line is "# pxname,svname,qcur,..."
+	buffered := bufio.NewReader(reader)
+	header, err := buffered.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stats header: %v", err)
+	}
+	columns := strings.Split(strings.TrimSpace(strings.TrimPrefix(header, "# ")), ",")
+
+	records := csv.NewReader(buffered)
+	records.FieldsPerRecord = -1
+	var rows []map[string]string
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stats: %v", err)
+		}
+		row := map[string]string{}
+		for i, value := range record {
+			if i < len(columns) {
+				row[columns[i]] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+type haproxyBackend struct {
+	status   string
+	queue    float64
+	requests float64
+	errors   float64
+	down     []string
+}
+
+func statValue(row map[string]string, column string) float64 {
+	value, _ := strconv.ParseFloat(row[column], 64)
+	return value
+}
+
+// checkHAProxy alerts when backends or their servers go down, requests
+// queue up or the share of errors grows.
+func (s *SystemMonitor) checkHAProxy(ctx context.Context) error {
+	rows, err := readHAProxyStats(s.config.HAProxyStats)
+	if err != nil {
+		return err
+	}
+
+	backends := map[string]*haproxyBackend{}
+	backend := func(name string) *haproxyBackend {
+		if backends[name] == nil {
+			backends[name] = &haproxyBackend{}
+		}
+		return backends[name]
+	}
+	for _, row := range rows {
+		name := row["pxname"]
+		switch row["svname"] {
+		case "FRONTEND":
+		case "BACKEND":
+			b := backend(name)
+			b.status = row["status"]
+			b.queue = statValue(row, "qcur")
+			b.requests = statValue(row, "stot")
+			b.errors = statValue(row, "econ") + statValue(row, "eresp") + statValue(row, "hrsp_5xx")
+		default:
+			if strings.HasPrefix(row["status"], "DOWN") {
+				b := backend(name)
+				b.down = append(b.down, row["svname"])
+			}
+		}
+	}
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	previous := s.haproxyCounters
+	s.haproxyCounters = map[string][2]float64{}
+	for _, name := range names {
+		b := backends[name]
+		s.haproxyCounters[name] = [2]float64{b.requests, b.errors}
+		labels := map[string]string{"backend": name}
+
+		// Backend state, a backend with some servers down is degraded
+		status := "pass"
+		severity := SeverityInfo
+		cause := fmt.Sprintf("Backend is %s", b.status)
+		if b.status != "UP" {
+			status = "fail"
+			severity = SeverityCritical
+		} else if len(b.down) > 0 {
+			status = "fail"
+			severity = SeverityWarning
+		}
+		if len(b.down) > 0 {
+			cause += ", servers down: " + strings.Join(b.down, ", ")
+		}
+		if status == "fail" {
+			s.log.Warn("HAProxy backend %s: %s", name, cause)
+		}
+		s.recordValue(valueName("haproxy", name, "servers_down"), float64(len(b.down)))
+		s.recordValue(valueName("haproxy", name, "queue"), b.queue)
+
+		if err := s.sendMetric(Metric{
+			Name:      "haproxy",
+			Title:     fmt.Sprintf("HAProxy Backend %s - %s", name, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("haproxy-status-%s-%s", valueName(name), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     float64(len(b.down)),
+			Limit:     0,
+			Unit:      UnitCount,
+			Type:      TypeGauge,
+			Severity:  severity,
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+
+		type check struct {
+			kind  string
+			title string
+			cause string
+			value float64
+			limit float64
+			unit  string
+		}
+		checks := []check{
+			{"queue", "Queue", fmt.Sprintf("%.0f requests queued", b.queue), b.queue, s.config.HAProxyQueueLimit, UnitCount},
+		}
+		// Counters reset when HAProxy reloads
+		if before, ok := previous[name]; ok && b.requests >= before[0] && b.errors >= before[1] {
+			requests, errors := b.requests-before[0], b.errors-before[1]
+			rate := 0.0
+			if requests > 0 {
+				rate = errors / requests * 100
+			}
+			s.recordValue(valueName("haproxy", name, "error_percent"), rate)
+			checks = append(checks, check{"errors", "Error Rate", fmt.Sprintf("%.0f errors in %.0f requests", errors, requests), rate, s.config.HAProxyErrorRateLimit, UnitPercent})
+		}
+
+		for _, check := range checks {
+			status := s.getStatus(check.value, check.limit)
+			if status == "fail" {
+				s.log.Warn("HAProxy backend %s %s %.2f exceeds limit of %.2f", name, check.kind, check.value, check.limit)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "haproxy",
+				Title:     fmt.Sprintf("HAProxy Backend %s %s - %s", name, check.title, s.hostname),
+				Cause:     check.cause,
+				AlertID:   fmt.Sprintf("haproxy-%s-%s-%s", check.kind, valueName(name), s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     check.value,
+				Limit:     check.limit,
+				Unit:      check.unit,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(status, check.value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	s.log.Log("HAProxy: %d backends", len(names))
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+// degradedChecks returns what the enabled checks can't do without root,
+// logged at startup when the agent runs as another user.
+func degradedChecks(config Config) []string {
+	var degraded []string
+	add := func(enabled bool, message string) {
+		if enabled {
+			degraded = append(degraded, message)
+		}
+	}
+	add(config.NVMe, "--nvme: nvme-cli needs CAP_SYS_ADMIN to read the SMART log")
+	add(config.LVM, "--lvm: lvs needs root to read the device mapper")
+	add(config.Btrfs, "--btrfs: btrfs-progs need CAP_SYS_ADMIN for usage and device statistics")
+	add(config.IPMI, "--ipmi: ipmitool needs root to open /dev/ipmi0")
+	add(config.IOErrors, "--io-errors: dmesg needs CAP_SYSLOG when kernel.dmesg_restrict is set, only sysfs counters are compared")
+	add(config.SSHFailedLoginLimit > 0, "--ssh-failed-login-limit: the auth log is only readable by root and the adm group")
+	add(config.JournalErrorLimit > 0, "--journal-error-limit: only the journal of the user is read, unless it is in the systemd-journal group")
+	add(config.Fail2ban, "--fail2ban: fail2ban-client needs access to the fail2ban socket")
+	add(config.Firewall, "--firewall: ufw, nft and iptables need CAP_NET_ADMIN to list the ruleset")
+	add(config.DockerSocket != "", "--docker-socket: the user must be in the docker group")
+	add(config.EBPF, "--ebpf: bpftrace needs CAP_BPF and CAP_PERFMON")
+	degraded = append(degraded, "top processes in alerts: I/O of processes of other users is not readable without CAP_SYS_PTRACE")
+	return degraded
+}
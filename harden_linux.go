@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// capabilityNames names the capabilities kept by harden, for the log.
+var capabilityNames = map[int]string{
+	unix.CAP_DAC_OVERRIDE:     "CAP_DAC_OVERRIDE",
+	unix.CAP_DAC_READ_SEARCH:  "CAP_DAC_READ_SEARCH",
+	unix.CAP_NET_BIND_SERVICE: "CAP_NET_BIND_SERVICE",
+	unix.CAP_NET_ADMIN:        "CAP_NET_ADMIN",
+	unix.CAP_NET_RAW:          "CAP_NET_RAW",
+	unix.CAP_SYS_PTRACE:       "CAP_SYS_PTRACE",
+	unix.CAP_SYS_ADMIN:        "CAP_SYS_ADMIN",
+	unix.CAP_SYSLOG:           "CAP_SYSLOG",
+	unix.CAP_PERFMON:          "CAP_PERFMON",
+	unix.CAP_BPF:              "CAP_BPF",
+}
+
+// Return actions of seccomp filters, from linux/seccomp.h
+const (
+	seccompRetErrno = 0x00050000
+	seccompRetAllow = 0x7fff0000
+)
+
+// seccompArchitectures are the audit architectures of the Go
+// architectures the seccomp filter knows the syscall numbers of.
+var seccompArchitectures = map[string]uint32{
+	"amd64": unix.AUDIT_ARCH_X86_64,
+	"arm64": unix.AUDIT_ARCH_AARCH64,
+}
+
+// requiredCapabilities returns the capabilities the enabled checks need.
+// Reading logs and /proc of other processes, and sending pings, is always
+// needed.
+func requiredCapabilities(config Config, listen string) map[int]bool {
+	keep := map[int]bool{
+		unix.CAP_DAC_READ_SEARCH: true,
+		unix.CAP_NET_RAW:         true,
+		unix.CAP_SYS_PTRACE:      true,
+	}
+	if config.NVMe || config.LVM || config.Btrfs {
+		keep[unix.CAP_SYS_ADMIN] = true
+	}
+	if config.IOErrors {
+		keep[unix.CAP_SYSLOG] = true
+	}
+	if config.Firewall {
+		keep[unix.CAP_NET_ADMIN] = true
+	}
+	// /dev/ipmi0 is only writable by root
+	if config.Fail2ban || config.DockerSocket != "" || config.IPMI {
+		keep[unix.CAP_DAC_OVERRIDE] = true
+	}
+	if config.EBPF {
+		keep[unix.CAP_BPF] = true
+		keep[unix.CAP_PERFMON] = true
+		// Kernels before 5.8 only know CAP_SYS_ADMIN
+		keep[unix.CAP_SYS_ADMIN] = true
+	}
+	if _, port, err := splitListen(listen); err == nil && port < 1024 {
+		keep[unix.CAP_NET_BIND_SERVICE] = true
+	}
+	return keep
+}
+
+func splitListen(listen string) (string, int, error) {
+	i := strings.LastIndex(listen, ":")
+	if i < 0 {
+		return "", 0, fmt.Errorf("no port")
+	}
+	port, err := strconv.Atoi(listen[i+1:])
+	return listen[:i], port, err
+}
+
+// harden drops every capability the enabled checks don't need, from the
+// bounding set too, so tools run by the checks can't regain them, and
+// sets no_new_privs so setuid binaries can't either. With seccomp, a
+// filter makes syscalls no check needs, such as mount, ptrace or loading
+// kernel modules, fail with EPERM. It returns the kept capabilities.
+func harden(config Config, listen string, seccomp bool) ([]string, error) {
+	keep := requiredCapabilities(config, listen)
+	last := unix.CAP_LAST_CAP
+	if data, err := os.ReadFile("/proc/sys/kernel/cap_last_cap"); err == nil {
+		if value, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			last = value
+		}
+	}
+
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&header, &data[0]); err != nil {
+		return nil, fmt.Errorf("failed to read capabilities: %v", err)
+	}
+	permitted := func(capability int) bool {
+		return data[capability/32].Permitted&(1<<(capability%32)) != 0
+	}
+
+	// Dropping from the bounding set needs CAP_SETPCAP, so it comes first
+	if permitted(unix.CAP_SETPCAP) {
+		for capability := 0; capability <= last; capability++ {
+			if keep[capability] {
+				continue
+			}
+			if err := allThreads(unix.SYS_PRCTL, unix.PR_CAPBSET_DROP, uintptr(capability), 0); err != nil && err != unix.EINVAL {
+				return nil, fmt.Errorf("failed to drop capability %d from the bounding set: %v", capability, err)
+			}
+		}
+	}
+
+	var kept []string
+	var reduced [2]unix.CapUserData
+	for capability := 0; capability <= last && capability < 64; capability++ {
+		if !keep[capability] || !permitted(capability) {
+			continue
+		}
+		bit := uint32(1) << (capability % 32)
+		reduced[capability/32].Effective |= bit
+		reduced[capability/32].Permitted |= bit
+		reduced[capability/32].Inheritable |= bit
+		kept = append(kept, capabilityNames[capability])
+	}
+	if err := allThreads(unix.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&reduced[0])), 0); err != nil {
+		return nil, fmt.Errorf("failed to drop capabilities: %v", err)
+	}
+	sort.Strings(kept)
+
+	if err := allThreads(unix.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0); err != nil {
+		return nil, fmt.Errorf("failed to set no_new_privs: %v", err)
+	}
+
+	if seccomp {
+		if err := installSeccomp(config.EBPF); err != nil {
+			return nil, err
+		}
+	}
+	return kept, nil
+}
+
+// allThreads runs a syscall on every thread of the agent. Capabilities and
+// no_new_privs are per thread, so a check running on another thread would
+// otherwise keep them. Go can't reach the threads started by C code, so
+// it fails on a build with cgo.
+func allThreads(trap, a1, a2, a3 uintptr) error {
+	if _, _, errno := syscall.AllThreadsSyscall(trap, a1, a2, a3); errno != 0 {
+		if errno == syscall.ENOTSUP {
+			return fmt.Errorf("%v: the agent was built with cgo, rebuild it with CGO_ENABLED=0", errno)
+		}
+		return errno
+	}
+	return nil
+}
+
+// deniedSyscalls are syscalls no check needs. A denylist, unlike an
+// allowlist, keeps working for the tools the checks run, whose syscalls
+// vary by version and distribution.
+func deniedSyscalls(ebpf bool) []uint32 {
+	denied := []uint32{
+		unix.SYS_PTRACE,
+		unix.SYS_PROCESS_VM_READV,
+		unix.SYS_PROCESS_VM_WRITEV,
+		unix.SYS_MOUNT,
+		unix.SYS_UMOUNT2,
+		unix.SYS_PIVOT_ROOT,
+		unix.SYS_CHROOT,
+		unix.SYS_UNSHARE,
+		unix.SYS_SETNS,
+		unix.SYS_INIT_MODULE,
+		unix.SYS_FINIT_MODULE,
+		unix.SYS_DELETE_MODULE,
+		unix.SYS_KEXEC_LOAD,
+		unix.SYS_KEXEC_FILE_LOAD,
+		unix.SYS_REBOOT,
+		unix.SYS_SWAPON,
+		unix.SYS_SWAPOFF,
+		unix.SYS_ACCT,
+		unix.SYS_SETTIMEOFDAY,
+		unix.SYS_CLOCK_SETTIME,
+		unix.SYS_SETHOSTNAME,
+		unix.SYS_SETDOMAINNAME,
+		unix.SYS_OPEN_BY_HANDLE_AT,
+		unix.SYS_USERFAULTFD,
+		unix.SYS_ADD_KEY,
+		unix.SYS_REQUEST_KEY,
+		unix.SYS_KEYCTL,
+	}
+	if !ebpf {
+		denied = append(denied, unix.SYS_BPF, unix.SYS_PERF_EVENT_OPEN)
+	}
+	return denied
+}
+
+// installSeccomp installs a filter returning EPERM for deniedSyscalls on
+// every thread of the agent and the processes it starts.
+func installSeccomp(ebpf bool) error {
+	arch, ok := seccompArchitectures[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+
+	statement := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+	deny := statement(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM))
+	allow := statement(unix.BPF_RET|unix.BPF_K, seccompRetAllow)
+
+	// struct seccomp_data { int nr; __u32 arch; ... }
+	filter := []unix.SockFilter{
+		statement(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, 4),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 1, 0),
+		deny,
+		statement(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, 0),
+	}
+	if runtime.GOARCH == "amd64" {
+		// x32 syscalls share the architecture with their own numbers
+		filter = append(filter, jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, 0x40000000, 0, 1), deny)
+	}
+	for _, syscall := range deniedSyscalls(ebpf) {
+		filter = append(filter, jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, syscall, 0, 1), deny)
+	}
+	filter = append(filter, allow)
+
+	program := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if _, _, errno := unix.Syscall(unix.SYS_SECCOMP, 1 /* SECCOMP_SET_MODE_FILTER */, 1 /* SECCOMP_FILTER_FLAG_TSYNC */, uintptr(unsafe.Pointer(&program))); errno != 0 {
+		return fmt.Errorf("failed to install seccomp filter: %v", errno)
+	}
+	return nil
+}
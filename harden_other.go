@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func harden(config Config, listen string, seccomp bool) ([]string, error) {
+	return nil, fmt.Errorf("hardening is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Heartbeat is a job that is expected to ping the agent at least once
+// every Period, with Grace as additional slack for slow runs.
+type Heartbeat struct {
+	Name   string
+	Period time.Duration
+	Grace  time.Duration
+}
+
+// ParseHeartbeat parses "<name>:<period>[:<grace>]", e.g. "backup:24h:1h".
+func ParseHeartbeat(value string) (Heartbeat, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 || strings.TrimSpace(parts[0]) == "" {
+		return Heartbeat{}, fmt.Errorf("expected <name>:<period>[:<grace>]")
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Heartbeat{}, fmt.Errorf("invalid period %q", parts[1])
+	}
+
+	heartbeat := Heartbeat{Name: strings.TrimSpace(parts[0]), Period: period}
+	if len(parts) == 3 {
+		grace, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+		if err != nil || grace < 0 {
+			return Heartbeat{}, fmt.Errorf("invalid grace period %q", parts[2])
+		}
+		heartbeat.Grace = grace
+	}
+
+	return heartbeat, nil
+}
+
+// heartbeatTracker records the last ping of each registered job. Jobs
+// count as pinged at agent start, so they get a full period after a
+// restart, unless their last ping was saved to the heartbeat file.
+type heartbeatTracker struct {
+	mu    sync.Mutex
+	path  string
+	pings map[string]time.Time
+}
+
+func newHeartbeatTracker(heartbeats []Heartbeat, path string) *heartbeatTracker {
+	tracker := &heartbeatTracker{
+		path:  path,
+		pings: map[string]time.Time{},
+	}
+	for _, heartbeat := range heartbeats {
+		tracker.pings[heartbeat.Name] = time.Now()
+	}
+	return tracker
+}
+
+func (t *heartbeatTracker) Ping(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.pings[name]; !ok {
+		return fmt.Errorf("heartbeat %s is not registered", name)
+	}
+	t.pings[name] = time.Now()
+	return nil
+}
+
+func (t *heartbeatTracker) LastPing(name string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.pings[name]
+}
+
+// Load restores the pings saved by Save. Pings of jobs that are no longer
+// registered are dropped.
+func (t *heartbeatTracker) Load() error {
+	if t.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return err
+	}
+
+	pings := map[string]time.Time{}
+	if err := json.Unmarshal(data, &pings); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, ping := range pings {
+		if _, ok := t.pings[name]; ok && !ping.IsZero() {
+			t.pings[name] = ping
+		}
+	}
+	return nil
+}
+
+// Save writes the pings to the heartbeat file.
+func (t *heartbeatTracker) Save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	data, err := json.Marshal(t.pings)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(t.path, data)
+}
+
+func (s *SystemMonitor) checkHeartbeats(ctx context.Context) error {
+	for _, heartbeat := range s.config.Heartbeats {
+		lastPing := s.heartbeats.LastPing(heartbeat.Name)
+		since := time.Since(lastPing)
+		deadline := heartbeat.Period + heartbeat.Grace
+
+		value := since.Minutes()
+		status := s.getStatus(value, deadline.Minutes())
+		if status == "fail" {
+			s.log.Warn("Heartbeat %s missed, last ping %s ago (expected every %s)", heartbeat.Name, since.Round(time.Second), heartbeat.Period)
+		} else {
+			s.log.Log("Heartbeat %s: last ping %s ago (expected every %s)", heartbeat.Name, since.Round(time.Second), heartbeat.Period)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "heartbeat",
+			Title:     fmt.Sprintf("Heartbeat %s - %s", heartbeat.Name, s.hostname),
+			Cause:     fmt.Sprintf("Last ping at %s", lastPing.Format("2006-01-02 15:04:05")),
+			AlertID:   fmt.Sprintf("heartbeat-%s-%s", heartbeat.Name, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     deadline.Minutes(),
+			Unit:      UnitMinutes,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"job": heartbeat.Name},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultHistoryFile = "/var/lib/monitoring/history.jsonl"
+
+// historyEntry is a line of the history file: every value collected in a
+// check cycle.
+type historyEntry struct {
+	Timestamp int64              `json:"timestamp"`
+	Values    map[string]float64 `json:"values"`
+}
+
+// recordHistory appends the values of this cycle to the history file and
+// drops entries older than the retention about once an hour.
+func (s *SystemMonitor) recordHistory(ctx context.Context) error {
+	values := s.collectedValues()
+	for name, value := range values {
+		// JSON can't represent them
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			delete(values, name)
+		}
+	}
+	line, err := json.Marshal(historyEntry{Timestamp: time.Now().Unix(), Values: values})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.config.HistoryFile), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.config.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if time.Since(s.historyPruned) < time.Hour || s.config.HistoryRetention <= 0 {
+		return nil
+	}
+	s.historyPruned = time.Now()
+	return pruneHistory(s.config.HistoryFile, time.Now().Add(-s.config.HistoryRetention))
+}
+
+// pruneHistory rewrites the history file without entries before cutoff.
+func pruneHistory(path string, cutoff time.Time) error {
+	entries, err := readHistory(path, cutoff, time.Now(), nil)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	return writeFileAtomic(path, data)
+}
+
+// readHistory returns the entries between from and to, keeping only the
+// values match accepts. A nil match keeps every value.
+func readHistory(path string, from, to time.Time, match func(name string) bool) ([]historyEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry historyEntry
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if entry.Timestamp < from.Unix() || entry.Timestamp > to.Unix() {
+			continue
+		}
+		if match != nil {
+			for name := range entry.Values {
+				if !match(name) {
+					delete(entry.Values, name)
+				}
+			}
+			if len(entry.Values) == 0 {
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// parseHistoryTime parses an RFC 3339 time, a date or a duration before
+// now, e.g. "2024-05-01T12:00:00Z", "2024-05-01" or "24h".
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("expected an RFC 3339 time, a date or a duration such as 24h")
+}
+
+func runHistoryCommand(args []string) {
+	log := New()
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprintf(os.Stderr, "Usage: %s history export [options]\n", os.Args[0])
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("history export", flag.ExitOnError)
+	file := fs.String("file", defaultHistoryFile, "History file of the agent")
+	fromValue := fs.String("from", "24h", "Start of the range, as an RFC 3339 time, a date or a duration before now")
+	toValue := fs.String("to", "0s", "End of the range, as an RFC 3339 time, a date or a duration before now")
+	format := fs.String("format", "csv", "Output format, csv or json")
+	metric := fs.String("metric", "", "Only export values starting with this name, e.g. \"disk.\"")
+	fs.Parse(args[1:])
+
+	now := time.Now()
+	from, err := parseHistoryTime(*fromValue, now)
+	if err != nil {
+		log.Fatal("Invalid --from %q: %v", *fromValue, err)
+	}
+	to, err := parseHistoryTime(*toValue, now)
+	if err != nil {
+		log.Fatal("Invalid --to %q: %v", *toValue, err)
+	}
+	if *format != "csv" && *format != "json" {
+		log.Fatal("Invalid --format %q, expected csv or json", *format)
+	}
+
+	var match func(string) bool
+	if *metric != "" {
+		match = func(name string) bool { return strings.HasPrefix(name, *metric) }
+	}
+	entries, err := readHistory(*file, from, to, match)
+	if err != nil {
+		log.Fatal("Failed to read history: %v", err)
+	}
+
+	if *format == "json" {
+		type jsonEntry struct {
+			Time   string             `json:"time"`
+			Values map[string]float64 `json:"values"`
+		}
+		output := make([]jsonEntry, 0, len(entries))
+		for _, entry := range entries {
+			output = append(output, jsonEntry{time.Unix(entry.Timestamp, 0).UTC().Format(time.RFC3339), entry.Values})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			log.Fatal("Failed to write history: %v", err)
+		}
+		return
+	}
+
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write([]string{"time", "metric", "value"})
+	for _, entry := range entries {
+		names := make([]string, 0, len(entry.Values))
+		for name := range entry.Values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		timestamp := time.Unix(entry.Timestamp, 0).UTC().Format(time.RFC3339)
+		for _, name := range names {
+			writer.Write([]string{timestamp, name, strconv.FormatFloat(entry.Values[name], 'f', -1, 64)})
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Fatal("Failed to write history: %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHTTPCheckBodySize bounds how much of a response is read for content
+// assertions.
+const maxHTTPCheckBodySize = 1024 * 1024
+
+// HTTPCheck is an endpoint that must answer with the expected status and,
+// optionally, a body passing content assertions.
+type HTTPCheck struct {
+	Name     string
+	URL      string
+	Status   int
+	Contains []string
+	Regexps  []*regexp.Regexp
+	JSON     []JSONAssertion
+	P95Limit float64
+}
+
+// ParseHTTPCheck parses "<name>=<url>[;<assertion>...]" where assertions
+// are status=<code>, contains=<text>, regex=<pattern> or
+// json=<path> <==|!=> <value>, and p95=<ms> overrides the latency limit, e.g.
+// "api=https://example.com/v1/health;json=$.status == \"pass\"".
+func ParseHTTPCheck(value string) (HTTPCheck, error) {
+	parts := strings.Split(value, ";")
+	name, rawURL, found := strings.Cut(parts[0], "=")
+	name, rawURL = strings.TrimSpace(name), strings.TrimSpace(rawURL)
+	if !found || name == "" || !(strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://")) {
+		return HTTPCheck{}, fmt.Errorf("expected <name>=<url>[;<assertion>...]")
+	}
+
+	check := HTTPCheck{Name: name, URL: rawURL}
+	for _, part := range parts[1:] {
+		kind, argument, found := strings.Cut(part, "=")
+		if !found || argument == "" {
+			return HTTPCheck{}, fmt.Errorf("invalid assertion %q", part)
+		}
+		switch strings.TrimSpace(kind) {
+		case "status":
+			status, err := strconv.Atoi(argument)
+			if err != nil || status < 100 || status > 599 {
+				return HTTPCheck{}, fmt.Errorf("invalid status %q", argument)
+			}
+			check.Status = status
+		case "contains":
+			check.Contains = append(check.Contains, argument)
+		case "regex":
+			pattern, err := regexp.Compile(argument)
+			if err != nil {
+				return HTTPCheck{}, fmt.Errorf("invalid regex %q: %v", argument, err)
+			}
+			check.Regexps = append(check.Regexps, pattern)
+		case "json":
+			assertion, err := ParseJSONAssertion(argument)
+			if err != nil {
+				return HTTPCheck{}, fmt.Errorf("invalid JSON assertion %q: %v", argument, err)
+			}
+			check.JSON = append(check.JSON, assertion)
+		case "p95":
+			limit, err := strconv.ParseFloat(argument, 64)
+			if err != nil || limit <= 0 {
+				return HTTPCheck{}, fmt.Errorf("invalid p95 limit %q", argument)
+			}
+			check.P95Limit = limit
+		default:
+			return HTTPCheck{}, fmt.Errorf("unknown assertion %q, expected status, contains, regex, json or p95", kind)
+		}
+	}
+	return check, nil
+}
+
+// probe requests the endpoint and returns the response time in
+// milliseconds along with every assertion that failed.
+func (c HTTPCheck) probe(ctx context.Context, client *http.Client) (float64, []string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPCheckBodySize))
+	latency := float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		return latency, nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	var failures []string
+	if c.Status != 0 && resp.StatusCode != c.Status {
+		failures = append(failures, fmt.Sprintf("status %d, expected %d", resp.StatusCode, c.Status))
+	} else if c.Status == 0 && resp.StatusCode >= 400 {
+		failures = append(failures, fmt.Sprintf("status %d", resp.StatusCode))
+	}
+	for _, text := range c.Contains {
+		if !strings.Contains(string(body), text) {
+			failures = append(failures, fmt.Sprintf("body does not contain %q", text))
+		}
+	}
+	for _, pattern := range c.Regexps {
+		if !pattern.Match(body) {
+			failures = append(failures, fmt.Sprintf("body does not match %q", pattern))
+		}
+	}
+	if len(c.JSON) > 0 {
+		var document interface{}
+		if err := json.Unmarshal(body, &document); err != nil {
+			failures = append(failures, fmt.Sprintf("body is not JSON: %v", err))
+		} else {
+			for _, assertion := range c.JSON {
+				if ok, mismatch := assertion.Evaluate(document); !ok {
+					failures = append(failures, mismatch)
+				}
+			}
+		}
+	}
+	return latency, failures, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func (s *SystemMonitor) checkHTTP(ctx context.Context) error {
+	client := &http.Client{Timeout: s.config.HTTPTimeout}
+
+	for _, check := range s.config.HTTPChecks {
+		labels := s.geoLabels(map[string]string{"check": check.Name, "url": check.URL}, check.URL)
+
+		// Every sample must pass; the first failure is reported
+		var latencies []float64
+		var failures []string
+		for i := 0; i < s.config.HTTPSamples && len(failures) == 0; i++ {
+			latency, sampleFailures, err := check.probe(ctx, client)
+			if err != nil {
+				sampleFailures = []string{err.Error()}
+			} else {
+				latencies = append(latencies, latency)
+			}
+			failures = sampleFailures
+		}
+		sort.Float64s(latencies)
+		p50 := percentile(latencies, 50)
+		p95 := percentile(latencies, 95)
+
+		status := "pass"
+		value := 0.0
+		cause := fmt.Sprintf("Responded in %.0f ms (p50), %.0f ms (p95) over %d requests", p50, p95, len(latencies))
+		if len(failures) > 0 {
+			status = "fail"
+			value = 1
+			cause = strings.Join(failures, "; ")
+			s.log.Warn("HTTP check %s failed: %s", check.Name, cause)
+			cause = s.tracerouteCause(ctx, cause, check.URL)
+		} else {
+			s.log.Log("HTTP check %s: %s", check.Name, cause)
+		}
+		s.recordValue(valueName("http", check.Name, "up"), 1-value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "http",
+			Title:     fmt.Sprintf("HTTP Check %s - %s", check.Name, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("http-%s-%s", check.Name, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+
+		// Latency is only meaningful for endpoints that respond correctly,
+		// a failing one is already alerted above
+		if len(failures) > 0 {
+			continue
+		}
+		s.recordValue(valueName("http", check.Name, "latency_ms"), p50)
+		s.recordValue(valueName("http", check.Name, "p95_ms"), p95)
+
+		p95Limit := s.config.HTTPP95Limit
+		if check.P95Limit > 0 {
+			p95Limit = check.P95Limit
+		}
+		type latencyCheck struct {
+			kind  string
+			title string
+			value float64
+			limit float64
+		}
+		checks := []latencyCheck{
+			{"p50", "Median Latency", p50, s.config.HTTPP50Limit},
+			{"p95", "P95 Latency", p95, p95Limit},
+		}
+		for _, latency := range checks {
+			if latency.limit <= 0 {
+				continue
+			}
+			status := s.getStatus(latency.value, latency.limit)
+			if status == "fail" {
+				s.log.Warn("HTTP check %s %s latency %.0f ms exceeds limit of %.0f ms", check.Name, latency.kind, latency.value, latency.limit)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "http",
+				Title:     fmt.Sprintf("HTTP Check %s %s - %s", check.Name, latency.title, s.hostname),
+				Cause:     fmt.Sprintf("%s latency over %d requests", latency.kind, len(latencies)),
+				AlertID:   fmt.Sprintf("http-%s-%s-%s", latency.kind, check.Name, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     latency.value,
+				Limit:     latency.limit,
+				Unit:      UnitMS,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(status, latency.value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// thpModes are the values of /sys/kernel/mm/transparent_hugepage/enabled.
+var thpModes = map[string]bool{"always": true, "madvise": true, "never": true}
+
+// readProcValues reads "<name>: <value> [kB]" lines of /proc/meminfo or
+// "<name> <value>" lines of /proc/vmstat.
+func readProcValues(path string) (map[string]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]float64{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if value, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			values[strings.TrimSuffix(fields[0], ":")] = value
+		}
+	}
+	return values, nil
+}
+
+// thpSetting returns the selected value of a transparent hugepage
+// setting, shown in brackets, e.g. "madvise" for "always [madvise] never".
+func thpSetting(name string) (string, error) {
+	data, err := os.ReadFile("/sys/kernel/mm/transparent_hugepage/" + name)
+	if err != nil {
+		return "", err
+	}
+	text := string(data)
+	start, end := strings.Index(text, "["), strings.Index(text, "]")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("unexpected %s: %s", name, strings.TrimSpace(text))
+	}
+	return text[start+1 : end], nil
+}
+
+// checkHugepages alerts on memory lost to hugepages: a static pool the
+// database doesn't use (e.g. PostgreSQL with huge_pages=try failing to map
+// it), transparent hugepages in another mode than the databases on the
+// host expect, and processes stalling in direct compaction, which THP
+// defrag=always causes on fragmented memory.
+func (s *SystemMonitor) checkHugepages(ctx context.Context) error {
+	meminfo, err := readProcValues("/proc/meminfo")
+	if err != nil {
+		return fmt.Errorf("failed to read /proc/meminfo: %v", err)
+	}
+	vmstat, err := readProcValues("/proc/vmstat")
+	if err != nil {
+		return fmt.Errorf("failed to read /proc/vmstat: %v", err)
+	}
+
+	total := meminfo["HugePages_Total"]
+	free := meminfo["HugePages_Free"]
+	reserved := meminfo["HugePages_Rsvd"]
+	sizeMB := meminfo["Hugepagesize"] / 1024
+	s.recordValue("hugepages.total", total)
+	s.recordValue("hugepages.free", free)
+	s.recordValue("hugepages.reserved", reserved)
+	s.recordValue("hugepages.total_mb", total*sizeMB)
+	s.recordValue("thp.anon_mb", meminfo["AnonHugePages"]/1024)
+
+	enabled, err := thpSetting("enabled")
+	if err != nil {
+		return fmt.Errorf("failed to read transparent hugepage mode: %v", err)
+	}
+	defrag, _ := thpSetting("defrag")
+	s.log.Log("Hugepages: %.0f of %.0f free, %.0f reserved (%.0f MB pages), THP enabled=%s defrag=%s, %.0f MB anonymous THP",
+		free, total, reserved, sizeMB, enabled, defrag, meminfo["AnonHugePages"]/1024)
+
+	// Pages that are free but not reserved are promised to nobody
+	if total > 0 {
+		unused := free - reserved
+		if unused < 0 {
+			unused = 0
+		}
+		value := 100 * unused / total
+		s.recordValue("hugepages.unused_percent", value)
+
+		status := s.getStatus(value, s.config.HugepagesUnusedLimit)
+		cause := fmt.Sprintf("%.0f of %.0f hugepages (%.0f MB) neither used nor reserved", unused, total, unused*sizeMB)
+		if status == "fail" {
+			s.log.Warn("%s", cause)
+		}
+		if err := s.sendMetric(Metric{
+			Name:      "hugepages",
+			Title:     fmt.Sprintf("Unused Hugepages - %s", s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("hugepages-unused-%s", s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     s.config.HugepagesUnusedLimit,
+			Unit:      UnitPercent,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if s.config.THPMode != "" {
+		status, severity := "pass", SeverityInfo
+		value := 0.0
+		cause := fmt.Sprintf("Transparent hugepages are %s (defrag %s)", enabled, defrag)
+		if enabled != s.config.THPMode {
+			status, severity = "fail", SeverityWarning
+			value = 1
+			cause = fmt.Sprintf("Transparent hugepages are %s, expected %s (defrag %s)", enabled, s.config.THPMode, defrag)
+			s.log.Warn("%s", cause)
+		}
+		if err := s.sendMetric(Metric{
+			Name:      "thp",
+			Title:     fmt.Sprintf("Transparent Hugepages - %s", s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("thp-mode-%s", s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  severity,
+			Labels:    map[string]string{"enabled": enabled, "defrag": defrag},
+		}); err != nil {
+			return err
+		}
+	}
+
+	// The first cycle only records the counter
+	stalls, now := vmstat["compact_stall"], time.Now()
+	previous, previousAt := s.compactStalls, s.compactStallsAt
+	s.compactStalls, s.compactStallsAt = stalls, now
+	if previousAt.IsZero() || stalls < previous || s.config.THPStallLimit <= 0 {
+		return nil
+	}
+	value := (stalls - previous) / now.Sub(previousAt).Minutes()
+	s.recordValue("thp.compact_stalls_per_minute", value)
+
+	status := s.getStatus(value, s.config.THPStallLimit)
+	cause := fmt.Sprintf("%.1f direct compaction stalls per minute, %.0f THP faults fell back to small pages (enabled=%s, defrag=%s)",
+		value, vmstat["thp_fault_fallback"], enabled, defrag)
+	if status == "fail" {
+		s.log.Warn("%s", cause)
+	}
+	return s.sendMetric(Metric{
+		Name:      "thp",
+		Title:     fmt.Sprintf("Compaction Stalls - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("thp-stalls-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     s.config.THPStallLimit,
+		Unit:      UnitPerMinute,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+	})
+}
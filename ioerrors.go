@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kernelIOErrorPatterns match the kernel log lines the block layer and
+// filesystems print on failed I/O, capturing the device name.
+var kernelIOErrorPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`I/O error, dev ([a-z0-9]+)`),
+	regexp.MustCompile(`Buffer I/O error on dev(?:ice)? ([a-z0-9]+)`),
+	regexp.MustCompile(`EXT4-fs error \(device ([a-z0-9]+)\)`),
+	regexp.MustCompile(`XFS \(([a-z0-9]+)\): .*I/O error`),
+	regexp.MustCompile(`BTRFS error \(device ([a-z0-9]+)\)`),
+}
+
+// sysfsIOErrorCounts reads the SCSI error counters of all block devices
+// from /sys/block/<device>/device/ioerr_cnt, e.g. "0x1a".
+func sysfsIOErrorCounts() map[string]float64 {
+	counts := map[string]float64{}
+	files, _ := filepath.Glob("/sys/block/*/device/ioerr_cnt")
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		count, err := strconv.ParseInt(strings.TrimSpace(string(data)), 0, 64)
+		if err != nil {
+			continue
+		}
+		counts[filepath.Base(filepath.Dir(filepath.Dir(file)))] = float64(count)
+	}
+	return counts
+}
+
+// kernelIOErrorCounts counts I/O error messages per device in the kernel
+// ring buffer.
+func kernelIOErrorCounts(ctx context.Context) (map[string]float64, error) {
+	output, err := runCommand(ctx, "dmesg")
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]float64{}
+	for _, line := range strings.Split(string(output), "\n") {
+		for _, pattern := range kernelIOErrorPatterns {
+			if match := pattern.FindStringSubmatch(line); match != nil {
+				counts[match[1]]++
+				break
+			}
+		}
+	}
+	return counts, nil
+}
+
+// checkIOErrors alerts when a device's I/O error counters increased since
+// the previous cycle. Read and write errors precede data loss well before
+// SMART reports a failing drive. The first cycle only records a baseline.
+func (s *SystemMonitor) checkIOErrors(ctx context.Context) error {
+	counts := map[string]float64{}
+	for device, count := range sysfsIOErrorCounts() {
+		counts["sysfs:"+device] = count
+	}
+	kernel, err := kernelIOErrorCounts(ctx)
+	if err != nil {
+		s.log.Error("Failed to read kernel log: %v", err)
+	}
+	for device, count := range kernel {
+		counts["kernel:"+device] = count
+	}
+
+	previous := s.ioErrors
+	s.ioErrors = counts
+	if previous == nil {
+		return nil
+	}
+
+	increases := map[string]float64{}
+	for key, count := range counts {
+		device := key[strings.Index(key, ":")+1:]
+		if _, ok := increases[device]; !ok {
+			increases[device] = 0
+		}
+		// The ring buffer wraps and counters reset on reboot or hotplug,
+		// a lower count is a new baseline.
+		if before, ok := previous[key]; ok && count > before {
+			increases[device] += count - before
+		} else if !ok && strings.HasPrefix(key, "kernel:") {
+			increases[device] += count
+		}
+	}
+
+	devices := make([]string, 0, len(increases))
+	for device := range increases {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+
+	for _, device := range devices {
+		value := increases[device]
+		s.recordValue(valueName("io_errors", device, "new"), value)
+
+		status := s.getStatus(value, 0)
+		cause := "No new I/O errors since the last check"
+		if status == "fail" {
+			cause = fmt.Sprintf("%.0f new I/O errors on %s since the last check", value, device)
+			s.log.Warn("%s", cause)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "io-errors",
+			Title:     fmt.Sprintf("I/O Errors %s - %s", device, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("io-errors-%s-%s", device, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitCount,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"device": device},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
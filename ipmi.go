@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IPMI sensor kinds, each alerted on as one check
+var ipmiKinds = []struct {
+	kind  string
+	title string
+}{
+	{"fan", "Fans"},
+	{"power-supply", "Power Supplies"},
+	{"voltage", "Voltages"},
+	{"temperature", "Temperatures"},
+	{"other", "Hardware Sensors"},
+}
+
+// ipmiFaults are sensor event descriptions reporting a fault while the
+// status column of discrete sensors still reads "ok", and whether the
+// fault is critical.
+var ipmiFaults = []struct {
+	text     string
+	critical bool
+}{
+	{"failure detected", true},
+	{"ac lost", true},
+	{"redundancy lost", true},
+	{"predictive failure", false},
+	{"redundancy degraded", false},
+	{"non-redundant", false},
+}
+
+// ipmiSensor is a line of "ipmitool sdr elist", e.g.
+// "Fan1A RPM | 30h | ok | 29.1 | 4080 RPM" or
+// "Status | 85h | ok | 10.1 | Presence detected, Failure detected".
+type ipmiSensor struct {
+	Name    string
+	Status  string
+	Entity  string
+	Reading string
+	Kind    string
+	Fault   string
+}
+
+// parseIPMISensors parses the output of "ipmitool sdr elist". Sensors
+// without a reading ("ns") are skipped.
+func parseIPMISensors(output []byte) []ipmiSensor {
+	var sensors []ipmiSensor
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, "|")
+		if len(fields) < 5 {
+			continue
+		}
+		sensor := ipmiSensor{
+			Name:    strings.TrimSpace(fields[0]),
+			Status:  strings.TrimSpace(fields[2]),
+			Entity:  strings.TrimSpace(fields[3]),
+			Reading: strings.TrimSpace(fields[4]),
+		}
+		if sensor.Name == "" || sensor.Status == "ns" {
+			continue
+		}
+
+		reading := strings.ToLower(sensor.Reading)
+		name := strings.ToLower(sensor.Name)
+		switch {
+		case strings.HasSuffix(reading, " rpm") || strings.HasPrefix(sensor.Entity, "29.") || strings.Contains(name, "fan"):
+			sensor.Kind = "fan"
+		case strings.HasSuffix(reading, " volts"):
+			sensor.Kind = "voltage"
+		case strings.HasSuffix(reading, " degrees c"):
+			sensor.Kind = "temperature"
+		case strings.HasPrefix(sensor.Entity, "10.") || strings.HasPrefix(name, "ps") || strings.Contains(name, "power supply"):
+			sensor.Kind = "power-supply"
+		default:
+			sensor.Kind = "other"
+		}
+
+		// Threshold sensors report non-critical, critical and
+		// non-recoverable in the status column
+		switch sensor.Status {
+		case "nc":
+			sensor.Fault = "warning"
+		case "cr", "nr":
+			sensor.Fault = "critical"
+		}
+		for _, fault := range ipmiFaults {
+			if !strings.Contains(reading, fault.text) {
+				continue
+			}
+			if fault.critical {
+				sensor.Fault = "critical"
+			} else if sensor.Fault == "" {
+				sensor.Fault = "warning"
+			}
+		}
+		sensors = append(sensors, sensor)
+	}
+	return sensors
+}
+
+// ipmiValueName returns the name of the value of a threshold sensor for
+// rules, e.g. "ipmi.fan1a_rpm.rpm", or "" for discrete sensors.
+func ipmiValueName(sensor ipmiSensor) (string, float64) {
+	fields := strings.Fields(sensor.Reading)
+	if len(fields) < 2 {
+		return "", 0
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", 0
+	}
+	suffix := strings.ToLower(fields[len(fields)-1])
+	if sensor.Kind == "temperature" {
+		suffix = "temperature"
+	}
+	return valueName("ipmi", strings.ToLower(sensor.Name), suffix), value
+}
+
+// checkIPMI alerts on faulty fans, power supplies, voltages and
+// temperatures reported by the BMC, one alert per kind of sensor listing
+// the faulty sensors. The BMC evaluates its own thresholds, so a fan
+// slowing down or a power supply losing its feed is alerted before the
+// host goes down.
+func (s *SystemMonitor) checkIPMI(ctx context.Context) error {
+	output, err := runCommand(ctx, "ipmitool", "sdr", "elist")
+	if err != nil {
+		return fmt.Errorf("failed to read IPMI sensors: %v", err)
+	}
+	sensors := parseIPMISensors(output)
+	if len(sensors) == 0 {
+		return fmt.Errorf("ipmitool reported no sensors")
+	}
+
+	counts := map[string]int{}
+	faults := map[string][]string{}
+	critical := map[string]bool{}
+	for _, sensor := range sensors {
+		counts[sensor.Kind]++
+		if name, value := ipmiValueName(sensor); name != "" {
+			s.recordValue(name, value)
+		}
+		if sensor.Fault == "" {
+			continue
+		}
+		faults[sensor.Kind] = append(faults[sensor.Kind], fmt.Sprintf("%s: %s (%s)", sensor.Name, sensor.Reading, sensor.Status))
+		critical[sensor.Kind] = critical[sensor.Kind] || sensor.Fault == "critical"
+	}
+	s.log.Log("IPMI: %d sensors, %d fans, %d power supply, %d voltage, %d temperature", len(sensors),
+		counts["fan"], counts["power-supply"], counts["voltage"], counts["temperature"])
+
+	for _, kind := range ipmiKinds {
+		if counts[kind.kind] == 0 {
+			continue
+		}
+		failing := faults[kind.kind]
+		sort.Strings(failing)
+		value := float64(len(failing))
+		s.recordValue(valueName("ipmi", kind.kind, "faults"), value)
+
+		status := s.getStatus(value, 0)
+		severity := s.getSeverity(status, value, 0)
+		cause := fmt.Sprintf("%d %s sensors reporting no fault", counts[kind.kind], kind.kind)
+		if status == "fail" {
+			if !critical[kind.kind] {
+				severity = SeverityWarning
+			}
+			cause = fmt.Sprintf("%d of %d %s sensors faulty: %s", len(failing), counts[kind.kind], kind.kind, strings.Join(failing, ", "))
+			s.log.Warn("%s", cause)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "ipmi",
+			Title:     fmt.Sprintf("IPMI %s - %s", kind.title, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("ipmi-%s-%s", kind.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitCount,
+			Type:      TypeGauge,
+			Severity:  severity,
+			Labels:    map[string]string{"sensor_type": kind.kind},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// countJournalErrors counts journal entries with priority err or higher
+// since the given time, optionally of a single unit. Entries are printed
+// as one JSON object per line, which keeps multi-line messages countable.
+func countJournalErrors(ctx context.Context, since time.Time, unit string) (int, error) {
+	args := []string{"--priority=err", fmt.Sprintf("--since=@%d", since.Unix()), "--output=json", "--output-fields=PRIORITY", "--quiet", "--no-pager"}
+	if unit != "" {
+		args = append(args, "--unit="+unit)
+	}
+
+	output, err := runCommand(ctx, "journalctl", args...)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// checkJournalErrors alerts when the rate of error entries in the journal
+// exceeds JournalErrorLimit per minute, per configured unit or overall.
+func (s *SystemMonitor) checkJournalErrors(ctx context.Context) error {
+	now := time.Now()
+	since := s.journalAt
+	if since.IsZero() {
+		since = now.Add(-time.Duration(s.config.Interval) * time.Second)
+	}
+	s.journalAt = now
+	minutes := now.Sub(since).Minutes()
+
+	units := s.config.JournalUnits
+	if len(units) == 0 {
+		units = []string{""}
+	}
+
+	for _, unit := range units {
+		count, err := countJournalErrors(ctx, since, unit)
+		if err != nil {
+			return err
+		}
+
+		name := "all units"
+		alertID := fmt.Sprintf("journal-errors-%s", s.hostname)
+		labels := map[string]string{}
+		valueKey := valueName("journal", "errors_per_minute")
+		if unit != "" {
+			name = unit
+			alertID = fmt.Sprintf("journal-errors-%s-%s", valueName(unit), s.hostname)
+			labels["unit"] = unit
+			valueKey = valueName("journal", unit, "errors_per_minute")
+		}
+
+		value := float64(count) / minutes
+		s.recordValue(valueKey, value)
+		status := s.getStatus(value, s.config.JournalErrorLimit)
+		if status == "fail" {
+			s.log.Warn("Journal errors for %s: %.2f/min exceed limit of %.2f/min", name, value, s.config.JournalErrorLimit)
+		} else {
+			s.log.Log("Journal errors for %s: %.2f/min (limit: %.2f/min)", name, value, s.config.JournalErrorLimit)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "journal",
+			Title:     fmt.Sprintf("Journal Error Rate %s - %s", name, s.hostname),
+			Cause:     fmt.Sprintf("%d entries with priority err or higher in the last %.0f minutes", count, minutes),
+			AlertID:   alertID,
+			Timestamp: now.Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     s.config.JournalErrorLimit,
+			Unit:      UnitPerMinute,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
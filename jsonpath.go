@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONAssertion compares the value at a JSONPath with an expected JSON
+// literal, e.g. `$.status == "pass"` or `$.checks[0].ok != false`.
+// Strings may also be single-quoted.
+type JSONAssertion struct {
+	Path     []string
+	Operator string
+	Expected interface{}
+	text     string
+}
+
+func (a JSONAssertion) String() string {
+	return a.text
+}
+
+// ParseJSONAssertion parses "<path> <==|!=> <literal>". Paths support
+// $.field, nested fields and [index]; literals are JSON values.
+func ParseJSONAssertion(value string) (JSONAssertion, error) {
+	operator := "=="
+	index := strings.Index(value, "==")
+	if notEqual := strings.Index(value, "!="); notEqual >= 0 && (index < 0 || notEqual < index) {
+		operator, index = "!=", notEqual
+	}
+	if index < 0 {
+		return JSONAssertion{}, fmt.Errorf("expected <path> == <value> or <path> != <value>")
+	}
+
+	path, err := parseJSONPath(strings.TrimSpace(value[:index]))
+	if err != nil {
+		return JSONAssertion{}, err
+	}
+
+	var expected interface{}
+	literal := strings.TrimSpace(value[index+2:])
+	if len(literal) >= 2 && strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") {
+		// Single quotes are easier to pass through shells and compose files
+		expected = literal[1 : len(literal)-1]
+	} else if err := json.Unmarshal([]byte(literal), &expected); err != nil {
+		return JSONAssertion{}, fmt.Errorf("invalid value %s, strings must be quoted", literal)
+	}
+
+	return JSONAssertion{Path: path, Operator: operator, Expected: expected, text: strings.TrimSpace(value)}, nil
+}
+
+// parseJSONPath splits "$.checks[0].status" into ["checks", "0", "status"].
+func parseJSONPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+
+	var segments []string
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty field in path %q", path)
+			}
+			segments = append(segments, rest[1:end+1])
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in path %q", path)
+			}
+			segment := strings.Trim(rest[1:end], `'"`)
+			segments = append(segments, segment)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q in path %q", rest[0], path)
+		}
+	}
+	return segments, nil
+}
+
+// lookupJSONPath returns the value at path, or false if it does not exist.
+func lookupJSONPath(document interface{}, path []string) (interface{}, bool) {
+	current := document
+	for _, segment := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Evaluate checks the assertion against a decoded JSON document and
+// returns a description of the mismatch if it does not hold.
+func (a JSONAssertion) Evaluate(document interface{}) (bool, string) {
+	actual, found := lookupJSONPath(document, a.Path)
+	if !found {
+		return a.Operator == "!=", fmt.Sprintf("%s: path not found", a.text)
+	}
+
+	expected, _ := json.Marshal(a.Expected)
+	got, _ := json.Marshal(actual)
+	equal := string(expected) == string(got)
+	if equal == (a.Operator == "==") {
+		return true, ""
+	}
+	return false, fmt.Sprintf("%s: got %s", a.text, got)
+}
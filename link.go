@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readSysNet reads an attribute of a network interface from sysfs.
+// Attributes like speed fail to read while the link is down.
+func readSysNet(iface, attribute string) string {
+	data, err := os.ReadFile(filepath.Join("/sys/class/net", iface, attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// physicalInterfaces lists interfaces backed by a device, skipping
+// loopback, bridges, veth pairs and other virtual interfaces.
+func physicalInterfaces() ([]string, error) {
+	entries, err := os.ReadDir("/sys/class/net")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %v", err)
+	}
+
+	var interfaces []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join("/sys/class/net", entry.Name(), "device")); err == nil {
+			interfaces = append(interfaces, entry.Name())
+		}
+	}
+	sort.Strings(interfaces)
+	return interfaces, nil
+}
+
+// checkLinks alerts when a link goes down, negotiates a lower speed than
+// the highest seen since start (or LinkMinSpeed) or runs at half duplex.
+// Unlisted interfaces that have never been up, like unused ports, are
+// skipped.
+func (s *SystemMonitor) checkLinks(ctx context.Context) error {
+	if s.linkSpeeds == nil {
+		s.linkSpeeds = map[string]float64{}
+	}
+
+	interfaces := s.config.LinkInterfaces
+	if len(interfaces) == 0 {
+		var err error
+		if interfaces, err = physicalInterfaces(); err != nil {
+			return err
+		}
+	}
+
+	for _, iface := range interfaces {
+		state := readSysNet(iface, "operstate")
+		if state == "" {
+			return fmt.Errorf("interface %s not found", iface)
+		}
+		_, seen := s.linkSpeeds[iface]
+		if state != "up" && !seen && len(s.config.LinkInterfaces) == 0 {
+			s.log.Log("Interface %s is %s, skipping unused interface", iface, state)
+			continue
+		}
+		if !seen {
+			s.linkSpeeds[iface] = 0
+		}
+		labels := map[string]string{"interface": iface}
+
+		type check struct {
+			kind   string
+			title  string
+			cause  string
+			status string
+			value  float64
+			limit  float64
+			unit   string
+		}
+		status := "pass"
+		value := 0.0
+		if state != "up" {
+			status = "fail"
+			value = 1
+		}
+		s.recordValue(valueName("link", iface, "up"), 1-value)
+		checks := []check{
+			{"state", "Link State", fmt.Sprintf("Interface %s is %s", iface, state), status, value, 0, UnitBoolean},
+		}
+
+		if state == "up" {
+			// Virtual NICs report -1 or no speed at all
+			speed, err := strconv.ParseFloat(readSysNet(iface, "speed"), 64)
+			if err == nil && speed > 0 {
+				s.recordValue(valueName("link", iface, "speed_mbps"), speed)
+				expected := s.linkSpeeds[iface]
+				if s.config.LinkMinSpeed > expected {
+					expected = s.config.LinkMinSpeed
+				}
+				if speed > s.linkSpeeds[iface] {
+					s.linkSpeeds[iface] = speed
+				}
+
+				status := "pass"
+				if speed < expected {
+					status = "fail"
+				}
+				checks = append(checks, check{"speed", "Link Speed", fmt.Sprintf("Interface %s negotiated %.0f Mbit/s, expected %.0f Mbit/s", iface, speed, expected), status, speed, expected, UnitMbps})
+			}
+
+			if duplex := readSysNet(iface, "duplex"); duplex == "full" || duplex == "half" {
+				status := "pass"
+				value := 0.0
+				if duplex == "half" {
+					status = "fail"
+					value = 1
+				}
+				checks = append(checks, check{"duplex", "Link Duplex", fmt.Sprintf("Interface %s runs at %s duplex", iface, duplex), status, value, 0, UnitBoolean})
+			}
+		}
+
+		for _, check := range checks {
+			if check.status == "fail" {
+				s.log.Warn("%s", check.cause)
+			} else {
+				s.log.Log("%s", check.cause)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "link",
+				Title:     fmt.Sprintf("Interface %s %s - %s", iface, check.title, s.hostname),
+				Cause:     check.cause,
+				AlertID:   fmt.Sprintf("link-%s-%s-%s", check.kind, iface, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    check.status,
+				Value:     check.value,
+				Limit:     check.limit,
+				Unit:      check.unit,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(check.status, check.value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
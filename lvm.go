@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type lvmReport struct {
+	Report []struct {
+		LV []struct {
+			Name            string `json:"lv_name"`
+			VG              string `json:"vg_name"`
+			Attr            string `json:"lv_attr"`
+			DataPercent     string `json:"data_percent"`
+			MetadataPercent string `json:"metadata_percent"`
+			Origin          string `json:"origin"`
+			SegType         string `json:"segtype"`
+		} `json:"lv"`
+	} `json:"report"`
+}
+
+// checkLVM monitors thin pool data and metadata usage and the fill level
+// of classic snapshots. A thin pool running full corrupts the filesystems
+// on it without anything showing up in df.
+func (s *SystemMonitor) checkLVM(ctx context.Context) error {
+	output, err := runCommand(ctx, "lvs", "--reportformat", "json", "-o", "lv_name,vg_name,lv_attr,data_percent,metadata_percent,origin,segtype")
+	if err != nil {
+		return err
+	}
+
+	var report lvmReport
+	if err := json.Unmarshal(output, &report); err != nil {
+		return fmt.Errorf("failed to parse lvs output: %v", err)
+	}
+
+	for _, group := range report.Report {
+		for _, lv := range group.LV {
+			name := lv.VG + "/" + lv.Name
+
+			switch {
+			case lv.SegType == "thin-pool" || strings.HasPrefix(lv.Attr, "t"):
+				if err := s.sendLVMMetric(name, "thin-data", "Thin Pool Data", lv.DataPercent, s.config.LVMThinDataLimit); err != nil {
+					return err
+				}
+				if err := s.sendLVMMetric(name, "thin-metadata", "Thin Pool Metadata", lv.MetadataPercent, s.config.LVMThinMetadataLimit); err != nil {
+					return err
+				}
+
+			case strings.HasPrefix(lv.Attr, "s") || strings.HasPrefix(lv.Attr, "S"):
+				// Classic (non-thin) snapshots become invalid once full
+				if err := s.sendLVMMetric(name, "snapshot", "Snapshot "+lv.Origin, lv.DataPercent, s.config.LVMSnapshotLimit); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *SystemMonitor) sendLVMMetric(name, kind, title, percent string, limit float64) error {
+	value, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+	if err != nil {
+		s.log.Error("Failed to parse LVM usage of %s: %q", name, percent)
+		return nil
+	}
+
+	s.recordValue(valueName("lvm", name, strings.ReplaceAll(kind, "-", "_")+"_percent"), value)
+	status := s.getStatus(value, limit)
+	if status == "fail" {
+		s.log.Warn("LVM %s usage for %s %.2f%% exceeds limit of %.2f%%", strings.ToLower(title), name, value, limit)
+	} else {
+		s.log.Log("LVM %s usage for %s: %.2f%% (limit: %.2f%%)", strings.ToLower(title), name, value, limit)
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "lvm",
+		Title:     fmt.Sprintf("LVM %s Usage %s - %s", title, name, s.hostname),
+		Cause:     "LVM monitoring check",
+		AlertID:   fmt.Sprintf("lvm-%s-%s-%s", kind, valueName(name), s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     limit,
+		Unit:      UnitPercent,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+		Labels:    map[string]string{"lv": name},
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const auditLogPath = "/var/log/audit/audit.log"
+
+// macStatus reports the mandatory access control module in use and
+// whether it is enforcing, e.g. "selinux", "permissive".
+func macStatus() (module, mode string) {
+	if data, err := os.ReadFile("/sys/fs/selinux/enforce"); err == nil {
+		if strings.TrimSpace(string(data)) == "1" {
+			return "selinux", "enforcing"
+		}
+		return "selinux", "permissive"
+	}
+
+	if data, err := os.ReadFile("/sys/module/apparmor/parameters/enabled"); err == nil {
+		if strings.TrimSpace(string(data)) != "Y" {
+			return "apparmor", "disabled"
+		}
+		// One line per loaded profile, e.g. "docker-default (enforce)"
+		profiles, err := os.ReadFile("/sys/kernel/security/apparmor/profiles")
+		if err == nil && strings.Contains(string(profiles), "(enforce)") {
+			return "apparmor", "enforcing"
+		}
+		return "apparmor", "complain"
+	}
+
+	return "none", "disabled"
+}
+
+// macDenials counts SELinux AVC and AppArmor denials since the previous
+// cycle, from the audit log if auditd is running, otherwise from the
+// kernel messages in the journal.
+func (s *SystemMonitor) macDenials(ctx context.Context) (int, error) {
+	var lines []string
+	if _, err := os.Stat(auditLogPath); err == nil {
+		if s.auditLog == nil {
+			s.auditLog = &logTail{path: auditLogPath}
+		}
+		if lines, err = s.auditLog.Read(); err != nil {
+			return 0, err
+		}
+	} else {
+		now := time.Now()
+		since := s.auditAt
+		s.auditAt = now
+		if since.IsZero() {
+			return 0, nil
+		}
+		output, err := runCommand(ctx, "journalctl", "--dmesg", fmt.Sprintf("--since=@%d", since.Unix()), fmt.Sprintf("--until=@%d", now.Unix()), "--output=cat", "--quiet", "--no-pager")
+		if err != nil {
+			return 0, err
+		}
+		lines = strings.Split(string(output), "\n")
+	}
+
+	count := 0
+	for _, line := range lines {
+		if strings.Contains(line, "avc:  denied") || strings.Contains(line, `apparmor="DENIED"`) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// checkMAC alerts when SELinux or AppArmor is not enforcing. The mode seen
+// at the first check is logged, so a transition to permissive or disabled
+// shows up in the alert cause.
+func (s *SystemMonitor) checkMAC(ctx context.Context) error {
+	module, mode := macStatus()
+	if s.macMode == "" {
+		s.macMode = mode
+	}
+
+	value := 0.0
+	if mode == "enforcing" {
+		value = 1
+	}
+	s.recordValue("mac.enforcing", value)
+
+	denials, err := s.macDenials(ctx)
+	if err != nil {
+		s.log.Error("Failed to count access control denials: %v", err)
+	}
+	s.recordValue("mac.denials", float64(denials))
+
+	status := "pass"
+	cause := fmt.Sprintf("%s is %s, %d denials since the last check", module, mode, denials)
+	if mode != "enforcing" {
+		status = "fail"
+		if s.macMode != mode {
+			cause = fmt.Sprintf("%s changed from %s to %s, %d denials since the last check", module, s.macMode, mode, denials)
+		}
+		s.log.Warn("%s", cause)
+	} else {
+		s.log.Log("%s", cause)
+	}
+
+	if err := s.sendMetric(Metric{
+		Name:      "mac",
+		Title:     fmt.Sprintf("Access Control Enforcement - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("mac-enforcing-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     1,
+		Unit:      UnitBoolean,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+		Labels:    map[string]string{"module": module},
+	}); err != nil {
+		return err
+	}
+
+	if s.config.MACDenialLimit <= 0 {
+		return nil
+	}
+
+	value = float64(denials)
+	status = s.getStatus(value, s.config.MACDenialLimit)
+	if status == "fail" {
+		s.log.Warn("%s denials %.0f exceed limit of %.0f", module, value, s.config.MACDenialLimit)
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "mac",
+		Title:     fmt.Sprintf("Access Control Denials - %s", s.hostname),
+		Cause:     fmt.Sprintf("%d %s denials since the last check", denials, module),
+		AlertID:   fmt.Sprintf("mac-denials-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     s.config.MACDenialLimit,
+		Unit:      UnitCount,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+		Labels:    map[string]string{"module": module},
+	})
+}
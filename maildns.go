@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const mailDNSCheckInterval = time.Hour
+
+// MailDomain is a sending domain whose SPF, DMARC and DKIM records are
+// validated.
+type MailDomain struct {
+	Domain    string
+	Selectors []string
+}
+
+// ParseMailDomain parses "<domain>[:<dkim selectors>]", e.g.
+// "example.com:default,mailjet".
+func ParseMailDomain(value string) (MailDomain, error) {
+	domain, selectors, _ := strings.Cut(value, ":")
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if !strings.Contains(domain, ".") {
+		return MailDomain{}, fmt.Errorf("expected <domain>[:<dkim selectors>]")
+	}
+
+	mail := MailDomain{Domain: domain}
+	for _, selector := range strings.Split(selectors, ",") {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			mail.Selectors = append(mail.Selectors, selector)
+		}
+	}
+	return mail, nil
+}
+
+// lookupRecord returns the TXT record at name starting with prefix.
+// Records split into several strings are joined, as DKIM keys usually are.
+func lookupRecord(name, prefix string) (string, error) {
+	records, err := net.LookupTXT(name)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var found []string
+	for _, record := range records {
+		if strings.HasPrefix(strings.ToLower(record), strings.ToLower(prefix)) {
+			found = append(found, record)
+		}
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("%d records starting with %q at %s, receivers treat this as no record", len(found), prefix, name)
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// checkMailDNS alerts when a mail domain stops publishing its SPF, DMARC
+// or DKIM records, or when one of them changed since the previous check.
+func (s *SystemMonitor) checkMailDNS(ctx context.Context) error {
+	if time.Since(s.mailDNSAt) < mailDNSCheckInterval {
+		return nil
+	}
+	s.mailDNSAt = time.Now()
+	if s.mailRecords == nil {
+		s.mailRecords = map[string]string{}
+	}
+
+	for _, mail := range s.config.MailDomains {
+		type record struct {
+			kind   string
+			name   string
+			prefix string
+		}
+		records := []record{
+			{"spf", mail.Domain, "v=spf1"},
+			{"dmarc", "_dmarc." + mail.Domain, "v=DMARC1"},
+		}
+		for _, selector := range mail.Selectors {
+			records = append(records, record{"dkim-" + selector, selector + "._domainkey." + mail.Domain, "v=DKIM1"})
+		}
+
+		for _, r := range records {
+			value, err := lookupRecord(r.name, r.prefix)
+
+			status := "pass"
+			severity := SeverityInfo
+			cause := fmt.Sprintf("%s: %s", r.name, value)
+			previous, seen := s.mailRecords[r.name]
+			switch {
+			case err != nil:
+				status = "fail"
+				severity = SeverityCritical
+				cause = fmt.Sprintf("Failed to look up %s: %v", r.name, err)
+			case value == "":
+				status = "fail"
+				severity = SeverityCritical
+				cause = fmt.Sprintf("No %s record published at %s", strings.ToUpper(r.prefix[2:]), r.name)
+			case seen && previous != value:
+				status = "fail"
+				severity = SeverityWarning
+				cause = fmt.Sprintf("%s changed from %q to %q", r.name, previous, value)
+			}
+			if err == nil && value != "" {
+				s.mailRecords[r.name] = value
+			}
+
+			if status == "fail" {
+				s.log.Warn("%s", cause)
+			} else {
+				s.log.Log("Mail record %s is published", r.name)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "mail-dns",
+				Title:     fmt.Sprintf("Mail DNS %s %s - %s", strings.ToUpper(r.kind), mail.Domain, s.hostname),
+				Cause:     cause,
+				AlertID:   fmt.Sprintf("mail-dns-%s-%s-%s", r.kind, valueName(mail.Domain), s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     0,
+				Limit:     0,
+				Unit:      UnitBoolean,
+				Type:      TypeGauge,
+				Severity:  severity,
+				Labels:    map[string]string{"domain": mail.Domain, "record": r.kind},
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type MatrixSink struct {
+	httpClient  *http.Client
+	homeserver  string
+	roomID      string
+	accessToken string
+	txnCounter  uint64
+	log         *Logger
+}
+
+func NewMatrixSink(homeserver, roomID, accessToken string) (*MatrixSink, error) {
+	if roomID == "" {
+		return nil, fmt.Errorf("Matrix room ID is required")
+	}
+	if accessToken == "" {
+		return nil, fmt.Errorf("Matrix access token is required")
+	}
+
+	return &MatrixSink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		homeserver:  strings.TrimRight(homeserver, "/"),
+		roomID:      roomID,
+		accessToken: accessToken,
+		log:         New(),
+	}, nil
+}
+
+func (m *MatrixSink) Name() string {
+	return "matrix"
+}
+
+// Accepts only failures, resolutions and digests.
+func (m *MatrixSink) Accepts(metric Metric) bool {
+	return notifiable(metric)
+}
+
+func (m *MatrixSink) Send(metric Metric) error {
+	formatted := fmt.Sprintf(`<p><font color="%s"><strong>[%s]</strong></font> <strong>%s</strong></p><p>Value: <code>%s</code> (limit: <code>%s</code>)<br>%s</p>`,
+		statusColor(metric),
+		strings.ToUpper(metric.Status),
+		html.EscapeString(metric.Title),
+		html.EscapeString(formatValue(metric.Value, metric.Unit)),
+		html.EscapeString(formatValue(metric.Limit, metric.Unit)),
+		html.EscapeString(metric.Cause))
+
+	// Recoveries are sent as notices so they don't trigger mentions
+	msgType := "m.text"
+	if metric.Status != "fail" {
+		msgType = "m.notice"
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"msgtype":        msgType,
+		"body":           formatMetricText(metric),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	// Matrix ignores a repeated transaction ID, so retries and redeliveries
+	// of an event are posted once
+	txnID := metric.EventID
+	if txnID == "" {
+		txnID = fmt.Sprintf("%d-%d", time.Now().UnixNano(), atomic.AddUint64(&m.txnCounter, 1))
+	}
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.homeserver,
+		url.PathEscape(m.roomID),
+		txnID)
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+m.accessToken)
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	return deliver(m.httpClient, req, m.Name(), m.log)
+}
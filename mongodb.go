@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// mongoStatusScript prints ping latency, connection counts, the replica
+// set members that are not healthy and the oplog window as JSON.
+const mongoStatusScript = `
+const start = Date.now();
+db.adminCommand({ ping: 1 });
+const ping = Date.now() - start;
+const connections = db.adminCommand({ serverStatus: 1 }).connections;
+let members = null, oplog = null;
+try {
+	members = db.adminCommand({ replSetGetStatus: 1 }).members
+		.filter(m => !["PRIMARY", "SECONDARY", "ARBITER"].includes(m.stateStr))
+		.map(m => m.name + " " + m.stateStr);
+	oplog = db.getReplicationInfo().timeDiff;
+} catch (e) {}
+print(JSON.stringify({ ping, current: connections.current, available: connections.available, members, oplog }));
+`
+
+type mongoStatus struct {
+	Ping      float64  `json:"ping"`
+	Current   float64  `json:"current"`
+	Available float64  `json:"available"`
+	Members   []string `json:"members"`
+	Oplog     *float64 `json:"oplog"`
+}
+
+// queryMongo runs the status script with mongosh. Members and Oplog are
+// nil for standalone servers.
+func queryMongo(ctx context.Context, uri string) (*mongoStatus, error) {
+	output, err := runCommand(ctx, "mongosh", uri, "--quiet", "--norc", "--eval", mongoStatusScript)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	var status mongoStatus
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &status); err != nil {
+		return nil, fmt.Errorf("unexpected mongosh output: %v", err)
+	}
+	return &status, nil
+}
+
+func (s *SystemMonitor) checkMongo(ctx context.Context) error {
+	status, err := queryMongo(ctx, s.config.MongoURI)
+	if err != nil {
+		s.log.Warn("MongoDB is not responding: %v", err)
+		return s.sendMetric(Metric{
+			Name:      "mongodb",
+			Title:     fmt.Sprintf("MongoDB Ping - %s", s.hostname),
+			Cause:     fmt.Sprintf("Status script failed: %v", err),
+			AlertID:   fmt.Sprintf("mongodb-ping-%s", s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    "fail",
+			Value:     0,
+			Limit:     s.config.MongoLatencyLimit,
+			Unit:      UnitMS,
+			Type:      TypeGauge,
+			Severity:  SeverityCritical,
+		})
+	}
+
+	connections := status.Current / (status.Current + status.Available) * 100
+	s.recordValue("mongodb.ping_ms", status.Ping)
+	s.recordValue("mongodb.connections", status.Current)
+	s.recordValue("mongodb.connections_percent", connections)
+	s.log.Log("MongoDB: ping %.0f ms, %.0f connections (%.1f%%)", status.Ping, status.Current, connections)
+
+	type check struct {
+		kind   string
+		title  string
+		cause  string
+		status string
+		value  float64
+		limit  float64
+		unit   string
+	}
+	checks := []check{
+		{"ping", "Ping", fmt.Sprintf("Ping took %.0f ms", status.Ping), s.getStatus(status.Ping, s.config.MongoLatencyLimit), status.Ping, s.config.MongoLatencyLimit, UnitMS},
+		{"connections", "Connections", fmt.Sprintf("%.0f connections in use, %.0f available", status.Current, status.Available), s.getStatus(connections, s.config.MongoConnectionsLimit), connections, s.config.MongoConnectionsLimit, UnitPercent},
+	}
+
+	if status.Members != nil {
+		unhealthy := float64(len(status.Members))
+		s.recordValue("mongodb.unhealthy_members", unhealthy)
+		cause := "All replica set members are healthy"
+		if unhealthy > 0 {
+			cause = "Unhealthy members: " + strings.Join(status.Members, ", ")
+		}
+		checks = append(checks, check{"members", "Replica Set Members", cause, s.getStatus(unhealthy, 0), unhealthy, 0, UnitCount})
+	}
+
+	// A short oplog window means a secondary that falls behind for longer
+	// needs a full resync.
+	if status.Oplog != nil {
+		hours := *status.Oplog / 3600
+		limit := s.config.MongoOplogWindowLimit.Hours()
+		s.recordValue("mongodb.oplog_window_hours", hours)
+		result := "pass"
+		if hours < limit {
+			result = "fail"
+		}
+		checks = append(checks, check{"oplog", "Oplog Window", fmt.Sprintf("Oplog covers %.1f hours", hours), result, hours, limit, UnitHours})
+	}
+
+	for _, check := range checks {
+		if check.status == "fail" {
+			s.log.Warn("MongoDB %s: %s", check.kind, check.cause)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "mongodb",
+			Title:     fmt.Sprintf("MongoDB %s - %s", check.title, s.hostname),
+			Cause:     check.cause,
+			AlertID:   fmt.Sprintf("mongodb-%s-%s", check.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    check.status,
+			Value:     check.value,
+			Limit:     check.limit,
+			Unit:      check.unit,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(check.status, check.value, 0),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
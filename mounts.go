@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// remoteFilesystems are probed for stalls, a dead server makes statfs
+// block until the server comes back.
+var remoteFilesystems = map[string]bool{
+	"nfs":        true,
+	"nfs4":       true,
+	"cifs":       true,
+	"smb3":       true,
+	"smbfs":      true,
+	"fuse.sshfs": true,
+}
+
+var errMountStalled = errors.New("mount did not respond")
+
+type usageResult struct {
+	usage *disk.UsageStat
+	err   error
+}
+
+// mountProber runs disk.Usage in a separate goroutine with a hard timeout,
+// so a hung mount cannot block the check cycle. A probe that hangs keeps
+// its goroutine until the mount responds again, no further probes of that
+// mount are started meanwhile.
+type mountProber struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	pending map[string]bool
+}
+
+func newMountProber(timeout time.Duration) *mountProber {
+	return &mountProber{
+		timeout: timeout,
+		pending: map[string]bool{},
+	}
+}
+
+func (p *mountProber) Usage(mount string) (*disk.UsageStat, error) {
+	p.mu.Lock()
+	if p.pending[mount] {
+		p.mu.Unlock()
+		return nil, errMountStalled
+	}
+	p.pending[mount] = true
+	p.mu.Unlock()
+
+	result := make(chan usageResult, 1)
+	go func() {
+		usage, err := disk.Usage(mount)
+
+		p.mu.Lock()
+		delete(p.pending, mount)
+		p.mu.Unlock()
+
+		result <- usageResult{usage: usage, err: err}
+	}()
+
+	select {
+	case r := <-result:
+		return r.usage, r.err
+	case <-time.After(p.timeout):
+		return nil, errMountStalled
+	}
+}
+
+func (s *SystemMonitor) checkRemoteMounts(ctx context.Context) error {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		return fmt.Errorf("failed to list partitions: %v", err)
+	}
+
+	for _, partition := range partitions {
+		if !remoteFilesystems[partition.Fstype] {
+			continue
+		}
+		mount := partition.Mountpoint
+
+		start := time.Now()
+		_, err := s.mounts.Usage(mount)
+		latency := float64(time.Since(start).Milliseconds())
+		s.recordValue(valueName("mount", mount, "latency_ms"), latency)
+
+		status := "pass"
+		cause := fmt.Sprintf("%s mount of %s responded in %.0f ms", partition.Fstype, partition.Device, latency)
+		if err == errMountStalled {
+			status = "fail"
+			cause = fmt.Sprintf("%s mount of %s did not respond within %s", partition.Fstype, partition.Device, s.config.MountTimeout)
+			s.log.Warn("Remote mount %s (%s) is stalled", mount, partition.Device)
+		} else if err != nil {
+			// e.g. "stale file handle" after the export was recreated
+			status = "fail"
+			cause = fmt.Sprintf("%s mount of %s failed: %v", partition.Fstype, partition.Device, err)
+			s.log.Warn("Remote mount %s (%s) failed: %v", mount, partition.Device, err)
+		} else {
+			s.log.Log("Remote mount %s (%s) responded in %.0f ms", mount, partition.Device, latency)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "mount",
+			Title:     fmt.Sprintf("Remote Mount %s - %s", mount, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("mount-%s-%s", valueName(mount), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     latency,
+			Limit:     float64(s.config.MountTimeout.Milliseconds()),
+			Unit:      UnitMS,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, latency, 0),
+			Labels:    map[string]string{"mount": mount},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkExpectedMounts alerts when a mount point that must be present is
+// not mounted. An unmounted data volume otherwise just drops out of the
+// /mnt/* disk checks, and writes silently land on the root filesystem.
+func (s *SystemMonitor) checkExpectedMounts(ctx context.Context) error {
+	if len(s.config.ExpectedMounts) == 0 {
+		return nil
+	}
+
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		return fmt.Errorf("failed to list partitions: %v", err)
+	}
+
+	mounted := map[string]string{}
+	for _, partition := range partitions {
+		mounted[partition.Mountpoint] = partition.Device
+	}
+
+	for _, mount := range s.config.ExpectedMounts {
+		device, ok := mounted[mount]
+
+		status := "pass"
+		cause := fmt.Sprintf("%s is mounted from %s", mount, device)
+		value := 1.0
+		if !ok {
+			status = "fail"
+			cause = fmt.Sprintf("%s is not mounted", mount)
+			value = 0
+			s.log.Warn("Expected mount %s is missing", mount)
+		} else {
+			s.log.Log("Expected mount %s is mounted from %s", mount, device)
+		}
+		s.recordValue(valueName("mount", mount, "present"), value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "mount",
+			Title:     fmt.Sprintf("Expected Mount %s - %s", mount, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("mount-expected-%s-%s", valueName(mount), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     1,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"mount": mount},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
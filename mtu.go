@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// IPv4 and ICMP headers add 28 bytes to the ping payload
+	icmpOverhead = 28
+	minimumMTU   = 576
+	ethernetMTU  = 1500
+)
+
+// pingDF sends a single ping with the don't fragment bit set and
+// reports whether a reply came back.
+func pingDF(ctx context.Context, host string, payload int) bool {
+	_, err := runCommand(ctx, "ping", "-M", "do", "-s", strconv.Itoa(payload), "-c", "1", "-W", "1", host)
+	return err == nil
+}
+
+// pathMTU finds the largest packet that reaches host unfragmented with a
+// binary search between the IPv4 minimum and the Ethernet MTU. Routers
+// that drop oversized packets without sending "fragmentation needed",
+// PMTU blackholes, only show up this way.
+func pathMTU(ctx context.Context, host string) (int, error) {
+	low, high := minimumMTU-icmpOverhead, ethernetMTU-icmpOverhead
+	if !pingDF(ctx, host, low) {
+		return 0, fmt.Errorf("%s does not answer pings of %d bytes", host, minimumMTU)
+	}
+	if pingDF(ctx, host, high) {
+		return ethernetMTU, nil
+	}
+	// low always passes and high always fails
+	for high-low > 1 {
+		middle := (low + high) / 2
+		if pingDF(ctx, host, middle) {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return low + icmpOverhead, nil
+}
+
+func (s *SystemMonitor) checkPathMTU(ctx context.Context) error {
+	for _, target := range s.config.MTUTargets {
+		mtu, err := pathMTU(ctx, target)
+		if err != nil {
+			s.log.Error("Failed to probe path MTU to %s: %v", target, err)
+			continue
+		}
+		value := float64(mtu)
+		limit := float64(s.config.MTUMin)
+		s.recordValue(valueName("mtu", target), value)
+
+		status := "pass"
+		if mtu < s.config.MTUMin {
+			status = "fail"
+			s.log.Warn("Path MTU to %s is %d bytes, below minimum of %d bytes", target, mtu, s.config.MTUMin)
+		} else {
+			s.log.Log("Path MTU to %s: %d bytes", target, mtu)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "mtu",
+			Title:     fmt.Sprintf("Path MTU to %s - %s", target, s.hostname),
+			Cause:     fmt.Sprintf("Largest unfragmented packet to %s is %d bytes", target, mtu),
+			AlertID:   fmt.Sprintf("mtu-%s-%s", target, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     limit,
+			Unit:      UnitBytes,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    s.geoLabels(map[string]string{"target": target}, target),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
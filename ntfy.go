@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NtfySink publishes alerts to an ntfy topic URL, e.g. https://ntfy.sh/my-alerts.
+type NtfySink struct {
+	httpClient *http.Client
+	url        string
+	token      string
+	log        *Logger
+}
+
+func NewNtfySink(url, token string) *NtfySink {
+	return &NtfySink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		url:   url,
+		token: token,
+		log:   New(),
+	}
+}
+
+func (n *NtfySink) Name() string {
+	return "ntfy"
+}
+
+// ntfyPriority maps severities to ntfy priorities (1 = min, 5 = urgent)
+// and a tag that ntfy renders as an emoji.
+func ntfyPriority(metric Metric) (string, string) {
+	if metric.Status != "fail" {
+		return "2", "white_check_mark"
+	}
+	switch metric.Severity {
+	case SeverityCritical:
+		return "5", "rotating_light"
+	case SeverityWarning:
+		return "4", "warning"
+	default:
+		return "3", "information_source"
+	}
+}
+
+// Accepts only failures, resolutions and digests.
+func (n *NtfySink) Accepts(metric Metric) bool {
+	return notifiable(metric)
+}
+
+func (n *NtfySink) Send(metric Metric) error {
+	req, err := http.NewRequest(http.MethodPost, n.url, strings.NewReader(formatMetricText(metric)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	priority, tag := ntfyPriority(metric)
+	req.Header.Set("Title", metric.Title)
+	req.Header.Set("Priority", priority)
+	req.Header.Set("Tags", tag)
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	return deliver(n.httpClient, req, n.Name(), n.log)
+}
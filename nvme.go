@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// nvmeSmartLog is the SMART / health information log page (02h) as printed
+// by "nvme smart-log -o json". Older nvme-cli releases name the wear field
+// percent_used, newer ones percentage_used.
+type nvmeSmartLog struct {
+	CriticalWarning int     `json:"critical_warning"`
+	Temperature     float64 `json:"temperature"`
+	AvailSpare      float64 `json:"avail_spare"`
+	SpareThresh     float64 `json:"spare_thresh"`
+	PercentUsed     float64 `json:"percent_used"`
+	PercentageUsed  float64 `json:"percentage_used"`
+	MediaErrors     float64 `json:"media_errors"`
+}
+
+func readNVMeSmartLog(ctx context.Context, device string) (*nvmeSmartLog, error) {
+	output, err := runCommand(ctx, "nvme", "smart-log", device, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var smart nvmeSmartLog
+	if err := json.Unmarshal(output, &smart); err != nil {
+		return nil, fmt.Errorf("failed to parse smart log of %s: %v", device, err)
+	}
+	if smart.PercentUsed == 0 {
+		smart.PercentUsed = smart.PercentageUsed
+	}
+	return &smart, nil
+}
+
+func (s *SystemMonitor) checkNVMe(ctx context.Context) error {
+	controllers, err := filepath.Glob("/sys/class/nvme/nvme*")
+	if err != nil {
+		return fmt.Errorf("failed to list NVMe controllers: %v", err)
+	}
+
+	for _, controller := range controllers {
+		name := filepath.Base(controller)
+		device := "/dev/" + name
+		labels := map[string]string{"device": device}
+
+		smart, err := readNVMeSmartLog(ctx, device)
+		if err != nil {
+			s.log.Error("Failed to read SMART log of %s: %v", device, err)
+			continue
+		}
+
+		// The log page reports the composite temperature in Kelvin
+		temperature := smart.Temperature - 273
+		s.recordValue(valueName("nvme", name, "percent_used"), smart.PercentUsed)
+		s.recordValue(valueName("nvme", name, "available_spare"), smart.AvailSpare)
+		s.recordValue(valueName("nvme", name, "media_errors"), smart.MediaErrors)
+		s.recordValue(valueName("nvme", name, "temperature"), temperature)
+		s.log.Log("NVMe %s: %.0f%% used, %.0f%% spare (threshold: %.0f%%), %.0f media errors, %.0f°C",
+			device, smart.PercentUsed, smart.AvailSpare, smart.SpareThresh, smart.MediaErrors, temperature)
+
+		spareStatus := "pass"
+		if smart.AvailSpare < smart.SpareThresh {
+			spareStatus = "fail"
+		}
+		warningStatus := "pass"
+		if smart.CriticalWarning != 0 {
+			warningStatus = "fail"
+		}
+
+		checks := []struct {
+			kind   string
+			title  string
+			cause  string
+			status string
+			value  float64
+			limit  float64
+			unit   string
+			typ    string
+		}{
+			{
+				kind:   "wear",
+				title:  "Wear",
+				cause:  fmt.Sprintf("%.0f%% of the rated endurance used", smart.PercentUsed),
+				status: s.getStatus(smart.PercentUsed, s.config.NVMeWearLimit),
+				value:  smart.PercentUsed,
+				limit:  s.config.NVMeWearLimit,
+				unit:   UnitPercent,
+				typ:    TypeGauge,
+			},
+			{
+				kind:   "spare",
+				title:  "Available Spare",
+				cause:  fmt.Sprintf("%.0f%% spare capacity left, vendor threshold %.0f%%", smart.AvailSpare, smart.SpareThresh),
+				status: spareStatus,
+				value:  smart.AvailSpare,
+				limit:  smart.SpareThresh,
+				unit:   UnitPercent,
+				typ:    TypeGauge,
+			},
+			{
+				kind:   "media-errors",
+				title:  "Media Errors",
+				cause:  fmt.Sprintf("%.0f unrecovered data integrity errors", smart.MediaErrors),
+				status: s.getStatus(smart.MediaErrors, 0),
+				value:  smart.MediaErrors,
+				limit:  0,
+				unit:   UnitCount,
+				typ:    TypeCounter,
+			},
+			{
+				kind:   "temperature",
+				title:  "Temperature",
+				cause:  fmt.Sprintf("Composite temperature %.0f°C", temperature),
+				status: s.getStatus(temperature, s.config.NVMeTemperatureLimit),
+				value:  temperature,
+				limit:  s.config.NVMeTemperatureLimit,
+				unit:   UnitCelsius,
+				typ:    TypeGauge,
+			},
+			{
+				kind:   "critical-warning",
+				title:  "Critical Warning",
+				cause:  fmt.Sprintf("Critical warning flags 0x%02x", smart.CriticalWarning),
+				status: warningStatus,
+				value:  float64(smart.CriticalWarning),
+				limit:  0,
+				unit:   UnitCount,
+				typ:    TypeGauge,
+			},
+		}
+
+		for _, check := range checks {
+			if check.status == "fail" {
+				s.log.Warn("NVMe %s %s: %s", device, check.kind, check.cause)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "nvme",
+				Title:     fmt.Sprintf("NVMe %s %s - %s", name, check.title, s.hostname),
+				Cause:     check.cause,
+				AlertID:   fmt.Sprintf("nvme-%s-%s-%s", check.kind, name, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    check.status,
+				Value:     check.value,
+				Limit:     check.limit,
+				Unit:      check.unit,
+				Type:      check.typ,
+				Severity:  s.getSeverity(check.status, check.value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxExportSize bounds the OpenMetrics text read from the agent API.
+const maxExportSize = 16 * 1024 * 1024
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var openMetricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeOpenMetrics writes the latest collected values as gauges named
+// after their value name, e.g. "disk.data.used_percent" becomes
+// monitoring_disk_data_used_percent, followed by one sample per failing
+// alert, the availability of every alert and the alerting statistics.
+func (s *SystemMonitor) writeOpenMetrics(w io.Writer) error {
+	values := s.collectedValues()
+	alerts := s.alerts.List()
+	uptime := s.uptime.List(time.Now())
+	stats := s.alertStats()
+	var buf bytes.Buffer
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		metric := "monitoring_" + strings.ReplaceAll(name, ".", "_")
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", metric)
+		fmt.Fprintf(&buf, "%s %g\n", metric, values[name])
+	}
+
+	buf.WriteString("# TYPE monitoring_alert_failing gauge\n")
+	buf.WriteString("# HELP monitoring_alert_failing Alerts currently failing.\n")
+	for _, alert := range alerts {
+		if alert.Status != "fail" {
+			continue
+		}
+		fmt.Fprintf(&buf, "monitoring_alert_failing{alert_id=\"%s\",title=\"%s\",acknowledged=\"%t\"} 1\n",
+			openMetricsLabelEscaper.Replace(alert.AlertID),
+			openMetricsLabelEscaper.Replace(alert.Title),
+			alert.Acknowledged)
+	}
+
+	buf.WriteString("# TYPE monitoring_availability_percent gauge\n")
+	buf.WriteString("# HELP monitoring_availability_percent Share of the window alerts were passing.\n")
+	for _, status := range uptime {
+		for _, window := range uptimeWindows {
+			fmt.Fprintf(&buf, "monitoring_availability_percent{alert_id=\"%s\",window=\"%s\"} %g\n",
+				openMetricsLabelEscaper.Replace(status.AlertID), window.name, status.Availability[window.name])
+		}
+	}
+
+	buf.WriteString("# TYPE monitoring_alerts counter\n")
+	buf.WriteString("# HELP monitoring_alerts Alerts by what happened to them since the agent started.\n")
+	outcomes := []struct {
+		name  string
+		count int
+	}{
+		{"sent", stats.Sent},
+		{"recovered", stats.Recovered},
+		{"suppressed", stats.Suppressed},
+		{"repeated", stats.Repeated},
+		{"unrouted", stats.Unrouted},
+		{"held", stats.Held},
+	}
+	for _, outcome := range outcomes {
+		fmt.Fprintf(&buf, "monitoring_alerts_total{outcome=\"%s\"} %d\n", outcome.name, outcome.count)
+	}
+
+	buf.WriteString("# TYPE monitoring_sink_deliveries counter\n")
+	for _, sink := range stats.Sinks {
+		name := openMetricsLabelEscaper.Replace(sink.Name)
+		fmt.Fprintf(&buf, "monitoring_sink_deliveries_total{sink=\"%s\",result=\"delivered\"} %d\n", name, sink.Delivered)
+		fmt.Fprintf(&buf, "monitoring_sink_deliveries_total{sink=\"%s\",result=\"failed\"} %d\n", name, sink.Failed)
+	}
+	buf.WriteString("# TYPE monitoring_sink_latency_seconds gauge\n")
+	buf.WriteString("# HELP monitoring_sink_latency_seconds Average delivery latency of the sink.\n")
+	for _, sink := range stats.Sinks {
+		fmt.Fprintf(&buf, "monitoring_sink_latency_seconds{sink=\"%s\"} %g\n", openMetricsLabelEscaper.Replace(sink.Name), sink.AverageLatencyMS/1000)
+	}
+	buf.WriteString("# TYPE monitoring_sink_circuit_open gauge\n")
+	buf.WriteString("# HELP monitoring_sink_circuit_open Whether deliveries to the sink are paused after consecutive failures.\n")
+	for _, sink := range stats.Sinks {
+		open := 0
+		if sink.Circuit == circuitOpen || sink.Circuit == circuitHalfOpen {
+			open = 1
+		}
+		fmt.Fprintf(&buf, "monitoring_sink_circuit_open{sink=\"%s\"} %d\n", openMetricsLabelEscaper.Replace(sink.Name), open)
+	}
+	buf.WriteString("# EOF\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// GET /metrics
+func (a *APIServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	w.Header().Set("Content-Type", openMetricsContentType)
+	a.monitor.writeOpenMetrics(w)
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so readers such as node_exporter's textfile collector
+// never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+func runExportCommand(args []string) {
+	log := New()
+	fs, api, token := newAPIClientFlags("export")
+	output := fs.String("output", "", "File to write to instead of stdout, e.g. /var/lib/node_exporter/textfile/monitoring.prom")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export [options]\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	data, err := newAPIClient(*api, *token).request(http.MethodGet, "/metrics", maxExportSize+1)
+	if err != nil {
+		log.Fatal("Failed to export metrics: %v", err)
+	}
+	if len(data) > maxExportSize {
+		log.Fatal("Failed to export metrics: response is larger than %d bytes", maxExportSize)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := writeFileAtomic(*output, data); err != nil {
+		log.Fatal("Failed to write %s: %v", *output, err)
+	}
+}
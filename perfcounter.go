@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// windowsCounterDefaults are the counters of --windows-counters, covering
+// what the Linux checks read from /proc: disk queueing, hard page faults,
+// paging file usage and runnable threads.
+var windowsCounterDefaults = []string{
+	`disk_queue=\PhysicalDisk(_Total)\Avg. Disk Queue Length:2`,
+	`page_reads=\Memory\Pages Input/sec:100`,
+	`paging_file=\Paging File(_Total)\% Usage:80`,
+	`processor_queue=\System\Processor Queue Length:10`,
+}
+
+// PerfCounter is a Windows performance counter alerted against Limit, or
+// only recorded for rules when Limit is 0.
+type PerfCounter struct {
+	Name  string
+	Path  string
+	Limit float64
+}
+
+// Unit guesses the unit of the counter from its name: "% Usage" is a
+// percentage, "Pages Input/sec" a rate and anything else a count, such as
+// queue lengths.
+func (c PerfCounter) Unit() string {
+	name := c.Path[strings.LastIndex(c.Path, `\`)+1:]
+	switch {
+	case strings.Contains(name, "%"):
+		return UnitPercent
+	case strings.HasSuffix(name, "/sec"):
+		return UnitPerSecond
+	}
+	return UnitCount
+}
+
+// ParsePerfCounter parses "<name>=<counter>[:<limit>]", e.g.
+// `iis_queue=\HTTP Service Request Queues(_Total)\CurrentQueueSize:100`.
+func ParsePerfCounter(value string) (PerfCounter, error) {
+	name, path, ok := strings.Cut(value, "=")
+	name = strings.TrimSpace(name)
+	path = strings.TrimSpace(path)
+	if !ok || name == "" || !strings.HasPrefix(path, `\`) {
+		return PerfCounter{}, fmt.Errorf(`expected <name>=\<object>(<instance>)\<counter>[:<limit>]`)
+	}
+
+	// Instances may contain colons, e.g. \LogicalDisk(C:)\% Free Space
+	counter := PerfCounter{Name: name, Path: path}
+	if i := strings.LastIndex(path, ":"); i >= 0 {
+		if limit, err := strconv.ParseFloat(strings.TrimSpace(path[i+1:]), 64); err == nil {
+			counter.Path = strings.TrimSpace(path[:i])
+			counter.Limit = limit
+		}
+	}
+	return counter, nil
+}
+
+// readPerfCounters samples the counters with typeperf, which ships with
+// Windows. Rate counters such as Pages/sec need two samples, so the second
+// one is used. Counters without a value, e.g. of a stopped IIS application
+// pool, are missing from the result.
+func readPerfCounters(ctx context.Context, counters []PerfCounter) (map[string]float64, error) {
+	args := []string{"-sc", "2", "-si", "1"}
+	for _, counter := range counters {
+		args = append(args, counter.Path)
+	}
+	output, err := runCommand(ctx, "typeperf", args...)
+
+	// "(PDH-CSV 4.0)","\\host\Memory\Pages/sec",...
+	// "10/16/2026 10:00:00.123","3.000000",...
+	reader := csv.NewReader(bytes.NewReader(output))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var sample []string
+	for {
+		record, readErr := reader.Read()
+		if readErr != nil {
+			break
+		}
+		if len(record) == len(counters)+1 && !strings.HasPrefix(record[0], "(PDH-CSV") {
+			sample = record[1:]
+		}
+	}
+	if sample == nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("typeperf returned no samples: %s", strings.TrimSpace(string(output)))
+	}
+
+	values := map[string]float64{}
+	for i, counter := range counters {
+		if value, err := strconv.ParseFloat(strings.TrimSpace(sample[i]), 64); err == nil {
+			values[counter.Name] = value
+		}
+	}
+	return values, nil
+}
+
+// checkPerfCounters alerts on Windows performance counters above their
+// limit. All counters are sampled in one typeperf run.
+func (s *SystemMonitor) checkPerfCounters(ctx context.Context) error {
+	values, err := readPerfCounters(ctx, s.config.PerfCounters)
+	if err != nil {
+		return fmt.Errorf("failed to read performance counters: %v", err)
+	}
+
+	for _, counter := range s.config.PerfCounters {
+		value, ok := values[counter.Name]
+		if !ok {
+			s.log.Warn("Performance counter %s has no value, check %s", counter.Name, counter.Path)
+			continue
+		}
+		s.recordValue(valueName("perf", counter.Name), value)
+		if counter.Limit == 0 {
+			s.log.Log("Performance counter %s: %.2f", counter.Name, value)
+			continue
+		}
+
+		status := s.getStatus(value, counter.Limit)
+		cause := fmt.Sprintf("%s is %.2f (limit: %.2f)", counter.Path, value, counter.Limit)
+		if status == "fail" {
+			s.log.Warn("Performance counter %s: %s", counter.Name, cause)
+		} else {
+			s.log.Log("Performance counter %s: %s", counter.Name, cause)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "perfcounter",
+			Title:     fmt.Sprintf("Performance Counter %s - %s", counter.Name, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("perfcounter-%s-%s", valueName(counter.Name), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     counter.Limit,
+			Unit:      counter.Unit(),
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"counter": counter.Path},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
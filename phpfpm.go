@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// phpFPMStatus is the JSON status page of a PHP-FPM pool (pm.status_path
+// with ?json).
+type phpFPMStatus struct {
+	Pool               string  `json:"pool"`
+	ListenQueue        float64 `json:"listen queue"`
+	IdleProcesses      float64 `json:"idle processes"`
+	ActiveProcesses    float64 `json:"active processes"`
+	TotalProcesses     float64 `json:"total processes"`
+	MaxChildrenReached float64 `json:"max children reached"`
+}
+
+// checkPHPFPM alerts on pool exhaustion: requests waiting in the listen
+// queue, too many busy workers and the pool hitting pm.max_children since
+// the previous cycle.
+func (s *SystemMonitor) checkPHPFPM(ctx context.Context) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	var status phpFPMStatus
+	if err := getJSON(client, s.config.PHPFPMStatusURL, &status); err != nil {
+		s.log.Warn("PHP-FPM status page failed: %v", err)
+		return s.sendMetric(Metric{
+			Name:      "php-fpm",
+			Title:     fmt.Sprintf("PHP-FPM Status - %s", s.hostname),
+			Cause:     fmt.Sprintf("Failed to read status page: %v", err),
+			AlertID:   fmt.Sprintf("php-fpm-status-%s", s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    "fail",
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  SeverityCritical,
+		})
+	}
+
+	workers := 0.0
+	if status.TotalProcesses > 0 {
+		workers = status.ActiveProcesses / status.TotalProcesses * 100
+	}
+	// The counter resets on restart
+	reached := 0.0
+	if s.phpFPMMaxChildren != nil && status.MaxChildrenReached >= *s.phpFPMMaxChildren {
+		reached = status.MaxChildrenReached - *s.phpFPMMaxChildren
+	}
+	s.phpFPMMaxChildren = &status.MaxChildrenReached
+
+	s.recordValue("php_fpm.listen_queue", status.ListenQueue)
+	s.recordValue("php_fpm.workers_percent", workers)
+	s.recordValue("php_fpm.max_children_reached", reached)
+	cause := fmt.Sprintf("Pool %s: %.0f active, %.0f idle processes, %.0f requests queued", status.Pool, status.ActiveProcesses, status.IdleProcesses, status.ListenQueue)
+	s.log.Log("PHP-FPM %s", cause)
+
+	checks := []struct {
+		kind  string
+		title string
+		value float64
+		limit float64
+		unit  string
+	}{
+		{"listen-queue", "Listen Queue", status.ListenQueue, s.config.PHPFPMQueueLimit, UnitCount},
+		{"workers", "Worker Saturation", workers, s.config.WebWorkersLimit, UnitPercent},
+		{"max-children", "Max Children Reached", reached, 0, UnitCount},
+	}
+	for _, check := range checks {
+		result := s.getStatus(check.value, check.limit)
+		if result == "fail" {
+			s.log.Warn("PHP-FPM %s %.2f exceeds limit of %.2f", check.kind, check.value, check.limit)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "php-fpm",
+			Title:     fmt.Sprintf("PHP-FPM %s - %s", check.title, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("php-fpm-%s-%s", check.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    result,
+			Value:     check.value,
+			Limit:     check.limit,
+			Unit:      check.unit,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(result, check.value, 0),
+			Labels:    map[string]string{"pool": status.Pool},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const pingCount = 10
+
+var pingReply = regexp.MustCompile(`time=([0-9.]+) ?ms`)
+
+// pingResult is one cycle of pings to a target.
+type pingResult struct {
+	Sent     int
+	Received int
+	RTTs     []float64
+}
+
+// pingTarget sends pingCount pings at 200ms intervals and collects the
+// round trip time of every reply.
+func pingTarget(ctx context.Context, host string) (pingResult, error) {
+	output, err := runCommand(ctx, "ping", "-c", strconv.Itoa(pingCount), "-i", "0.2", "-W", "1", host)
+	result := pingResult{Sent: pingCount}
+	for _, match := range pingReply.FindAllSubmatch(output, -1) {
+		rtt, _ := strconv.ParseFloat(string(match[1]), 64)
+		result.RTTs = append(result.RTTs, rtt)
+	}
+	result.Received = len(result.RTTs)
+	// ping exits non-zero when no reply came back, which is a result too,
+	// but not when it could not run or resolve the host
+	if err != nil && len(output) == 0 {
+		return pingResult{}, err
+	}
+	return result, nil
+}
+
+// meanStddev returns the mean and population standard deviation.
+func meanStddev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// checkPing pings every target and alerts on the jitter (standard
+// deviation of the round trip time) of the cycle and the packet loss over
+// the last PingWindow cycles. Unstable links break websockets and
+// realtime updates long before the average round trip time moves.
+func (s *SystemMonitor) checkPing(ctx context.Context) error {
+	if s.pingWindows == nil {
+		s.pingWindows = map[string][]pingResult{}
+	}
+
+	for _, target := range s.config.PingTargets {
+		result, err := pingTarget(ctx, target)
+		if err != nil {
+			s.log.Error("Failed to ping %s: %v", target, err)
+			continue
+		}
+
+		window := append(s.pingWindows[target], result)
+		if len(window) > s.config.PingWindow {
+			window = window[len(window)-s.config.PingWindow:]
+		}
+		s.pingWindows[target] = window
+
+		var sent, received int
+		for _, cycle := range window {
+			sent += cycle.Sent
+			received += cycle.Received
+		}
+		loss := float64(sent-received) / float64(sent) * 100
+		mean, jitter := meanStddev(result.RTTs)
+		s.recordValue(valueName("ping", target, "loss_percent"), loss)
+		if result.Received > 0 {
+			s.recordValue(valueName("ping", target, "rtt_ms"), mean)
+			s.recordValue(valueName("ping", target, "jitter_ms"), jitter)
+		}
+		s.log.Log("Ping %s: %d/%d replies, RTT %.1f ms, jitter %.1f ms, loss %.1f%% over %d cycles", target, result.Received, result.Sent, mean, jitter, loss, len(window))
+
+		type check struct {
+			kind  string
+			title string
+			cause string
+			value float64
+			limit float64
+			unit  string
+		}
+		checks := []check{
+			{"loss", "Packet Loss", fmt.Sprintf("%.1f%% of %d pings lost over the last %d checks", loss, sent, len(window)), loss, s.config.PingLossLimit, UnitPercent},
+		}
+		// Jitter needs a few replies to mean anything
+		if result.Received >= 2 {
+			checks = append(checks, check{"jitter", "Jitter", fmt.Sprintf("Round trip time %.1f ms ± %.1f ms over %d replies", mean, jitter, result.Received), jitter, s.config.PingJitterLimit, UnitMS})
+		}
+
+		for _, check := range checks {
+			status := s.getStatus(check.value, check.limit)
+			cause := check.cause
+			if status == "fail" {
+				s.log.Warn("Ping %s %s %.1f exceeds limit of %.1f", target, check.kind, check.value, check.limit)
+				if result.Received == 0 {
+					cause = s.tracerouteCause(ctx, cause, "//"+target)
+				}
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "ping",
+				Title:     fmt.Sprintf("Ping %s %s - %s", target, check.title, s.hostname),
+				Cause:     cause,
+				AlertID:   fmt.Sprintf("ping-%s-%s-%s", check.kind, target, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     check.value,
+				Limit:     check.limit,
+				Unit:      check.unit,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(status, check.value, 0),
+				Labels:    s.geoLabels(map[string]string{"target": target}, target),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
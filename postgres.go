@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// postgresStatusQuery returns the connection count, max_connections, the
+// age of the longest running transaction and the replication lag in
+// seconds. The lag is the replay delay on a standby and the largest
+// replay_lag of the standbys on a primary.
+const postgresStatusQuery = `SELECT
+	(SELECT count(*) FROM pg_stat_activity),
+	current_setting('max_connections')::int,
+	COALESCE((SELECT EXTRACT(EPOCH FROM max(now() - xact_start)) FROM pg_stat_activity WHERE xact_start IS NOT NULL AND state <> 'idle'), 0),
+	CASE WHEN pg_is_in_recovery()
+		THEN COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		ELSE COALESCE((SELECT EXTRACT(EPOCH FROM max(replay_lag)) FROM pg_stat_replication), 0)
+	END`
+
+type postgresStatus struct {
+	Connections    float64
+	MaxConnections float64
+	TransactionAge float64
+	ReplicationLag float64
+}
+
+// queryPostgres runs the status query with psql, which handles
+// authentication and TLS as configured in the connection URI.
+func queryPostgres(ctx context.Context, uri string) (*postgresStatus, error) {
+	output, err := runCommand(ctx, "psql", uri, "--no-psqlrc", "--tuples-only", "--no-align", "--field-separator=|", "--command="+postgresStatusQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Split(strings.TrimSpace(string(output)), "|")
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("unexpected psql output: %q", strings.TrimSpace(string(output)))
+	}
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return nil, fmt.Errorf("unexpected psql output: %q", strings.TrimSpace(string(output)))
+		}
+	}
+
+	return &postgresStatus{
+		Connections:    values[0],
+		MaxConnections: values[1],
+		TransactionAge: values[2],
+		ReplicationLag: values[3],
+	}, nil
+}
+
+func (s *SystemMonitor) checkPostgres(ctx context.Context) error {
+	start := time.Now()
+	status, err := queryPostgres(ctx, s.config.PostgresURI)
+	latency := float64(time.Since(start).Milliseconds())
+	if err != nil {
+		s.log.Warn("PostgreSQL is not responding: %v", err)
+		return s.sendMetric(Metric{
+			Name:      "postgres",
+			Title:     fmt.Sprintf("PostgreSQL Availability - %s", s.hostname),
+			Cause:     fmt.Sprintf("Query failed: %v", err),
+			AlertID:   fmt.Sprintf("postgres-latency-%s", s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    "fail",
+			Value:     latency,
+			Limit:     s.config.PostgresLatencyLimit,
+			Unit:      UnitMS,
+			Type:      TypeGauge,
+			Severity:  SeverityCritical,
+		})
+	}
+
+	connections := status.Connections / status.MaxConnections * 100
+	s.recordValue("postgres.latency_ms", latency)
+	s.recordValue("postgres.connections", status.Connections)
+	s.recordValue("postgres.connections_percent", connections)
+	s.recordValue("postgres.transaction_age_seconds", status.TransactionAge)
+	s.recordValue("postgres.replication_lag_seconds", status.ReplicationLag)
+	s.log.Log("PostgreSQL: %.0f ms, %.0f/%.0f connections, longest transaction %.0fs, replication lag %.0fs",
+		latency, status.Connections, status.MaxConnections, status.TransactionAge, status.ReplicationLag)
+
+	checks := []struct {
+		kind  string
+		title string
+		cause string
+		value float64
+		limit float64
+		unit  string
+	}{
+		{"latency", "Availability", fmt.Sprintf("Status query took %.0f ms", latency), latency, s.config.PostgresLatencyLimit, UnitMS},
+		{"connections", "Connections", fmt.Sprintf("%.0f of %.0f connections in use", status.Connections, status.MaxConnections), connections, s.config.PostgresConnectionsLimit, UnitPercent},
+		{"transaction-age", "Transaction Age", fmt.Sprintf("Longest running transaction started %.0f seconds ago", status.TransactionAge), status.TransactionAge, s.config.PostgresTransactionAgeLimit.Seconds(), UnitSeconds},
+		{"replication-lag", "Replication Lag", fmt.Sprintf("Replication is %.0f seconds behind", status.ReplicationLag), status.ReplicationLag, s.config.PostgresReplicationLagLimit.Seconds(), UnitSeconds},
+	}
+	for _, check := range checks {
+		result := s.getStatus(check.value, check.limit)
+		if result == "fail" {
+			s.log.Warn("PostgreSQL %s %.2f exceeds limit of %.2f", check.kind, check.value, check.limit)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "postgres",
+			Title:     fmt.Sprintf("PostgreSQL %s - %s", check.title, s.hostname),
+			Cause:     check.cause,
+			AlertID:   fmt.Sprintf("postgres-%s-%s", check.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    result,
+			Value:     check.value,
+			Limit:     check.limit,
+			Unit:      check.unit,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(result, check.value, 0),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Probe is a remote target probed on behalf of a host that can't run the
+// agent, e.g. a router, a NAS or a managed database.
+type Probe struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	Target  string            `json:"target"`
+	Labels  map[string]string `json:"labels"`
+	Timeout string            `json:"timeout"`
+
+	// Thresholds, 0 to disable
+	LatencyLimitMS float64 `json:"latency_limit_ms"`
+	LossLimit      float64 `json:"loss_limit"`
+	DaysLimit      int     `json:"days_limit"`
+
+	// Type specific settings
+	Status int    `json:"status"`
+	Query  string `json:"query"`
+	Record string `json:"record"`
+	Expect string `json:"expect"`
+
+	timeout time.Duration
+}
+
+// probeResult is the outcome of probing a target once.
+type probeResult struct {
+	Up      bool
+	Latency float64
+	Loss    float64
+	Days    float64
+	Failure string
+}
+
+// LoadProbes reads and validates a JSON file with a list of probes.
+func LoadProbes(path string) ([]Probe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var probes []Probe
+	if err := json.Unmarshal(data, &probes); err != nil {
+		return nil, fmt.Errorf("failed to parse: %v", err)
+	}
+
+	names := map[string]bool{}
+	for i := range probes {
+		probe := &probes[i]
+		if probe.Name == "" || probe.Target == "" {
+			return nil, fmt.Errorf("probe %d: name and target are required", i+1)
+		}
+		if names[probe.Name] {
+			return nil, fmt.Errorf("probe %s: duplicate name", probe.Name)
+		}
+		names[probe.Name] = true
+
+		probe.timeout = 5 * time.Second
+		if probe.Timeout != "" {
+			if probe.timeout, err = time.ParseDuration(probe.Timeout); err != nil || probe.timeout <= 0 {
+				return nil, fmt.Errorf("probe %s: invalid timeout %q", probe.Name, probe.Timeout)
+			}
+		}
+
+		switch probe.Type {
+		case "http":
+			if parsed, err := url.Parse(probe.Target); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+				return nil, fmt.Errorf("probe %s: target must be an http:// or https:// URL", probe.Name)
+			}
+		case "tcp", "tls":
+			if _, _, err := net.SplitHostPort(probe.Target); err != nil {
+				return nil, fmt.Errorf("probe %s: target must be <host>:<port>", probe.Name)
+			}
+			if probe.Type == "tls" && probe.DaysLimit == 0 {
+				probe.DaysLimit = 14
+			}
+		case "icmp":
+			if probe.LossLimit == 0 {
+				probe.LossLimit = 50
+			}
+		case "dns":
+			if probe.Query == "" {
+				return nil, fmt.Errorf("probe %s: query is required", probe.Name)
+			}
+			if _, _, err := net.SplitHostPort(probe.Target); err != nil {
+				probe.Target = net.JoinHostPort(probe.Target, "53")
+			}
+			probe.Record = strings.ToUpper(probe.Record)
+			if probe.Record == "" {
+				probe.Record = "A"
+			}
+			switch probe.Record {
+			case "A", "AAAA", "CNAME", "MX", "NS", "TXT":
+			default:
+				return nil, fmt.Errorf("probe %s: unknown record %q, expected A, AAAA, CNAME, MX, NS or TXT", probe.Name, probe.Record)
+			}
+		default:
+			return nil, fmt.Errorf("probe %s: unknown type %q, expected http, tcp, icmp, dns or tls", probe.Name, probe.Type)
+		}
+	}
+	return probes, nil
+}
+
+// Run probes the target once.
+func (p Probe) Run(ctx context.Context) probeResult {
+	start := time.Now()
+	elapsed := func() float64 {
+		return float64(time.Since(start).Microseconds()) / 1000
+	}
+
+	switch p.Type {
+	case "http":
+		client := &http.Client{Timeout: p.timeout}
+		latency, failures, err := HTTPCheck{Name: p.Name, URL: p.Target, Status: p.Status}.probe(ctx, client)
+		if err != nil {
+			return probeResult{Failure: err.Error()}
+		}
+		if len(failures) > 0 {
+			return probeResult{Latency: latency, Failure: strings.Join(failures, ", ")}
+		}
+		return probeResult{Up: true, Latency: latency}
+
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", p.Target, p.timeout)
+		if err != nil {
+			return probeResult{Failure: err.Error()}
+		}
+		conn.Close()
+		return probeResult{Up: true, Latency: elapsed()}
+
+	case "tls":
+		host, _, _ := net.SplitHostPort(p.Target)
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: p.timeout}, "tcp", p.Target, &tls.Config{ServerName: host})
+		if err != nil {
+			return probeResult{Failure: err.Error()}
+		}
+		defer conn.Close()
+		certificates := conn.ConnectionState().PeerCertificates
+		if len(certificates) == 0 {
+			return probeResult{Failure: "no certificate presented"}
+		}
+		days := time.Until(certificates[0].NotAfter).Hours() / 24
+		return probeResult{Up: true, Latency: elapsed(), Days: days}
+
+	case "icmp":
+		result, err := pingTarget(ctx, p.Target)
+		if err != nil {
+			return probeResult{Failure: err.Error()}
+		}
+		loss := 100 * float64(result.Sent-result.Received) / float64(result.Sent)
+		if result.Received == 0 {
+			return probeResult{Loss: loss, Failure: "no replies"}
+		}
+		mean, _ := meanStddev(result.RTTs)
+		return probeResult{Up: true, Latency: mean, Loss: loss}
+
+	case "dns":
+		answers, err := p.resolve()
+		if err != nil {
+			return probeResult{Failure: err.Error()}
+		}
+		if p.Expect != "" {
+			found := false
+			for _, answer := range answers {
+				found = found || strings.TrimSuffix(answer, ".") == strings.TrimSuffix(p.Expect, ".")
+			}
+			if !found {
+				return probeResult{Latency: elapsed(), Failure: fmt.Sprintf("%s %s is %s, expected %s", p.Query, p.Record, strings.Join(answers, " "), p.Expect)}
+			}
+		}
+		return probeResult{Up: true, Latency: elapsed()}
+	}
+	return probeResult{Failure: "unknown probe type"}
+}
+
+// resolve queries the record of the probe at the target server.
+func (p Probe) resolve() ([]string, error) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: p.timeout}).DialContext(ctx, network, p.Target)
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var answers []string
+	switch p.Record {
+	case "A", "AAAA":
+		network := "ip4"
+		if p.Record == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, p.Query)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, p.Query)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, p.Query)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, record.Host)
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, p.Query)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, record.Host)
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, p.Query)
+		if err != nil {
+			return nil, err
+		}
+		answers = records
+	}
+	return answers, nil
+}
+
+// checkProbes probes every target in parallel, so slow targets don't add
+// up, and alerts on unreachable targets and exceeded thresholds. Each
+// probe has its own alert with the probe's labels for routing.
+func (s *SystemMonitor) checkProbes(ctx context.Context) error {
+	results := make([]probeResult, len(s.config.Probes))
+	var wg sync.WaitGroup
+	for i, probe := range s.config.Probes {
+		wg.Add(1)
+		go func(i int, probe Probe) {
+			defer wg.Done()
+			results[i] = probe.Run(ctx)
+		}(i, probe)
+	}
+	wg.Wait()
+
+	for i, probe := range s.config.Probes {
+		result := results[i]
+		up := 0.0
+		if result.Up {
+			up = 1
+		}
+		s.recordValue(valueName("probe", probe.Name, "up"), up)
+		s.recordValue(valueName("probe", probe.Name, "latency_ms"), result.Latency)
+		if probe.Type == "icmp" {
+			s.recordValue(valueName("probe", probe.Name, "loss_percent"), result.Loss)
+		}
+		if probe.Type == "tls" && result.Up {
+			s.recordValue(valueName("probe", probe.Name, "days_left"), result.Days)
+		}
+
+		status := "pass"
+		severity := SeverityInfo
+		value, limit, unit := result.Latency, probe.LatencyLimitMS, UnitMS
+		cause := fmt.Sprintf("%s probe of %s answered in %.1f ms", probe.Type, probe.Target, result.Latency)
+		switch {
+		case !result.Up:
+			status, severity = "fail", SeverityCritical
+			cause = fmt.Sprintf("%s probe of %s failed: %s", probe.Type, probe.Target, result.Failure)
+		case probe.Type == "icmp" && result.Loss > probe.LossLimit:
+			status, severity = "fail", SeverityWarning
+			value, limit, unit = result.Loss, probe.LossLimit, UnitPercent
+			cause = fmt.Sprintf("%s loses %.0f%% of pings, limit %.0f%%", probe.Target, result.Loss, probe.LossLimit)
+		case probe.Type == "tls" && result.Days < float64(probe.DaysLimit):
+			status, severity = "fail", SeverityWarning
+			value, limit, unit = result.Days, float64(probe.DaysLimit), UnitDays
+			cause = fmt.Sprintf("Certificate of %s expires in %.0f days, limit %d", probe.Target, result.Days, probe.DaysLimit)
+		case probe.LatencyLimitMS > 0 && result.Latency > probe.LatencyLimitMS:
+			status, severity = "fail", SeverityWarning
+			cause = fmt.Sprintf("%s probe of %s answered in %.1f ms, limit %.1f ms", probe.Type, probe.Target, result.Latency, probe.LatencyLimitMS)
+		}
+		if status == "fail" {
+			s.log.Warn("Probe %s: %s", probe.Name, cause)
+		}
+
+		labels := map[string]string{}
+		for name, value := range probe.Labels {
+			labels[name] = value
+		}
+		labels["probe"] = probe.Name
+		labels["probe_type"] = probe.Type
+		labels = s.geoLabels(labels, probe.Target)
+
+		if err := s.sendMetric(Metric{
+			Name:      "probe",
+			Title:     fmt.Sprintf("Probe %s - %s", probe.Name, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("probe-%s-%s", valueName(probe.Name), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     limit,
+			Unit:      unit,
+			Type:      TypeGauge,
+			Severity:  severity,
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+type processUsage struct {
+	PID   int32
+	Name  string
+	Usage float64
+}
+
+// topProcesses returns the n processes using the most of resource ("cpu"
+// or "memory"). CPU usage is measured over a one second window.
+func topProcesses(ctx context.Context, resource string, n int) ([]processUsage, error) {
+	processes, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %v", err)
+	}
+
+	usages := make([]processUsage, 0, len(processes))
+	switch resource {
+	case "cpu":
+		before := map[int32]float64{}
+		for _, p := range processes {
+			if times, err := p.TimesWithContext(ctx); err == nil {
+				before[p.Pid] = times.User + times.System
+			}
+		}
+
+		start := time.Now()
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		elapsed := time.Since(start).Seconds()
+
+		for _, p := range processes {
+			previous, ok := before[p.Pid]
+			if !ok {
+				continue
+			}
+			times, err := p.TimesWithContext(ctx)
+			if err != nil {
+				continue
+			}
+			usages = append(usages, processUsage{
+				PID:   p.Pid,
+				Usage: (times.User + times.System - previous) / elapsed * 100,
+			})
+		}
+
+	case "memory":
+		for _, p := range processes {
+			percent, err := p.MemoryPercent()
+			if err != nil {
+				continue
+			}
+			usages = append(usages, processUsage{
+				PID:   p.Pid,
+				Usage: float64(percent),
+			})
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown resource %q", resource)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Usage > usages[j].Usage
+	})
+	if len(usages) > n {
+		usages = usages[:n]
+	}
+
+	for i := range usages {
+		p, err := process.NewProcess(usages[i].PID)
+		if err == nil {
+			usages[i].Name, _ = p.Name()
+		}
+		if usages[i].Name == "" {
+			usages[i].Name = "unknown"
+		}
+	}
+
+	return usages, nil
+}
+
+// topProcessesCause appends the top consumers of resource to cause, so
+// responders immediately see what is eating the host.
+func (s *SystemMonitor) topProcessesCause(ctx context.Context, cause, resource string) string {
+	if s.config.TopProcesses <= 0 {
+		return cause
+	}
+
+	usages, err := topProcesses(ctx, resource, s.config.TopProcesses)
+	if err != nil {
+		s.log.Error("Failed to get top processes: %v", err)
+		return cause
+	}
+
+	parts := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		parts = append(parts, fmt.Sprintf("%s (%d) %.1f%%", usage.Name, usage.PID, usage.Usage))
+	}
+	s.log.Warn("Top processes by %s: %s", resource, strings.Join(parts, ", "))
+
+	return fmt.Sprintf("%s. Top processes: %s", cause, strings.Join(parts, ", "))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// promSample is a sample of the Prometheus text exposition format, e.g.
+// traefik_entrypoint_requests_total{code="502",entrypoint="websecure"} 3.
+type promSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// parsePromText parses the Prometheus text exposition format. Comments,
+// timestamps and malformed lines are skipped.
+func parsePromText(r io.Reader) ([]promSample, error) {
+	var samples []promSample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sample := promSample{Labels: map[string]string{}}
+		rest := line
+		if open := strings.IndexByte(line, '{'); open >= 0 {
+			close := strings.LastIndexByte(line, '}')
+			if close < open {
+				continue
+			}
+			sample.Name = line[:open]
+			parsePromLabels(line[open+1:close], sample.Labels)
+			rest = strings.TrimSpace(line[close+1:])
+		} else {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			sample.Name = fields[0]
+			rest = strings.Join(fields[1:], " ")
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		sample.Value = value
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+// parsePromLabels parses `code="502",entrypoint="websecure"` into labels.
+func parsePromLabels(text string, labels map[string]string) {
+	for text != "" {
+		equals := strings.IndexByte(text, '=')
+		if equals < 0 || equals+1 >= len(text) || text[equals+1] != '"' {
+			return
+		}
+		name := strings.TrimSpace(text[:equals])
+
+		var value strings.Builder
+		i := equals + 2
+		for ; i < len(text) && text[i] != '"'; i++ {
+			if text[i] == '\\' && i+1 < len(text) {
+				i++
+				if text[i] == 'n' {
+					value.WriteByte('\n')
+					continue
+				}
+			}
+			value.WriteByte(text[i])
+		}
+		labels[name] = value.String()
+
+		if i < len(text) {
+			i++
+		}
+		text = strings.TrimLeft(text[i:], ", ")
+	}
+}
+
+// scrapePrometheus fetches and parses a Prometheus metrics endpoint.
+func scrapePrometheus(ctx context.Context, client *http.Client, url string) ([]promSample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "text/plain")
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %s", url, resp.Status)
+	}
+	return parsePromText(resp.Body)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// publicIP asks an echo service like api64.ipify.org for the address the
+// host appears from, forcing network to "tcp4" or "tcp6".
+func publicIP(rawURL, network string) (net.IP, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %s", rawURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q from %s", strings.TrimSpace(string(body)), rawURL)
+	}
+	return ip, nil
+}
+
+// checkPublicIP alerts for one cycle when the public IPv4 or IPv6 address
+// changes, and for as long as --public-ip-domain does not resolve to it.
+// The first cycle only records the addresses.
+func (s *SystemMonitor) checkPublicIP(ctx context.Context) error {
+	if s.publicIPs == nil {
+		s.publicIPs = map[string]string{}
+	}
+
+	families := []struct {
+		kind    string
+		title   string
+		network string
+	}{
+		{"ipv4", "IPv4", "tcp4"},
+		{"ipv6", "IPv6", "tcp6"},
+	}
+
+	for _, family := range families {
+		ip, err := publicIP(s.config.PublicIPURL, family.network)
+		if err != nil {
+			// Many hosts have no IPv6 connectivity at all
+			if family.kind == "ipv6" {
+				s.log.Log("No public IPv6 address: %v", err)
+				continue
+			}
+			return err
+		}
+		current := ip.String()
+
+		previous, known := s.publicIPs[family.kind]
+		s.publicIPs[family.kind] = current
+		status := "pass"
+		value := 0.0
+		cause := fmt.Sprintf("Public %s address is %s", family.title, current)
+		if known && previous != current {
+			status = "fail"
+			value = 1
+			cause = fmt.Sprintf("Public %s address changed from %s to %s", family.title, previous, current)
+			s.log.Warn("%s", cause)
+		} else {
+			s.log.Log("%s", cause)
+		}
+
+		if s.config.PublicIPDomain != "" && status == "pass" {
+			records, err := net.LookupIP(s.config.PublicIPDomain)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %v", s.config.PublicIPDomain, err)
+			}
+			var addresses []string
+			matched := false
+			for _, record := range records {
+				if (record.To4() != nil) != (family.kind == "ipv4") {
+					continue
+				}
+				addresses = append(addresses, record.String())
+				matched = matched || record.Equal(ip)
+			}
+			// A domain without records of this family is not managed for it
+			if len(addresses) > 0 && !matched {
+				status = "fail"
+				value = 1
+				cause = fmt.Sprintf("%s resolves to %s, but the public %s address is %s", s.config.PublicIPDomain, strings.Join(addresses, ", "), family.title, current)
+				s.log.Warn("%s", cause)
+			}
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "public-ip",
+			Title:     fmt.Sprintf("Public %s Address - %s", family.title, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("public-ip-%s-%s", family.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    s.geoLabels(map[string]string{"address": current}, current),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
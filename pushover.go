@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pushoverURL = "https://api.pushover.net/1/messages.json"
+
+type PushoverSink struct {
+	httpClient *http.Client
+	token      string
+	user       string
+	log        *Logger
+}
+
+func NewPushoverSink(token, user string) (*PushoverSink, error) {
+	if user == "" {
+		return nil, fmt.Errorf("Pushover user key is required")
+	}
+
+	return &PushoverSink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		token: token,
+		user:  user,
+		log:   New(),
+	}, nil
+}
+
+func (p *PushoverSink) Name() string {
+	return "pushover"
+}
+
+// pushoverPriority maps severities to Pushover priorities. Emergency (2)
+// is not used as it requires acknowledgement handling.
+func pushoverPriority(metric Metric) int {
+	if metric.Status != "fail" {
+		return -1
+	}
+	switch metric.Severity {
+	case SeverityCritical:
+		return 1
+	case SeverityWarning:
+		return 0
+	default:
+		return -1
+	}
+}
+
+// Accepts only failures, resolutions and digests.
+func (p *PushoverSink) Accepts(metric Metric) bool {
+	return notifiable(metric)
+}
+
+func (p *PushoverSink) Send(metric Metric) error {
+	form := url.Values{}
+	form.Set("token", p.token)
+	form.Set("user", p.user)
+	form.Set("title", metric.Title)
+	form.Set("message", formatMetricText(metric))
+	form.Set("priority", strconv.Itoa(pushoverPriority(metric)))
+	form.Set("timestamp", strconv.FormatInt(metric.Timestamp, 10))
+
+	req, err := http.NewRequest(http.MethodPost, pushoverURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	return deliver(p.httpClient, req, p.Name(), p.log)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuietHours is a daily period during which a sink only receives critical
+// failures. Without a sink it applies to every sink without quiet hours
+// of its own; Off exempts a sink from the shared quiet hours.
+type QuietHours struct {
+	Sink string
+	clockWindow
+	Off bool
+}
+
+type QuietSchedule []QuietHours
+
+// ParseQuietHours parses "[<sink>=]<start>-<end>" or "<sink>=off", for
+// example "22:00-07:00" or "mattermost=20:00-08:00".
+func ParseQuietHours(value string, sinks []Sink) (QuietHours, error) {
+	var quiet QuietHours
+	period := value
+	if sink, rest, ok := strings.Cut(value, "="); ok {
+		quiet.Sink = strings.TrimSpace(sink)
+		period = rest
+
+		known := false
+		for _, s := range sinks {
+			known = known || s.Name() == quiet.Sink
+		}
+		if !known {
+			return QuietHours{}, fmt.Errorf("unknown sink %q", quiet.Sink)
+		}
+		if strings.TrimSpace(period) == "off" {
+			quiet.Off = true
+			return quiet, nil
+		}
+	}
+
+	window, err := parseClockWindow(period)
+	if err != nil {
+		return QuietHours{}, err
+	}
+	quiet.clockWindow = window
+	return quiet, nil
+}
+
+// clockWindow is a daily period in minutes after midnight, local time.
+type clockWindow struct {
+	Start int
+	End   int
+}
+
+// parseClockWindow parses "<start>-<end>", e.g. "22:00-07:00".
+func parseClockWindow(value string) (clockWindow, error) {
+	start, end, ok := strings.Cut(value, "-")
+	if !ok {
+		return clockWindow{}, fmt.Errorf("expected <start>-<end>, e.g. 22:00-07:00")
+	}
+	var window clockWindow
+	var err error
+	if window.Start, err = parseClock(start); err != nil {
+		return clockWindow{}, err
+	}
+	if window.End, err = parseClock(end); err != nil {
+		return clockWindow{}, err
+	}
+	if window.Start == window.End {
+		return clockWindow{}, fmt.Errorf("start and end must differ")
+	}
+	return window, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains returns whether the local time of day at is within the window,
+// which wraps around midnight when it ends before it starts.
+func (w clockWindow) contains(at time.Time) bool {
+	minute := at.Hour()*60 + at.Minute()
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// Quiet returns whether the sink is in its quiet hours at the given time.
+// Quiet hours of the sink itself take precedence over shared ones.
+func (s QuietSchedule) Quiet(sink string, at time.Time) bool {
+	var shared, own []QuietHours
+	for _, quiet := range s {
+		switch quiet.Sink {
+		case "":
+			shared = append(shared, quiet)
+		case sink:
+			own = append(own, quiet)
+		}
+	}
+	if len(own) > 0 {
+		shared = own
+	}
+	for _, quiet := range shared {
+		if !quiet.Off && quiet.contains(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// heldAlert is a failure held back during quiet hours, reported in the
+// next digest.
+type heldAlert struct {
+	AlertID string
+	Title   string
+	Cause   string
+	Count   int
+	First   time.Time
+	Last    time.Time
+}
+
+// heldAlerts collects failures held back during quiet hours.
+type heldAlerts struct {
+	mu     sync.Mutex
+	alerts map[string]*heldAlert
+}
+
+func newHeldAlerts() *heldAlerts {
+	return &heldAlerts{alerts: map[string]*heldAlert{}}
+}
+
+func (h *heldAlerts) Add(metric Metric, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	held, ok := h.alerts[metric.AlertID]
+	if !ok {
+		held = &heldAlert{AlertID: metric.AlertID, First: at}
+		h.alerts[metric.AlertID] = held
+	}
+	held.Title = metric.Title
+	held.Cause = metric.Cause
+	held.Count++
+	held.Last = at
+}
+
+// Take returns the held alerts, earliest first, and clears them.
+func (h *heldAlerts) Take() []heldAlert {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list := make([]heldAlert, 0, len(h.alerts))
+	for _, held := range h.alerts {
+		list = append(list, *held)
+	}
+	h.alerts = map[string]*heldAlert{}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].First.Before(list[j].First)
+	})
+	return list
+}
+
+// quietTargets removes the sinks in their quiet hours from targets,
+// unless the metric is a critical failure or a digest, which is sent at
+// the hour it was scheduled for. Held back failures are kept for the
+// next digest.
+func (s *SystemMonitor) quietTargets(metric Metric, targets []Sink) []Sink {
+	if len(s.config.QuietHours) == 0 || metric.Name == "digest" {
+		return targets
+	}
+	if metric.Status == "fail" && metric.Severity == SeverityCritical {
+		return targets
+	}
+
+	now := s.now()
+	var delivered []Sink
+	for _, sink := range targets {
+		if !s.config.QuietHours.Quiet(sink.Name(), now) {
+			delivered = append(delivered, sink)
+		}
+	}
+	if len(delivered) < len(targets) && metric.Status == "fail" {
+		s.held.Add(metric, now)
+		s.counters.Add("held")
+		s.log.Log("Alert %s held back during quiet hours", metric.AlertID)
+	}
+	return delivered
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RabbitMQQueue overrides the depth limit for queues matching a glob
+// pattern.
+type RabbitMQQueue struct {
+	Pattern string
+	Limit   float64
+}
+
+// ParseRabbitMQQueue parses "<queue pattern>:<limit>", e.g. "mails.*:1000".
+func ParseRabbitMQQueue(value string) (RabbitMQQueue, error) {
+	separator := strings.LastIndex(value, ":")
+	if separator <= 0 {
+		return RabbitMQQueue{}, fmt.Errorf("expected <queue pattern>:<limit>")
+	}
+
+	pattern := strings.TrimSpace(value[:separator])
+	if _, err := path.Match(pattern, ""); err != nil {
+		return RabbitMQQueue{}, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	limit, err := strconv.ParseFloat(strings.TrimSpace(value[separator+1:]), 64)
+	if err != nil || limit < 0 {
+		return RabbitMQQueue{}, fmt.Errorf("invalid limit %q", value[separator+1:])
+	}
+
+	return RabbitMQQueue{Pattern: pattern, Limit: limit}, nil
+}
+
+type rabbitMQQueueStats struct {
+	Name                   string  `json:"name"`
+	VHost                  string  `json:"vhost"`
+	MessagesReady          float64 `json:"messages_ready"`
+	MessagesUnacknowledged float64 `json:"messages_unacknowledged"`
+}
+
+type rabbitMQNode struct {
+	Name          string `json:"name"`
+	Running       bool   `json:"running"`
+	MemAlarm      bool   `json:"mem_alarm"`
+	DiskFreeAlarm bool   `json:"disk_free_alarm"`
+}
+
+// queueLimit returns the depth limit of a queue, the first matching
+// per-queue limit or the default.
+func (s *SystemMonitor) queueLimit(name string) float64 {
+	for _, queue := range s.config.RabbitMQQueues {
+		if matched, _ := path.Match(queue.Pattern, name); matched {
+			return queue.Limit
+		}
+	}
+	return s.config.RabbitMQQueueLimit
+}
+
+// checkRabbitMQ reads queues and nodes from the management API, alerting
+// on deep queues, unacknowledged messages and memory or disk alarms.
+func (s *SystemMonitor) checkRabbitMQ(ctx context.Context) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	base := strings.TrimSuffix(s.config.RabbitMQURL, "/")
+
+	var nodes []rabbitMQNode
+	if err := getJSON(client, base+"/api/nodes", &nodes); err != nil {
+		return fmt.Errorf("failed to get nodes: %v", err)
+	}
+
+	for _, node := range nodes {
+		var alarms []string
+		if !node.Running {
+			alarms = append(alarms, "node is not running")
+		}
+		if node.MemAlarm {
+			alarms = append(alarms, "memory alarm")
+		}
+		if node.DiskFreeAlarm {
+			alarms = append(alarms, "disk free alarm")
+		}
+
+		status := "pass"
+		cause := "Node is running without alarms"
+		value := float64(len(alarms))
+		if len(alarms) > 0 {
+			// Alarms block all publishers until they clear
+			status = "fail"
+			cause = strings.Join(alarms, ", ")
+			s.log.Warn("RabbitMQ node %s: %s", node.Name, cause)
+		}
+		s.recordValue(valueName("rabbitmq", node.Name, "alarms"), value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "rabbitmq",
+			Title:     fmt.Sprintf("RabbitMQ Node %s - %s", node.Name, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("rabbitmq-node-%s-%s", valueName(node.Name), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitCount,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"node": node.Name},
+		}); err != nil {
+			return err
+		}
+	}
+
+	var queues []rabbitMQQueueStats
+	if err := getJSON(client, base+"/api/queues", &queues); err != nil {
+		return fmt.Errorf("failed to get queues: %v", err)
+	}
+
+	for _, queue := range queues {
+		s.recordValue(valueName("rabbitmq", queue.VHost, queue.Name, "ready"), queue.MessagesReady)
+		s.recordValue(valueName("rabbitmq", queue.VHost, queue.Name, "unacked"), queue.MessagesUnacknowledged)
+		labels := map[string]string{"vhost": queue.VHost, "queue": queue.Name}
+
+		checks := []struct {
+			kind  string
+			title string
+			value float64
+			limit float64
+		}{
+			{"ready", "Depth", queue.MessagesReady, s.queueLimit(queue.Name)},
+			{"unacked", "Unacknowledged", queue.MessagesUnacknowledged, s.config.RabbitMQUnackedLimit},
+		}
+		for _, check := range checks {
+			if check.limit <= 0 {
+				continue
+			}
+			status := s.getStatus(check.value, check.limit)
+			if status == "fail" {
+				s.log.Warn("RabbitMQ queue %s %s %.0f exceeds limit of %.0f", queue.Name, check.kind, check.value, check.limit)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "rabbitmq",
+				Title:     fmt.Sprintf("RabbitMQ Queue %s %s - %s", queue.Name, check.title, s.hostname),
+				Cause:     fmt.Sprintf("%.0f ready, %.0f unacknowledged messages", queue.MessagesReady, queue.MessagesUnacknowledged),
+				AlertID:   fmt.Sprintf("rabbitmq-%s-%s-%s-%s", check.kind, valueName(queue.VHost), valueName(queue.Name), s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     check.value,
+				Limit:     check.limit,
+				Unit:      UnitCount,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(status, check.value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	s.log.Log("RabbitMQ: %d nodes, %d queues", len(nodes), len(queues))
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// kernelSegments splits a kernel release into runs of digits and of
+// letters, e.g. "5.14.0-362.13.1.el9_3.x86_64" into 5 14 0 362 13 1 el 9
+// 3 x 86 64.
+func kernelSegments(release string) []string {
+	var segments []string
+	start := -1
+	for i := 0; i <= len(release); i++ {
+		if start >= 0 && (i == len(release) || isDigit(release[i]) != isDigit(release[start]) || !isAlphanumeric(release[i])) {
+			segments = append(segments, release[start:i])
+			start = -1
+		}
+		if start < 0 && i < len(release) && isAlphanumeric(release[i]) {
+			start = i
+		}
+	}
+	return segments
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlphanumeric(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// compareKernelReleases returns -1, 0 or 1 when kernel release a is older
+// than, the same as or newer than b, comparing digits as numbers like
+// rpm and dpkg do, so 5.15.0-101 is newer than 5.15.0-91.
+func compareKernelReleases(a, b string) int {
+	segmentsA, segmentsB := kernelSegments(a), kernelSegments(b)
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		x, y := segmentsA[i], segmentsB[i]
+		if isDigit(x[0]) && isDigit(y[0]) {
+			x, y = strings.TrimLeft(x, "0"), strings.TrimLeft(y, "0")
+			if len(x) != len(y) {
+				if len(x) < len(y) {
+					return -1
+				}
+				return 1
+			}
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(segmentsA) < len(segmentsB):
+		return -1
+	case len(segmentsA) > len(segmentsB):
+		return 1
+	}
+	return 0
+}
+
+// rebootRequired reports whether the host needs a reboot and why. Debian
+// and Ubuntu flag it in /var/run/reboot-required; elsewhere a newer
+// kernel than the running one in /lib/modules gives it away. Kernels are
+// compared by version, as DKMS or depmod rebuild the modules of older
+// ones too.
+func rebootRequired() (bool, string, error) {
+	if _, err := os.Stat("/var/run/reboot-required"); err == nil {
+		cause := "/var/run/reboot-required is present"
+		if data, err := os.ReadFile("/var/run/reboot-required.pkgs"); err == nil {
+			if packages := strings.Fields(string(data)); len(packages) > 0 {
+				cause = fmt.Sprintf("Updated packages: %s", strings.Join(packages, ", "))
+			}
+		}
+		return true, cause, nil
+	}
+
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read running kernel release: %v", err)
+	}
+	running := strings.TrimSpace(string(release))
+
+	modules, err := filepath.Glob("/lib/modules/*")
+	if err != nil {
+		return false, "", fmt.Errorf("failed to list installed kernels: %v", err)
+	}
+
+	// Directories of removed kernels are left without modules.dep
+	newest := running
+	for _, module := range modules {
+		if _, err := os.Stat(filepath.Join(module, "modules.dep")); err != nil {
+			continue
+		}
+		if compareKernelReleases(filepath.Base(module), newest) > 0 {
+			newest = filepath.Base(module)
+		}
+	}
+
+	if newest != running {
+		return true, fmt.Sprintf("Running kernel %s, newest installed kernel %s", running, newest), nil
+	}
+	return false, fmt.Sprintf("Running kernel %s is the newest installed kernel", running), nil
+}
+
+// checkRebootRequired raises a warning for as long as the host waits for
+// a reboot, so kernel updates are not forgotten.
+func (s *SystemMonitor) checkRebootRequired(ctx context.Context) error {
+	required, cause, err := rebootRequired()
+	if err != nil {
+		return err
+	}
+
+	status := "pass"
+	severity := SeverityInfo
+	value := 0.0
+	if required {
+		status = "fail"
+		severity = SeverityWarning
+		value = 1
+		s.log.Warn("Reboot required: %s", cause)
+	} else {
+		s.log.Log("No reboot required")
+	}
+	s.recordValue("reboot.required", value)
+
+	return s.sendMetric(Metric{
+		Name:      "reboot",
+		Title:     fmt.Sprintf("Reboot Required - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("reboot-required-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     0,
+		Unit:      UnitBoolean,
+		Type:      TypeGauge,
+		Severity:  severity,
+	})
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// remoteScript reads everything needed for a cycle in one SSH session,
+// with only tools found on any Linux host. Sections start with "@@".
+const remoteScript = "export LC_ALL=C; " +
+	"echo @@stat; head -n 1 /proc/stat; " +
+	"echo @@meminfo; cat /proc/meminfo; " +
+	"echo @@loadavg; cat /proc/loadavg; " +
+	"echo @@df; df -P -k 2>/dev/null; true"
+
+// pseudoFilesystems are skipped in the df output of
+// remote hosts.
+var pseudoFilesystems = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "overlay": true, "squashfs": true, "udev": true, "none": true, "shm": true,
+}
+
+// RemoteHost is a host without the agent, polled over SSH with key
+// authentication.
+type RemoteHost struct {
+	Name    string
+	User    string
+	Address string
+	Port    int
+}
+
+// ParseRemoteHost parses "[<name>=]<user>@<host>[:<port>]", e.g.
+// "nas=monitor@10.0.0.5:2222" or "monitor@[2001:db8::5]:2222". The name
+// defaults to the host. Users and hosts can't start with "-", ssh would
+// take them for options.
+func ParseRemoteHost(value string) (RemoteHost, error) {
+	remote := RemoteHost{Port: 22}
+	if name, rest, ok := strings.Cut(value, "="); ok {
+		remote.Name = strings.TrimSpace(name)
+		value = rest
+	}
+	user, address, ok := strings.Cut(strings.TrimSpace(value), "@")
+	if !ok || user == "" || address == "" {
+		return RemoteHost{}, fmt.Errorf("expected [<name>=]<user>@<host>[:<port>]")
+	}
+	remote.User = user
+	remote.Address = strings.Trim(address, "[]")
+	// A bare IPv6 address has colons but no port
+	if strings.Contains(address, ":") && net.ParseIP(remote.Address) == nil {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return RemoteHost{}, fmt.Errorf("invalid host %q: %v", address, err)
+		}
+		number, err := strconv.Atoi(port)
+		if err != nil || number < 1 || number > 65535 {
+			return RemoteHost{}, fmt.Errorf("invalid port %q", port)
+		}
+		remote.Address = host
+		remote.Port = number
+	}
+	if strings.HasPrefix(remote.Name, "-") {
+		return RemoteHost{}, fmt.Errorf("invalid name %q", remote.Name)
+	}
+	if strings.HasPrefix(remote.User, "-") || strings.ContainsAny(remote.User, " \t\r\n") {
+		return RemoteHost{}, fmt.Errorf("invalid user %q", remote.User)
+	}
+	if remote.Address == "" || strings.HasPrefix(remote.Address, "-") || strings.ContainsAny(remote.Address, " \t\r\n") {
+		return RemoteHost{}, fmt.Errorf("invalid host %q", remote.Address)
+	}
+	if remote.Name == "" {
+		remote.Name = remote.Address
+	}
+	return remote, nil
+}
+
+// remoteSample is what a remote host reported in one cycle.
+type remoteSample struct {
+	CPU       [2]float64 // busy and total jiffies since boot
+	MemTotal  float64
+	MemAvail  float64
+	Load1     float64
+	Load5     float64
+	Load15    float64
+	Disks     map[string]remoteDisk
+	DiskOrder []string
+}
+
+type remoteDisk struct {
+	UsedPercent float64
+	FreeMB      float64
+}
+
+// poll runs remoteScript on the host with the system ssh client. Host keys
+// must be known, so a swapped host is an error rather than a silent
+// connection.
+func (r RemoteHost) poll(ctx context.Context, key, knownHosts string) (remoteSample, error) {
+	args := []string{
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "ConnectTimeout=10",
+		"-p", strconv.Itoa(r.Port),
+	}
+	if key != "" {
+		args = append(args, "-i", key, "-o", "IdentitiesOnly=yes")
+	}
+	if knownHosts != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+knownHosts)
+	}
+	// Nothing after "--" is taken for an option
+	args = append(args, "--", r.User+"@"+r.Address, remoteScript)
+
+	output, err := runCommand(ctx, "ssh", args...)
+	if err != nil {
+		return remoteSample{}, err
+	}
+	return parseRemoteSample(output)
+}
+
+func parseRemoteSample(output []byte) (remoteSample, error) {
+	sample := remoteSample{Disks: map[string]remoteDisk{}}
+	section := ""
+	found := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "@@") {
+			section = line[2:]
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch section {
+		case "stat":
+			// cpu user nice system idle iowait irq softirq steal
+			if fields[0] != "cpu" || len(fields) < 5 {
+				continue
+			}
+			var total, idle float64
+			for i, field := range fields[1:] {
+				if i >= 8 {
+					break
+				}
+				value, _ := strconv.ParseFloat(field, 64)
+				total += value
+				if i == 3 || i == 4 {
+					idle += value
+				}
+			}
+			sample.CPU = [2]float64{total - idle, total}
+			found[section] = true
+		case "meminfo":
+			if len(fields) < 2 {
+				continue
+			}
+			value, _ := strconv.ParseFloat(fields[1], 64)
+			switch fields[0] {
+			case "MemTotal:":
+				sample.MemTotal = value / 1024
+				found[section] = true
+			case "MemAvailable:":
+				sample.MemAvail = value / 1024
+			}
+		case "loadavg":
+			if len(fields) < 3 {
+				continue
+			}
+			sample.Load1, _ = strconv.ParseFloat(fields[0], 64)
+			sample.Load5, _ = strconv.ParseFloat(fields[1], 64)
+			sample.Load15, _ = strconv.ParseFloat(fields[2], 64)
+			found[section] = true
+		case "df":
+			// Filesystem 1024-blocks Used Available Capacity Mounted on
+			if len(fields) < 6 || fields[0] == "Filesystem" || pseudoFilesystems[fields[0]] {
+				continue
+			}
+			mount := strings.Join(fields[5:], " ")
+			if strings.HasPrefix(mount, "/proc") || strings.HasPrefix(mount, "/sys") || strings.HasPrefix(mount, "/dev") || strings.HasPrefix(mount, "/run") {
+				continue
+			}
+			used, _ := strconv.ParseFloat(fields[2], 64)
+			available, _ := strconv.ParseFloat(fields[3], 64)
+			if used+available == 0 {
+				continue
+			}
+			if _, ok := sample.Disks[mount]; !ok {
+				sample.DiskOrder = append(sample.DiskOrder, mount)
+			}
+			sample.Disks[mount] = remoteDisk{
+				UsedPercent: 100 * used / (used + available),
+				FreeMB:      available / 1024,
+			}
+		}
+	}
+	for _, section := range []string{"stat", "meminfo", "loadavg"} {
+		if !found[section] {
+			return remoteSample{}, fmt.Errorf("no %s in output, is it a Linux host?", section)
+		}
+	}
+	return sample, scanner.Err()
+}
+
+// checkRemoteHosts polls every remote host in parallel and alerts on CPU,
+// memory and disk usage against the same limits as this host. The alerts
+// carry the remote host as host label, so they route like those of a host
+// running the agent. CPU usage needs two samples, so it is alerted from the
+// second cycle on.
+func (s *SystemMonitor) checkRemoteHosts(ctx context.Context) error {
+	samples := make([]remoteSample, len(s.config.RemoteHosts))
+	errs := make([]error, len(s.config.RemoteHosts))
+	var wg sync.WaitGroup
+	for i, remote := range s.config.RemoteHosts {
+		wg.Add(1)
+		go func(i int, remote RemoteHost) {
+			defer wg.Done()
+			samples[i], errs[i] = remote.poll(ctx, s.config.SSHKey, s.config.SSHKnownHosts)
+		}(i, remote)
+	}
+	wg.Wait()
+
+	cpuLimit, cpuCriticalLimit := s.limit("cpu-limit"), s.limit("cpu-critical-limit")
+	memoryLimit, memoryCriticalLimit := s.limit("memory-limit"), s.limit("memory-critical-limit")
+	diskLimit, diskCriticalLimit := s.limit("disk-limit"), s.limit("disk-critical-limit")
+
+	previous := s.remoteCPU
+	s.remoteCPU = map[string][2]float64{}
+
+	for i, remote := range s.config.RemoteHosts {
+		labels := map[string]string{"host": remote.Name, "agent": s.hostname}
+		send := func(metric Metric) error {
+			metric.Labels = map[string]string{}
+			for name, value := range labels {
+				metric.Labels[name] = value
+			}
+			metric.Timestamp = time.Now().Unix()
+			return s.sendMetric(metric)
+		}
+
+		up := 1.0
+		status := "pass"
+		cause := fmt.Sprintf("Polled %s@%s over SSH", remote.User, remote.Address)
+		if errs[i] != nil {
+			up = 0
+			status = "fail"
+			cause = fmt.Sprintf("Failed to poll %s@%s over SSH: %v", remote.User, remote.Address, errs[i])
+			s.log.Warn("Remote host %s: %v", remote.Name, errs[i])
+		}
+		s.recordValue(valueName("remote", remote.Name, "up"), up)
+		if err := send(Metric{
+			Name:     "remote",
+			Title:    fmt.Sprintf("Remote Host - %s", remote.Name),
+			Cause:    cause,
+			AlertID:  fmt.Sprintf("remote-%s", remote.Name),
+			Status:   status,
+			Value:    up,
+			Limit:    1,
+			Unit:     UnitBoolean,
+			Type:     TypeGauge,
+			Severity: s.getSeverity(status, 0, 0),
+		}); err != nil {
+			return err
+		}
+		if errs[i] != nil {
+			continue
+		}
+		sample := samples[i]
+
+		s.recordValue(valueName("remote", remote.Name, "load", "1"), sample.Load1)
+		s.recordValue(valueName("remote", remote.Name, "load", "5"), sample.Load5)
+		s.recordValue(valueName("remote", remote.Name, "load", "15"), sample.Load15)
+
+		s.remoteCPU[remote.Name] = sample.CPU
+		if last, ok := previous[remote.Name]; ok && sample.CPU[1] > last[1] && sample.CPU[0] >= last[0] {
+			value := 100 * (sample.CPU[0] - last[0]) / (sample.CPU[1] - last[1])
+			s.recordValue(valueName("remote", remote.Name, "cpu", "percent"), value)
+			status := s.getStatus(value, cpuLimit)
+			if status == "fail" {
+				s.log.Warn("CPU usage of %s %.2f%% exceeds limit of %.2f%%", remote.Name, value, cpuLimit)
+			}
+			if err := send(Metric{
+				Name:     "cpu",
+				Title:    fmt.Sprintf("CPU Usage - %s", remote.Name),
+				Cause:    "CPU monitoring check over SSH",
+				AlertID:  fmt.Sprintf("cpu-%s", remote.Name),
+				Status:   status,
+				Value:    value,
+				Limit:    cpuLimit,
+				Unit:     UnitPercent,
+				Type:     TypeGauge,
+				Severity: s.getSeverity(status, value, cpuCriticalLimit),
+			}); err != nil {
+				return err
+			}
+		}
+
+		if sample.MemTotal > 0 {
+			value := 100 * (sample.MemTotal - sample.MemAvail) / sample.MemTotal
+			s.recordValue(valueName("remote", remote.Name, "mem", "used_percent"), value)
+			s.recordValue(valueName("remote", remote.Name, "mem", "available_mb"), sample.MemAvail)
+			status := s.getStatus(value, memoryLimit)
+			if status == "fail" {
+				s.log.Warn("Memory usage of %s %.2f%% exceeds limit of %.2f%%", remote.Name, value, memoryLimit)
+			}
+			if err := send(Metric{
+				Name:     "memory",
+				Title:    fmt.Sprintf("Memory Usage - %s", remote.Name),
+				Cause:    fmt.Sprintf("Memory monitoring check over SSH, Available: %.0f MB", sample.MemAvail),
+				AlertID:  fmt.Sprintf("memory-%s", remote.Name),
+				Status:   status,
+				Value:    value,
+				Limit:    memoryLimit,
+				Unit:     UnitPercent,
+				Type:     TypeGauge,
+				Severity: s.getSeverity(status, value, memoryCriticalLimit),
+			}); err != nil {
+				return err
+			}
+		}
+
+		for _, mount := range sample.DiskOrder {
+			disk := sample.Disks[mount]
+			name := valueName("remote", remote.Name, "disk", "used_percent")
+			if mount != "/" {
+				name = valueName("remote", remote.Name, "disk", mount, "used_percent")
+			}
+			s.recordValue(name, disk.UsedPercent)
+			status := s.getStatus(disk.UsedPercent, diskLimit)
+			if status == "fail" {
+				s.log.Warn("Disk usage of %s for %s %.2f%% exceeds limit of %.2f%%, Free: %.0f MB", remote.Name, mount, disk.UsedPercent, diskLimit, disk.FreeMB)
+			}
+			id, title := "root", fmt.Sprintf("Root Disk Usage - %s", remote.Name)
+			if mount != "/" {
+				id, title = valueName(mount), fmt.Sprintf("Disk Usage %s - %s", mount, remote.Name)
+			}
+			if err := send(Metric{
+				Name:     "disk",
+				Title:    title,
+				Cause:    fmt.Sprintf("Disk monitoring check over SSH, Free: %.0f MB", disk.FreeMB),
+				AlertID:  fmt.Sprintf("disk-%s-%s", id, remote.Name),
+				Status:   status,
+				Value:    disk.UsedPercent,
+				Limit:    diskLimit,
+				Unit:     UnitPercent,
+				Type:     TypeGauge,
+				Severity: s.getSeverity(status, disk.UsedPercent, diskCriticalLimit),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRemoteConfigSize bounds remote config downloads.
+const maxRemoteConfigSize = 1024 * 1024
+
+// configCheckEnv names the file with the config a dry run of the agent
+// validates, see checkRemoteConfig.
+const configCheckEnv = "MONITORING_CONFIG_CHECK"
+
+// localOnlyFlags can't be set by a remote config: they decide where the
+// config comes from, which releases are trusted, how the agent is
+// sandboxed and who can reach it.
+var localOnlyFlags = map[string]bool{
+	"config-url":           true,
+	"config-cache":         true,
+	"config-poll-interval": true,
+	"release-public-key":   true,
+	"release-url":          true,
+	"self-update-interval": true,
+	"harden":               true,
+	"seccomp":              true,
+	"ssh-host":             true,
+	"ssh-key":              true,
+	"listen":               true,
+	"api-token":            true,
+}
+
+// remoteConfigCache is the last remote config fetched successfully, used
+// when the URL can't be reached.
+type remoteConfigCache struct {
+	URL    string          `json:"url"`
+	ETag   string          `json:"etag"`
+	Config json.RawMessage `json:"config"`
+}
+
+// fetchRemoteConfig requests the config at rawURL. With an ETag, an
+// unchanged config returns modified false and no data.
+func fetchRemoteConfig(client *http.Client, rawURL, etag string) ([]byte, string, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+	req.Header.Set("Accept", "application/json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to fetch config: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", false, fmt.Errorf("unexpected status from %s: %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to read config: %v", err)
+	}
+	if len(data) > maxRemoteConfigSize {
+		return nil, "", false, fmt.Errorf("config is larger than %d bytes", maxRemoteConfigSize)
+	}
+	if err := validateRemoteConfig(flag.CommandLine, data); err != nil {
+		return nil, "", false, err
+	}
+
+	// Compacted, so the config compares equal to its cached copy
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, "", false, fmt.Errorf("failed to parse config: %v", err)
+	}
+	return compact.Bytes(), resp.Header.Get("ETag"), true, nil
+}
+
+// parseRemoteConfig decodes a config object of flag names to values, e.g.
+// {"cpu-limit": 80, "http-check": ["api=https://example.com/v1/health"]},
+// into the values to pass to flag.Set.
+func parseRemoteConfig(data []byte) (map[string][]string, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+
+	values := map[string][]string{}
+	for name, value := range raw {
+		items, isList := value.([]interface{})
+		if !isList {
+			items = []interface{}{value}
+		}
+		for _, item := range items {
+			switch v := item.(type) {
+			case string:
+				values[name] = append(values[name], v)
+			case float64:
+				values[name] = append(values[name], strconv.FormatFloat(v, 'f', -1, 64))
+			case bool:
+				values[name] = append(values[name], strconv.FormatBool(v))
+			default:
+				return nil, fmt.Errorf("invalid value for %s, expected a string, number, boolean or a list of them", name)
+			}
+		}
+	}
+	return values, nil
+}
+
+// validateRemoteConfig checks that every key of the config is a flag that
+// may be set remotely.
+func validateRemoteConfig(fs *flag.FlagSet, data []byte) error {
+	values, err := parseRemoteConfig(data)
+	if err != nil {
+		return err
+	}
+	for name := range values {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown setting %q", name)
+		}
+		if localOnlyFlags[name] {
+			return fmt.Errorf("%s can only be set on the command line", name)
+		}
+	}
+	return nil
+}
+
+// applyRemoteConfig sets the flags of the config. Flags given on the
+// command line take precedence, so a single host can still override the
+// fleet's settings.
+func applyRemoteConfig(fs *flag.FlagSet, data []byte) error {
+	if err := validateRemoteConfig(fs, data); err != nil {
+		return err
+	}
+	values, err := parseRemoteConfig(data)
+	if err != nil {
+		return err
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if explicit[name] {
+			continue
+		}
+		for _, value := range values[name] {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid value %q for %s: %v", value, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkRemoteConfig validates a fetched config before it is cached. The
+// agent is started again with the same arguments in a dry run, which
+// parses the config into fresh flags, runs every check of startup and
+// exits before monitoring. A value startup would reject, e.g.
+// "cpu-limit": 200, is refused here instead of crash-looping every host
+// restarting into it.
+func checkRemoteConfig(data []byte) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "monitoring-config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), configCheckEnv+"="+file.Name())
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if _, message, ok := strings.Cut(line, "[FATAL] "); ok {
+			return fmt.Errorf("%s", strings.TrimSuffix(message, colorReset))
+		}
+	}
+	return fmt.Errorf("config check failed: %v", err)
+}
+
+func readRemoteConfigCache(path, rawURL string) (remoteConfigCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return remoteConfigCache{}, err
+	}
+	var cache remoteConfigCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return remoteConfigCache{}, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if cache.URL != rawURL {
+		return remoteConfigCache{}, fmt.Errorf("%s was fetched from %s", path, cache.URL)
+	}
+	return cache, nil
+}
+
+func writeRemoteConfigCache(path string, cache remoteConfigCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// loadRemoteConfig fetches the config at rawURL and caches it at
+// cachePath. When the URL can't be reached or returns an invalid config,
+// the cached config, the last one that passed checkRemoteConfig, is used
+// instead.
+func loadRemoteConfig(rawURL, cachePath string, log *Logger) (remoteConfigCache, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	cache, cacheErr := readRemoteConfigCache(cachePath, rawURL)
+
+	data, etag, modified, err := fetchRemoteConfig(client, rawURL, cache.ETag)
+	if err == nil && modified {
+		if err = checkRemoteConfig(data); err == nil {
+			cache = remoteConfigCache{URL: rawURL, ETag: etag, Config: data}
+			if err := writeRemoteConfigCache(cachePath, cache); err != nil {
+				log.Warn("Failed to cache remote config at %s: %v", cachePath, err)
+			}
+			return cache, nil
+		}
+		err = fmt.Errorf("invalid config: %v", err)
+	}
+	switch {
+	case err == nil && cacheErr == nil:
+		return cache, nil
+	case err == nil:
+		err = fmt.Errorf("config not modified, but no cached copy: %v", cacheErr)
+	}
+
+	if cacheErr != nil {
+		return remoteConfigCache{}, fmt.Errorf("%v, and no cached config: %v", err, cacheErr)
+	}
+	log.Warn("Failed to fetch remote config, using the cached copy: %v", err)
+	return cache, nil
+}
+
+// watchRemoteConfig polls the config URL every interval and restarts the
+// agent once the config changed, so the new settings are applied the same
+// way as on startup. Servers without ETag support return the config every
+// time, so it is compared with the one in use as well. A config that
+// fails checkRemoteConfig is logged and the one in use is kept.
+func watchRemoteConfig(interval time.Duration, rawURL, cachePath string, current remoteConfigCache, log *Logger) {
+	etag := current.ETag
+	client := &http.Client{Timeout: 30 * time.Second}
+	for range time.Tick(interval) {
+		data, newETag, modified, err := fetchRemoteConfig(client, rawURL, etag)
+		if err != nil {
+			log.Error("Failed to poll remote config: %v", err)
+			continue
+		}
+		if !modified || bytes.Equal(data, current.Config) {
+			continue
+		}
+		if err := checkRemoteConfig(data); err != nil {
+			log.Error("Rejected remote config, keeping the one in use: %v", err)
+			// Not checked again until it changes
+			etag = newETag
+			continue
+		}
+		current = remoteConfigCache{URL: rawURL, ETag: newETag, Config: data}
+		if err := writeRemoteConfigCache(cachePath, current); err != nil {
+			log.Error("Failed to cache remote config at %s: %v", cachePath, err)
+			continue
+		}
+
+		log.Info("Remote config changed, restarting to apply it")
+		if err := restartSelf(); err != nil {
+			log.Error("Failed to restart after config change: %v", err)
+		}
+		etag = newETag
+	}
+}
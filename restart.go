@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// replaceExecutable renames the update over the running executable.
+func replaceExecutable(update, executable string) error {
+	return os.Rename(update, executable)
+}
+
+// restartSelf replaces the running process with the updated executable,
+// keeping the PID and arguments, so supervisors don't notice.
+func restartSelf() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(executable, os.Args, os.Environ())
+}
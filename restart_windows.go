@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// replaceExecutable moves the running executable aside, as Windows
+// doesn't allow replacing it but allows renaming it, and renames the
+// update in its place. The previous executable is removed by the next
+// update, once it no longer runs.
+func replaceExecutable(update, executable string) error {
+	previous := executable + ".old"
+	os.Remove(previous)
+	if err := os.Rename(executable, previous); err != nil {
+		return err
+	}
+	if err := os.Rename(update, executable); err != nil {
+		os.Rename(previous, executable)
+		return err
+	}
+	return nil
+}
+
+// restartSelf can't replace the running process on Windows, the service
+// manager has to restart the agent.
+func restartSelf() error {
+	return fmt.Errorf("restarting in place is not supported on Windows, restart the service")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Route sends metrics matching all of its matchers to the listed sinks.
+// Matcher keys are "name", "status", "severity" or a label name; values
+// may contain glob patterns and alternatives separated by "|".
+type Route struct {
+	matchers map[string][]string
+	sinks    map[string]bool
+}
+
+type Routes []Route
+
+// ParseRoute parses a rule of the form "<matchers>:<sinks>", for example
+// "name=disk,severity=warning:mattermost" or "*:betterstack,ntfy".
+func ParseRoute(value string, sinks []Sink) (Route, error) {
+	separator := strings.LastIndex(value, ":")
+	if separator < 0 {
+		return Route{}, fmt.Errorf("expected <matchers>:<sinks>")
+	}
+
+	route := Route{
+		matchers: map[string][]string{},
+		sinks:    map[string]bool{},
+	}
+
+	matchers := strings.TrimSpace(value[:separator])
+	if matchers != "*" && matchers != "" {
+		for _, matcher := range strings.Split(matchers, ",") {
+			key, values, ok := strings.Cut(matcher, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return Route{}, fmt.Errorf("invalid matcher %q, expected key=value", matcher)
+			}
+			for _, pattern := range strings.Split(values, "|") {
+				pattern = strings.TrimSpace(pattern)
+				if _, err := path.Match(pattern, ""); err != nil {
+					return Route{}, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+				}
+				route.matchers[key] = append(route.matchers[key], pattern)
+			}
+		}
+	}
+
+	known := map[string]bool{}
+	for _, sink := range sinks {
+		known[sink.Name()] = true
+	}
+	for _, name := range strings.Split(value[separator+1:], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return Route{}, fmt.Errorf("unknown sink %q", name)
+		}
+		route.sinks[name] = true
+	}
+	if len(route.sinks) == 0 {
+		return Route{}, fmt.Errorf("no sinks given")
+	}
+
+	return route, nil
+}
+
+func (r Route) Matches(metric Metric) bool {
+	for key, patterns := range r.matchers {
+		var value string
+		switch key {
+		case "name":
+			value = metric.Name
+		case "status":
+			value = metric.Status
+		case "severity":
+			// Recoveries carry no failure severity. Let them through so
+			// every sink that could have received the failure also gets
+			// the recovery.
+			if metric.Status != "fail" {
+				continue
+			}
+			value = metric.Severity
+		default:
+			value = metric.Labels[key]
+		}
+
+		matched := false
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, value); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Sinks returns the sinks a metric is routed to. Without routes every
+// metric goes to every sink.
+func (r Routes) Sinks(metric Metric, sinks []Sink) []Sink {
+	if len(r) == 0 {
+		return sinks
+	}
+
+	selected := map[string]bool{}
+	for _, route := range r {
+		if route.Matches(metric) {
+			for name := range route.sinks {
+				selected[name] = true
+			}
+		}
+	}
+
+	var routed []Sink
+	for _, sink := range sinks {
+		if selected[sink.Name()] {
+			routed = append(routed, sink)
+		}
+	}
+	return routed
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var valueNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// Rule raises an alert while its expression evaluates to true.
+type Rule struct {
+	Name       string
+	Expression *Expression
+}
+
+// ParseRule parses a rule of the form "<name>:<expression>", for example
+// "memory-pressure:mem.available_mb < 512 && swap.used_percent > 50".
+func ParseRule(value string) (Rule, error) {
+	name, source, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return Rule{}, fmt.Errorf("expected <name>:<expression>")
+	}
+
+	expression, err := ParseExpression(source)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	return Rule{Name: name, Expression: expression}, nil
+}
+
+// valueName builds the name under which a collected value is available
+// to rule expressions, e.g. valueName("disk", "/mnt/data", "used_percent")
+// returns "disk.mnt_data.used_percent".
+func valueName(parts ...string) string {
+	for i, part := range parts {
+		parts[i] = strings.Trim(valueNameSanitizer.ReplaceAllString(part, "_"), "_")
+	}
+	return strings.Join(parts, ".")
+}
+
+// valueUnit returns the unit of a collected value from its name, e.g.
+// UnitPercent for "disk.data.used_percent", or UnitCount when the name
+// doesn't tell.
+func valueUnit(name string) string {
+	parts := strings.Split(name, ".")
+	last := parts[len(parts)-1]
+	if last == "max" && len(parts) > 1 {
+		last = parts[len(parts)-2]
+	}
+	switch {
+	case strings.Contains(last, "percent"):
+		return UnitPercent
+	case last == "mb" || strings.HasSuffix(last, "_mb"):
+		return UnitMB
+	case strings.HasSuffix(last, "_ms"):
+		return UnitMS
+	case strings.HasSuffix(last, "_seconds"):
+		return UnitSeconds
+	case last == "hours" || strings.HasSuffix(last, "_hours"):
+		return UnitHours
+	case last == "days_left" || strings.HasSuffix(last, "_days"):
+		return UnitDays
+	case strings.HasSuffix(last, "_mbps"):
+		return UnitMbps
+	case strings.HasSuffix(last, "per_second"):
+		return UnitPerSecond
+	case strings.HasSuffix(last, "per_minute"):
+		return UnitPerMinute
+	case last == "temperature" || parts[0] == "temperature":
+		return UnitCelsius
+	}
+	return UnitCount
+}
+
+func (s *SystemMonitor) recordValue(name string, value float64) {
+	s.valuesMu.Lock()
+	defer s.valuesMu.Unlock()
+
+	s.values[name] = value
+}
+
+func (s *SystemMonitor) collectedValues() map[string]float64 {
+	s.valuesMu.Lock()
+	defer s.valuesMu.Unlock()
+
+	values := make(map[string]float64, len(s.values))
+	for name, value := range s.values {
+		values[name] = value
+	}
+	return values
+}
+
+// recordStatus counts the failing alerts of each check in this cycle,
+// available to rules as failing.<name>, e.g. failing.disk. Rules can then
+// combine the configured limits of several checks.
+func (s *SystemMonitor) recordStatus(metric Metric) {
+	s.valuesMu.Lock()
+	defer s.valuesMu.Unlock()
+
+	if s.cycleFailing == nil {
+		s.cycleFailing = map[string]int{}
+	}
+	name := valueName("failing", metric.Name)
+	if metric.Status == "fail" {
+		s.cycleFailing[name]++
+	}
+	s.values[name] = float64(s.cycleFailing[name])
+}
+
+func (s *SystemMonitor) resetStatus() {
+	s.valuesMu.Lock()
+	defer s.valuesMu.Unlock()
+
+	s.cycleFailing = map[string]int{}
+}
+
+func (s *SystemMonitor) checkRules(ctx context.Context) error {
+	values := s.collectedValues()
+
+	for _, rule := range s.config.Rules {
+		result, err := rule.Expression.Evaluate(values)
+		if err != nil {
+			names := make([]string, 0, len(values))
+			for name := range values {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			s.log.Error("Failed to evaluate rule %s: %v (available values: %s)", rule.Name, err, strings.Join(names, ", "))
+			continue
+		}
+
+		status := "pass"
+		if result != 0 {
+			status = "fail"
+			s.log.Warn("Rule %s matched: %s", rule.Name, rule.Expression)
+		} else {
+			s.log.Log("Rule %s: not matched", rule.Name)
+		}
+
+		severity := SeverityInfo
+		if status == "fail" {
+			severity = SeverityCritical
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "rule",
+			Title:     fmt.Sprintf("Rule %s - %s", rule.Name, s.hostname),
+			Cause:     rule.Expression.String(),
+			AlertID:   fmt.Sprintf("rule-%s-%s", rule.Name, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     result,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  severity,
+			Labels:    map[string]string{"rule": rule.Name},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// The Alpine image has no zoneinfo for --timezone
+	_ "time/tzdata"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ThresholdSchedule overrides a limit on some days or during a daily
+// window, e.g. a higher CPU limit during the nightly backup.
+type ThresholdSchedule struct {
+	Limit  string
+	Value  float64
+	Days   map[time.Weekday]bool
+	Window *clockWindow
+}
+
+// scheduleLimit returns the setting a schedule can override, or nil for
+// unknown names.
+func scheduleLimit(config *Config, name string) *float64 {
+	switch name {
+	case "cpu-limit":
+		return &config.CPULimit
+	case "memory-limit":
+		return &config.MemoryLimit
+	case "disk-limit":
+		return &config.DiskLimit
+	case "cpu-critical-limit":
+		return &config.CPUCriticalLimit
+	case "memory-critical-limit":
+		return &config.MemoryCriticalLimit
+	case "disk-critical-limit":
+		return &config.DiskCriticalLimit
+	}
+	return nil
+}
+
+// ParseThresholdSchedule parses "<limit>=<value>@[<days>] [<start>-<end>]"
+// where days are a comma separated list of weekdays or ranges, for example
+// "cpu-limit=95@02:00-04:00", "disk-limit=90@sat,sun" or
+// "cpu-limit=95@mon-fri 22:00-06:00".
+func ParseThresholdSchedule(value string) (ThresholdSchedule, error) {
+	setting, when, ok := strings.Cut(value, "@")
+	name, number, found := strings.Cut(setting, "=")
+	if !ok || !found {
+		return ThresholdSchedule{}, fmt.Errorf("expected <limit>=<value>@[<days>] [<start>-<end>]")
+	}
+
+	schedule := ThresholdSchedule{Limit: strings.TrimSpace(name)}
+	if scheduleLimit(&Config{}, schedule.Limit) == nil {
+		return ThresholdSchedule{}, fmt.Errorf("unknown limit %q, expected cpu-limit, memory-limit, disk-limit or their -critical-limit", schedule.Limit)
+	}
+	limit, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || limit < 0 || limit > 100 {
+		return ThresholdSchedule{}, fmt.Errorf("invalid value %q, expected a percentage between 0 and 100", number)
+	}
+	schedule.Value = limit
+
+	for _, part := range strings.Fields(when) {
+		if strings.Contains(part, ":") {
+			if schedule.Window != nil {
+				return ThresholdSchedule{}, fmt.Errorf("more than one time window")
+			}
+			window, err := parseClockWindow(part)
+			if err != nil {
+				return ThresholdSchedule{}, err
+			}
+			schedule.Window = &window
+			continue
+		}
+		if schedule.Days != nil {
+			return ThresholdSchedule{}, fmt.Errorf("more than one list of days")
+		}
+		if schedule.Days, err = parseWeekdays(part); err != nil {
+			return ThresholdSchedule{}, err
+		}
+	}
+	if schedule.Days == nil && schedule.Window == nil {
+		return ThresholdSchedule{}, fmt.Errorf("expected days, a time window or both after @")
+	}
+	return schedule, nil
+}
+
+// parseWeekdays parses weekdays and ranges, e.g. "mon-fri,sun".
+func parseWeekdays(value string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, ok := weekdays[first]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q, expected mon, tue, wed, thu, fri, sat or sun", first)
+		}
+		to := from
+		if isRange {
+			if to, ok = weekdays[last]; !ok {
+				return nil, fmt.Errorf("invalid day %q, expected mon, tue, wed, thu, fri, sat or sun", last)
+			}
+		}
+		for day := from; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// Active returns whether the schedule applies at the given local time.
+// The days of a window wrapping around midnight are the days it starts on.
+func (t ThresholdSchedule) Active(at time.Time) bool {
+	day := at.Weekday()
+	if t.Window != nil && t.Window.Start > t.Window.End && at.Hour()*60+at.Minute() < t.Window.End {
+		day = (day + 6) % 7
+	}
+	if t.Days != nil && !t.Days[day] {
+		return false
+	}
+	return t.Window == nil || t.Window.contains(at)
+}
+
+// now returns the current time in the configured time zone, which quiet
+// hours, schedules and digests are in.
+func (s *SystemMonitor) now() time.Time {
+	if s.config.Location != nil {
+		return time.Now().In(s.config.Location)
+	}
+	return time.Now()
+}
+
+// applySchedules sets the limits with schedules to the value of the last
+// active schedule, or back to the configured value when none is active.
+func (s *SystemMonitor) applySchedules(at time.Time) {
+	if len(s.config.ThresholdSchedules) == 0 {
+		return
+	}
+	if s.baseLimits == nil {
+		s.baseLimits = map[string]float64{}
+		for _, schedule := range s.config.ThresholdSchedules {
+			s.baseLimits[schedule.Limit] = *scheduleLimit(&s.config, schedule.Limit)
+		}
+	}
+
+	values := map[string]float64{}
+	for name, value := range s.baseLimits {
+		values[name] = value
+	}
+	for _, schedule := range s.config.ThresholdSchedules {
+		if schedule.Active(at) {
+			values[schedule.Limit] = schedule.Value
+		}
+	}
+
+	s.limitsMu.Lock()
+	defer s.limitsMu.Unlock()
+	for name, value := range values {
+		limit := scheduleLimit(&s.config, name)
+		if *limit == value {
+			continue
+		}
+		if value == s.baseLimits[name] {
+			s.log.Info("Schedule ended, %s is back to %.1f", name, value)
+		} else {
+			s.log.Info("Schedule started, %s is %.1f", name, value)
+		}
+		*limit = value
+	}
+}
+
+// limit returns the current value of a limit schedules can override.
+// Checks that missed their deadline may still read it while the next
+// cycle applies the schedules.
+func (s *SystemMonitor) limit(name string) float64 {
+	s.limitsMu.RLock()
+	defer s.limitsMu.RUnlock()
+	return *scheduleLimit(&s.config, name)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// releasePublicKey is the base64 ed25519 key release checksums are signed
+// with, set at build time with -ldflags "-X main.releasePublicKey=...".
+var releasePublicKey = ""
+
+const defaultReleaseURL = "https://github.com/appwrite/monitoring/releases/latest/download"
+
+// maxBinarySize bounds release downloads.
+const maxBinarySize = 256 * 1024 * 1024
+
+// releaseAsset is the binary name for this platform, e.g.
+// "monitoring-linux-amd64".
+func releaseAsset() string {
+	asset := fmt.Sprintf("monitoring-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		asset += ".exe"
+	}
+	return asset
+}
+
+func download(client *http.Client, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", rawURL, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, limit)
+	}
+	return data, nil
+}
+
+// parseVersion parses "v1.2.3" or "1.2.3-rc.1" into its numbers and
+// pre-release suffix.
+func parseVersion(value string) ([]int, string, error) {
+	core, pre, _ := strings.Cut(strings.TrimPrefix(value, "v"), "-")
+	var numbers []int
+	for _, part := range strings.Split(core, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, "", fmt.Errorf("invalid version %q", value)
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, pre, nil
+}
+
+// compareVersions returns -1, 0 or 1 when a is older than, the same as or
+// newer than b. A pre-release is older than its release, and "dev"
+// builds are older than any release.
+func compareVersions(a, b string) (int, error) {
+	if a == "dev" || b == "dev" {
+		switch {
+		case a == b:
+			return 0, nil
+		case a == "dev":
+			return -1, nil
+		}
+		return 1, nil
+	}
+	numbersA, preA, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	numbersB, preB, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(numbersA) || i < len(numbersB); i++ {
+		var x, y int
+		if i < len(numbersA) {
+			x = numbersA[i]
+		}
+		if i < len(numbersB) {
+			y = numbersB[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0, nil
+	case preA == "":
+		return 1, nil
+	case preB == "", preA < preB:
+		return -1, nil
+	}
+	return 1, nil
+}
+
+// releaseManifest downloads SHA256SUMS and its detached ed25519 signature
+// SHA256SUMS.sig, verifies the signature and returns the release version
+// and the checksum of asset. Binaries are only trusted through this
+// signed list, and the version signed with it keeps an older release
+// served at the release URL from being installed.
+func releaseManifest(client *http.Client, baseURL, publicKey, asset string) (string, string, error) {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return "", "", fmt.Errorf("invalid public key, expected %d base64 encoded bytes", ed25519.PublicKeySize)
+	}
+
+	sums, err := download(client, baseURL+"/SHA256SUMS", 64*1024)
+	if err != nil {
+		return "", "", err
+	}
+	signature, err := download(client, baseURL+"/SHA256SUMS.sig", 1024)
+	if err != nil {
+		return "", "", err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), sums, signature) {
+		return "", "", fmt.Errorf("signature of SHA256SUMS does not match the public key")
+	}
+
+	// Lines are "version <version>" and "<sha256>  <file>", as written by
+	// sha256sum
+	var release, checksum string
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if fields[0] == "version" {
+			release = fields[1]
+		} else if strings.TrimPrefix(fields[1], "*") == asset {
+			checksum = strings.ToLower(fields[0])
+		}
+	}
+	if release == "" {
+		return "", "", fmt.Errorf("no version in SHA256SUMS")
+	}
+	if checksum == "" {
+		return "", "", fmt.Errorf("no checksum for %s in SHA256SUMS", asset)
+	}
+	return release, checksum, nil
+}
+
+// selfUpdate replaces the running executable with the latest release if
+// it is newer than the running version. The new binary is written next to the old one and
+// renamed over it, so the executable is never half written. It returns
+// whether an update was installed.
+func selfUpdate(baseURL, publicKey string, checkOnly bool, log *Logger) (bool, error) {
+	if publicKey == "" {
+		return false, fmt.Errorf("no release public key, pass --release-public-key")
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	executable, err := os.Executable()
+	if err != nil {
+		return false, fmt.Errorf("failed to locate executable: %v", err)
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return false, fmt.Errorf("failed to locate executable: %v", err)
+	}
+
+	asset := releaseAsset()
+	release, expected, err := releaseManifest(client, baseURL, publicKey, asset)
+	if err != nil {
+		return false, err
+	}
+	newer, err := compareVersions(release, version)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case newer == 0:
+		log.Info("Already running the latest release (%s)", version)
+		return false, nil
+	case newer < 0:
+		return false, fmt.Errorf("release %s at %s is older than the running %s, refusing to downgrade", release, baseURL, version)
+	}
+	if checkOnly {
+		log.Info("Release %s is available for %s (running %s)", release, asset, version)
+		return false, nil
+	}
+
+	binary, err := download(client, baseURL+"/"+asset, maxBinarySize)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(binary)
+	if hex.EncodeToString(sum[:]) != expected {
+		return false, fmt.Errorf("checksum of %s does not match SHA256SUMS", asset)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(executable), ".monitoring-update-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create update file: %v", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(binary); err != nil {
+		temp.Close()
+		return false, fmt.Errorf("failed to write update: %v", err)
+	}
+	if err := temp.Close(); err != nil {
+		return false, fmt.Errorf("failed to write update: %v", err)
+	}
+	if err := os.Chmod(temp.Name(), 0o755); err != nil {
+		return false, fmt.Errorf("failed to make update executable: %v", err)
+	}
+	if err := replaceExecutable(temp.Name(), executable); err != nil {
+		return false, fmt.Errorf("failed to replace %s: %v", executable, err)
+	}
+
+	log.Success("Updated %s from %s to %s", executable, version, release)
+	return true, nil
+}
+
+// autoUpdate checks for a new release every interval and restarts into
+// it once installed.
+func autoUpdate(interval time.Duration, baseURL, publicKey string, log *Logger) {
+	for range time.Tick(interval) {
+		updated, err := selfUpdate(baseURL, publicKey, false, log)
+		if err != nil {
+			log.Error("Auto-update failed: %v", err)
+			continue
+		}
+		if updated {
+			log.Info("Restarting into the updated release")
+			if err := restartSelf(); err != nil {
+				log.Error("Failed to restart after update: %v", err)
+			}
+		}
+	}
+}
+
+func runSelfUpdateCommand(args []string) {
+	log := New()
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	releaseURL := fs.String("release-url", defaultReleaseURL, "URL the release binaries, SHA256SUMS and SHA256SUMS.sig are downloaded from")
+	publicKey := fs.String("release-public-key", releasePublicKey, "Base64 ed25519 public key SHA256SUMS is signed with (default: built in)")
+	check := fs.Bool("check", false, "Only check whether an update is available")
+	fs.Parse(args)
+
+	if _, err := selfUpdate(*releaseURL, *publicKey, *check, log); err != nil {
+		log.Fatal("Self-update failed: %v", err)
+	}
+}
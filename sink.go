@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sink delivers metrics to an alerting or notification backend.
+type Sink interface {
+	Name() string
+	Send(metric Metric) error
+}
+
+// filteredSink is a sink that only delivers some metrics, e.g. only
+// critical failures. Metrics it doesn't accept are not sent to it, so
+// they are neither counted as delivered nor dead-lettered.
+type filteredSink interface {
+	Accepts(metric Metric) bool
+}
+
+// accepts reports whether the sink delivers the metric.
+func accepts(sink Sink, metric Metric) bool {
+	filtered, ok := sink.(filteredSink)
+	return !ok || filtered.Accepts(metric)
+}
+
+// notifiable reports whether a notification sink sends the metric:
+// failures, their resolutions and digests. Passing checks would notify
+// every cycle.
+func notifiable(metric Metric) bool {
+	return metric.Status == "fail" || metric.Resolved || metric.Name == "digest"
+}
+
+// formatMetricText renders a metric as a short human readable line for
+// sinks that deliver plain text (SMS, email, chat).
+func formatMetricText(metric Metric) string {
+	return fmt.Sprintf("[%s] %s: %s (limit: %s) - %s",
+		strings.ToUpper(metric.Status),
+		metric.Title,
+		formatValue(metric.Value, metric.Unit),
+		formatValue(metric.Limit, metric.Unit),
+		metric.Cause)
+}
+
+// unitSuffixes are appended to values of the unit in notifications.
+// Counts, ratios and booleans are shown as plain numbers.
+var unitSuffixes = map[string]string{
+	UnitPercent:   "%",
+	UnitMB:        " MB",
+	UnitMS:        " ms",
+	UnitSeconds:   " s",
+	UnitMinutes:   " min",
+	UnitHours:     " h",
+	UnitDays:      " days",
+	UnitPerSecond: "/s",
+	UnitPerMinute: "/min",
+	UnitCelsius:   "°C",
+	UnitMbps:      " Mbit/s",
+	UnitBytes:     " bytes",
+}
+
+// formatValue renders a value with its unit, e.g. "91.50%" or "230.00 ms".
+func formatValue(value float64, unit string) string {
+	if unit == UnitBoolean {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%.2f%s", value, unitSuffixes[unit])
+}
+
+// statusColor returns the hex color chat sinks use to highlight a metric.
+func statusColor(metric Metric) string {
+	if metric.Status != "fail" {
+		return "#2eb67d"
+	}
+	if metric.Severity == SeverityCritical {
+		return "#e01e5a"
+	}
+	return "#ecb22e"
+}
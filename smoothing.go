@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Smoothing evaluates the thresholds of a check against a smoothed value
+// instead of the latest sample, so short spikes don't flap alerts. Window
+// is the number of cycles for sma and median, Alpha the weight of the
+// latest sample for ema.
+type Smoothing struct {
+	Check  string
+	Method string
+	Window int
+	Alpha  float64
+}
+
+// smoothingChecks are the checks whose values can be smoothed.
+var smoothingChecks = []string{"cpu", "memory", "disk"}
+
+// ParseSmoothing parses "<check>=sma:<cycles>", "<check>=median:<cycles>"
+// or "<check>=ema:<alpha>", e.g. "cpu=median:5" or "memory=ema:0.3".
+func ParseSmoothing(value string) (Smoothing, error) {
+	check, setting, ok := strings.Cut(value, "=")
+	method, parameter, found := strings.Cut(setting, ":")
+	if !ok || !found {
+		return Smoothing{}, fmt.Errorf("expected <check>=sma:<cycles>, <check>=median:<cycles> or <check>=ema:<alpha>")
+	}
+
+	smoothing := Smoothing{Check: strings.TrimSpace(check), Method: strings.TrimSpace(method)}
+	known := false
+	for _, name := range smoothingChecks {
+		known = known || name == smoothing.Check
+	}
+	if !known {
+		return Smoothing{}, fmt.Errorf("unknown check %q, expected %s", smoothing.Check, strings.Join(smoothingChecks, ", "))
+	}
+
+	parameter = strings.TrimSpace(parameter)
+	switch smoothing.Method {
+	case "sma", "median":
+		window, err := strconv.Atoi(parameter)
+		if err != nil || window < 2 {
+			return Smoothing{}, fmt.Errorf("invalid window %q, expected at least 2 cycles", parameter)
+		}
+		smoothing.Window = window
+	case "ema":
+		alpha, err := strconv.ParseFloat(parameter, 64)
+		if err != nil || alpha <= 0 || alpha > 1 {
+			return Smoothing{}, fmt.Errorf("invalid alpha %q, expected a number greater than 0 and at most 1", parameter)
+		}
+		smoothing.Alpha = alpha
+	default:
+		return Smoothing{}, fmt.Errorf("unknown method %q, expected sma, median or ema", smoothing.Method)
+	}
+	return smoothing, nil
+}
+
+func (m Smoothing) String() string {
+	if m.Method == "ema" {
+		return fmt.Sprintf("%s=ema:%g", m.Check, m.Alpha)
+	}
+	return fmt.Sprintf("%s=%s:%d", m.Check, m.Method, m.Window)
+}
+
+// smoother keeps the recent samples of one alert.
+type smoother struct {
+	samples []float64
+	average float64
+}
+
+// Add adds a sample and returns the smoothed value. Until the window is
+// full, the available samples are used.
+func (m *smoother) Add(smoothing Smoothing, value float64) float64 {
+	if smoothing.Method == "ema" {
+		if len(m.samples) == 0 {
+			m.samples = append(m.samples, value)
+			m.average = value
+		} else {
+			m.average = smoothing.Alpha*value + (1-smoothing.Alpha)*m.average
+		}
+		return m.average
+	}
+
+	m.samples = append(m.samples, value)
+	if len(m.samples) > smoothing.Window {
+		m.samples = m.samples[len(m.samples)-smoothing.Window:]
+	}
+	if smoothing.Method == "median" {
+		sorted := append([]float64(nil), m.samples...)
+		sort.Float64s(sorted)
+		middle := len(sorted) / 2
+		if len(sorted)%2 == 0 {
+			return (sorted[middle-1] + sorted[middle]) / 2
+		}
+		return sorted[middle]
+	}
+	sum := 0.0
+	for _, sample := range m.samples {
+		sum += sample
+	}
+	return sum / float64(len(m.samples))
+}
+
+// smooth returns the value the thresholds of an alert are evaluated
+// against, along with the raw sample for the payload. Without smoothing
+// for the check, the value is returned as is and the raw value is nil.
+func (s *SystemMonitor) smooth(check, alertID string, value float64) (float64, *float64) {
+	smoothing, ok := s.config.Smoothing[check]
+	if !ok {
+		return value, nil
+	}
+	s.smoothersMu.Lock()
+	defer s.smoothersMu.Unlock()
+	if s.smoothers == nil {
+		s.smoothers = map[string]*smoother{}
+	}
+	m, ok := s.smoothers[alertID]
+	if !ok {
+		m = &smoother{}
+		s.smoothers[alertID] = m
+	}
+	raw := value
+	return m.Add(smoothing, value), &raw
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// dialSMTP connects to the mail relay, says EHLO, upgrades to TLS and
+// authenticates if credentials are given. Port 465 uses implicit TLS,
+// other ports must offer STARTTLS.
+func (s *SystemMonitor) dialSMTP() (*smtp.Client, error) {
+	host, port, err := net.SplitHostPort(s.config.SMTPAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address: %v", err)
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.config.SMTPAddress, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", s.config.SMTPAddress)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %v", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read greeting: %v", err)
+	}
+
+	if err := client.Hello(s.hostname); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("EHLO failed: %v", err)
+	}
+	if port != "465" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("relay does not offer STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+	if s.config.SMTPUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.config.SMTPUsername, s.config.SMTPPassword, host)); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("AUTH failed: %v", err)
+		}
+	}
+	return client, nil
+}
+
+// probeSMTP checks that a session with the mail relay can be set up.
+func (s *SystemMonitor) probeSMTP() error {
+	client, err := s.dialSMTP()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Quit()
+}
+
+// sendMail sends a plain text email through the mail relay.
+func (s *SystemMonitor) sendMail(from string, to []string, subject, body string) error {
+	client, err := s.dialSMTP()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("MAIL FROM failed: %v", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("RCPT TO %s failed: %v", recipient, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA failed: %v", err)
+	}
+	fmt.Fprintf(writer, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n",
+		from, strings.Join(to, ", "), mime.QEncoding.Encode("utf-8", subject), time.Now().Format(time.RFC1123Z))
+	fmt.Fprint(writer, strings.ReplaceAll(body, "\n", "\r\n"))
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
+	}
+	return client.Quit()
+}
+
+// checkSMTP alerts when the mail relay cannot be reached or responds
+// slower than SMTPLatencyLimit, before users report missing emails.
+func (s *SystemMonitor) checkSMTP(ctx context.Context) error {
+	start := time.Now()
+	err := s.probeSMTP()
+	latency := float64(time.Since(start).Milliseconds())
+	s.recordValue("smtp.latency_ms", latency)
+
+	status := s.getStatus(latency, s.config.SMTPLatencyLimit)
+	cause := fmt.Sprintf("%s responded in %.0f ms", s.config.SMTPAddress, latency)
+	if err != nil {
+		status = "fail"
+		cause = fmt.Sprintf("%s: %v", s.config.SMTPAddress, err)
+	}
+	if status == "fail" {
+		s.log.Warn("SMTP check failed: %s", cause)
+	} else {
+		s.log.Log("SMTP relay %s", cause)
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "smtp",
+		Title:     fmt.Sprintf("SMTP Relay - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("smtp-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     latency,
+		Limit:     s.config.SMTPLatencyLimit,
+		Unit:      UnitMS,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, latency, 0),
+		Labels:    map[string]string{"relay": s.config.SMTPAddress},
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type SNSSink struct {
+	httpClient  *http.Client
+	credentials *awsCredentialChain
+	topicARN    string
+	region      string
+	schema      int
+	log         *Logger
+}
+
+func NewSNSSink(topicARN, region string) (*SNSSink, error) {
+	// arn:aws:sns:<region>:<account>:<topic>
+	parts := strings.Split(topicARN, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" {
+		return nil, fmt.Errorf("invalid SNS topic ARN: %s", topicARN)
+	}
+	if region == "" {
+		region = parts[3]
+	}
+
+	return &SNSSink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		credentials: newAWSCredentialChain(),
+		topicARN:    topicARN,
+		region:      region,
+		schema:      payloadSchemaV1,
+		log:         New(),
+	}, nil
+}
+
+func (s *SNSSink) Name() string {
+	return "sns"
+}
+
+func (s *SNSSink) SetPayloadSchema(version int) {
+	s.schema = version
+}
+
+// Accepts only failures, resolutions and digests, as SMS subscribers
+// are charged for every message.
+func (s *SNSSink) Accepts(metric Metric) bool {
+	return notifiable(metric)
+}
+
+func (s *SNSSink) Send(metric Metric) error {
+	creds, err := s.credentials.Retrieve()
+	if err != nil {
+		return err
+	}
+
+	payload, err := encodePayload(metric, s.schema)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metric: %v", err)
+	}
+
+	// Lambda and SQS subscribers receive the JSON payload, SMS and email
+	// subscribers get a readable line instead.
+	text := formatMetricText(metric)
+	message, err := json.Marshal(map[string]string{
+		"default": string(payload),
+		"sms":     text,
+		"email":   text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	subject := metric.Title
+	if len(subject) > 100 {
+		subject = subject[:100]
+	}
+
+	form := url.Values{}
+	form.Set("Action", "Publish")
+	form.Set("Version", "2010-03-31")
+	form.Set("TopicArn", s.topicARN)
+	form.Set("Subject", subject)
+	form.Set("Message", string(message))
+	form.Set("MessageStructure", "json")
+	body := []byte(form.Encode())
+
+	endpoint := fmt.Sprintf("https://sns.%s.amazonaws.com/", s.region)
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+	signAWSRequest(req, body, creds, s.region, "sns", time.Now())
+
+	return deliver(s.httpClient, req, s.Name(), s.log)
+}
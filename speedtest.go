@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// speedtestDuration bounds each measured transfer.
+const speedtestDuration = 10 * time.Second
+
+// iperf3Throughput runs a client test against an iperf3 server and
+// returns the received throughput in Mbit/s. With reverse the server
+// sends, measuring the download.
+func iperf3Throughput(ctx context.Context, server string, reverse bool) (float64, error) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = server, "5201"
+	}
+	args := []string{"-c", host, "-p", port, "-J", "-t", fmt.Sprint(int(speedtestDuration.Seconds()))}
+	if reverse {
+		args = append(args, "-R")
+	}
+
+	output, err := runCommand(ctx, "iperf3", args...)
+	var result struct {
+		Error string `json:"error"`
+		End   struct {
+			SumReceived struct {
+				BitsPerSecond float64 `json:"bits_per_second"`
+			} `json:"sum_received"`
+		} `json:"end"`
+	}
+	// iperf3 reports errors such as a busy server in its JSON output
+	if jsonErr := json.Unmarshal(output, &result); jsonErr == nil && result.Error != "" {
+		return 0, fmt.Errorf("iperf3: %s", result.Error)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.End.SumReceived.BitsPerSecond / 1e6, nil
+}
+
+// httpThroughput downloads from rawURL for at most speedtestDuration and
+// returns the throughput in Mbit/s.
+func httpThroughput(rawURL string) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), speedtestDuration)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status from %s: %s", rawURL, resp.Status)
+	}
+
+	// A file larger than the link can transfer in time is cut off by the
+	// deadline, which still leaves a valid measurement
+	received, err := io.Copy(io.Discard, resp.Body)
+	if err != nil && ctx.Err() == nil {
+		return 0, fmt.Errorf("failed to read response: %v", err)
+	}
+	elapsed := time.Since(start).Seconds()
+	if received == 0 || elapsed <= 0 {
+		return 0, fmt.Errorf("no data received from %s", rawURL)
+	}
+	return float64(received) * 8 / elapsed / 1e6, nil
+}
+
+// checkSpeedtest measures the bandwidth at most once per
+// SpeedtestInterval, as every test saturates the uplink.
+func (s *SystemMonitor) checkSpeedtest(ctx context.Context) error {
+	if time.Since(s.speedtestAt) < s.config.SpeedtestInterval {
+		return nil
+	}
+	s.speedtestAt = time.Now()
+
+	type measurement struct {
+		kind    string
+		title   string
+		measure func() (float64, error)
+		minimum float64
+	}
+	var measurements []measurement
+	if strings.HasPrefix(s.config.SpeedtestTarget, "iperf3://") {
+		server := strings.TrimPrefix(s.config.SpeedtestTarget, "iperf3://")
+		measurements = []measurement{
+			{"download", "Download", func() (float64, error) { return iperf3Throughput(ctx, server, true) }, s.config.SpeedtestMinDownload},
+			{"upload", "Upload", func() (float64, error) { return iperf3Throughput(ctx, server, false) }, s.config.SpeedtestMinUpload},
+		}
+	} else {
+		measurements = []measurement{
+			{"download", "Download", func() (float64, error) { return httpThroughput(s.config.SpeedtestTarget) }, s.config.SpeedtestMinDownload},
+		}
+	}
+
+	for _, m := range measurements {
+		mbps, err := m.measure()
+		if err != nil {
+			return fmt.Errorf("failed to measure %s: %v", m.kind, err)
+		}
+		s.recordValue(valueName("speedtest", m.kind+"_mbps"), mbps)
+
+		status := "pass"
+		if m.minimum > 0 && mbps < m.minimum {
+			status = "fail"
+			s.log.Warn("%s bandwidth %.1f Mbit/s is below minimum of %.1f Mbit/s", m.title, mbps, m.minimum)
+		} else {
+			s.log.Log("%s bandwidth: %.1f Mbit/s", m.title, mbps)
+		}
+		if m.minimum <= 0 {
+			continue
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "speedtest",
+			Title:     fmt.Sprintf("%s Bandwidth - %s", m.title, s.hostname),
+			Cause:     fmt.Sprintf("Measured %.1f Mbit/s against %s", mbps, s.config.SpeedtestTarget),
+			AlertID:   fmt.Sprintf("speedtest-%s-%s", m.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     mbps,
+			Limit:     m.minimum,
+			Unit:      UnitMbps,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, mbps, 0),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
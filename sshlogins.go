@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var authLogPaths = []string{"/var/log/auth.log", "/var/log/secure"}
+
+// sshFailurePatterns match one line per failed login attempt. Attempts for
+// unknown users log "Invalid user" first and "Failed password for invalid
+// user" after, only the first is matched.
+var sshFailurePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`Invalid user \S* from (\S+)`),
+	regexp.MustCompile(`Failed \S+ for (\S+) from (\S+)`),
+}
+
+// logTail remembers how far a log file has been read. The first
+// read only records the end of the file, so old entries are not counted.
+type logTail struct {
+	path    string
+	offset  int64
+	started bool
+}
+
+// Read returns the lines appended since the previous read, starting over
+// when the file has been rotated.
+func (t *logTail) Read() ([]string, error) {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !t.started {
+		t.started = true
+		t.offset = info.Size()
+		return nil, nil
+	}
+	if info.Size() < t.offset {
+		t.offset = 0
+	}
+
+	if _, err := file.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	t.offset += int64(len(data))
+	return strings.Split(string(data), "\n"), nil
+}
+
+// sshLogLines returns the sshd log lines since the previous cycle, from
+// the auth log if there is one, otherwise from the journal.
+func (s *SystemMonitor) sshLogLines(ctx context.Context) ([]string, error) {
+	if s.authLog == nil {
+		for _, path := range authLogPaths {
+			if _, err := os.Stat(path); err == nil {
+				s.authLog = &logTail{path: path}
+				break
+			}
+		}
+	}
+	if s.authLog != nil {
+		return s.authLog.Read()
+	}
+
+	now := time.Now()
+	since := s.sshAt
+	s.sshAt = now
+	if since.IsZero() {
+		return nil, nil
+	}
+
+	output, err := runCommand(ctx, "journalctl", "_COMM=sshd", "_COMM=sshd-session", fmt.Sprintf("--since=@%d", since.Unix()), fmt.Sprintf("--until=@%d", now.Unix()), "--output=cat", "--quiet", "--no-pager")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(output), "\n"), nil
+}
+
+// checkSSHLogins alerts when the number of failed SSH logins in a cycle
+// exceeds SSHFailedLoginLimit, listing the most active source addresses.
+func (s *SystemMonitor) checkSSHLogins(ctx context.Context) error {
+	lines, err := s.sshLogLines(ctx)
+	if err != nil {
+		return err
+	}
+
+	sources := map[string]int{}
+	count := 0
+	for _, line := range lines {
+		for _, pattern := range sshFailurePatterns {
+			if match := pattern.FindStringSubmatch(line); match != nil {
+				sources[match[len(match)-1]]++
+				count++
+				break
+			}
+		}
+	}
+
+	addresses := make([]string, 0, len(sources))
+	for address := range sources {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		if sources[addresses[i]] != sources[addresses[j]] {
+			return sources[addresses[i]] > sources[addresses[j]]
+		}
+		return addresses[i] < addresses[j]
+	})
+	if len(addresses) > 5 {
+		addresses = addresses[:5]
+	}
+	top := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		top = append(top, fmt.Sprintf("%s (%d)", address, sources[address]))
+	}
+
+	value := float64(count)
+	s.recordValue("ssh.failed_logins", value)
+	status := s.getStatus(value, s.config.SSHFailedLoginLimit)
+	cause := fmt.Sprintf("%d failed SSH logins since the last check", count)
+	if len(top) > 0 {
+		cause += ", top sources: " + strings.Join(top, ", ")
+	}
+	if status == "fail" {
+		s.log.Warn("%s", cause)
+	} else {
+		s.log.Log("Failed SSH logins: %d (limit: %.0f)", count, s.config.SSHFailedLoginLimit)
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "ssh",
+		Title:     fmt.Sprintf("Failed SSH Logins - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("ssh-failed-logins-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     s.config.SSHFailedLoginLimit,
+		Unit:      UnitCount,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// AlertStats counts what happened to alerts since the agent started, to
+// tune routing, escalations and silencing with data.
+type AlertStats struct {
+	// New failures delivered to at least one sink
+	Sent int `json:"sent"`
+	// Recoveries delivered to at least one sink
+	Recovered int `json:"recovered"`
+	// Failures not delivered because the alert was acknowledged or snoozed
+	Suppressed int `json:"suppressed"`
+	// Failures of alerts already failing, which receivers deduplicate by
+	// alert ID
+	Repeated int `json:"repeated"`
+	// Failures and recoveries no sink is routed to
+	Unrouted int `json:"unrouted"`
+	// Failures held back from at least one sink during its quiet hours
+	Held int `json:"held"`
+	// Deliveries that failed, over all sinks
+	DeliveryFailures int `json:"delivery_failures"`
+
+	Sinks []SinkStats `json:"sinks"`
+}
+
+// SinkStats are the delivery statistics of a sink.
+type SinkStats struct {
+	Name             string  `json:"name"`
+	Delivered        int     `json:"delivered"`
+	Failed           int     `json:"failed"`
+	AverageLatencyMS float64 `json:"average_latency_ms"`
+	LastError        string  `json:"last_error,omitempty"`
+	// closed, open while deliveries are paused, or half-open
+	Circuit string `json:"circuit"`
+}
+
+// alertCounters are the counters of AlertStats maintained by sendMetric.
+type alertCounters struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newAlertCounters() *alertCounters {
+	return &alertCounters{counts: map[string]int{}}
+}
+
+func (c *alertCounters) Add(kind string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[kind]++
+}
+
+func (c *alertCounters) Get(kind string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.counts[kind]
+}
+
+// countAlert counts a delivered metric. Passing metrics of alerts that
+// weren't failing are routine and not counted.
+func (s *SystemMonitor) countAlert(metric Metric, wasFailing bool, targets, failed int) {
+	failing := metric.Status == "fail"
+	switch {
+	case failing && wasFailing:
+		s.counters.Add("repeated")
+	case !failing && !wasFailing:
+	case targets == 0:
+		s.counters.Add("unrouted")
+	case failed == targets:
+		// Counted as delivery failures
+	case failing:
+		s.counters.Add("sent")
+	default:
+		s.counters.Add("recovered")
+	}
+}
+
+func (s *SystemMonitor) alertStats() AlertStats {
+	stats := AlertStats{
+		Sent:       s.counters.Get("sent"),
+		Recovered:  s.counters.Get("recovered"),
+		Suppressed: s.counters.Get("suppressed"),
+		Repeated:   s.counters.Get("repeated"),
+		Unrouted:   s.counters.Get("unrouted"),
+		Held:       s.counters.Get("held"),
+		Sinks:      []SinkStats{},
+	}
+
+	circuits := map[string]string{}
+	for _, sink := range s.sinks {
+		if breaker, ok := sink.(*breakerSink); ok {
+			circuits[sink.Name()] = breaker.State()
+		}
+	}
+	for name, delivery := range s.deliveries.Snapshot() {
+		stats.DeliveryFailures += delivery.Failed
+		stats.Sinks = append(stats.Sinks, SinkStats{
+			Name:             name,
+			Delivered:        delivery.Delivered,
+			Failed:           delivery.Failed,
+			AverageLatencyMS: float64(delivery.AverageLatency().Microseconds()) / 1000,
+			LastError:        delivery.LastError,
+			Circuit:          circuits[name],
+		})
+	}
+	sort.Slice(stats.Sinks, func(i, j int) bool {
+		return stats.Sinks[i].Name < stats.Sinks[j].Name
+	})
+	return stats
+}
+
+// GET /stats
+func (a *APIServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, a.monitor.alertStats())
+}
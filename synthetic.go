@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var syntheticVariable = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+// SyntheticCheck is a scripted sequence of HTTP requests, e.g. create a
+// session, create a document and delete it again, loaded from a JSON file.
+type SyntheticCheck struct {
+	Name      string            `json:"name"`
+	Budget    string            `json:"budget"`
+	Variables map[string]string `json:"variables"`
+	Steps     []SyntheticStep   `json:"steps"`
+
+	budget time.Duration
+}
+
+// SyntheticStep is a single request of a synthetic check. Values
+// extracted from its response become variables of the following steps.
+type SyntheticStep struct {
+	Name     string            `json:"name"`
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers"`
+	Body     json.RawMessage   `json:"body"`
+	Status   int               `json:"status"`
+	Contains string            `json:"contains"`
+	Assert   []string          `json:"assert"`
+	Extract  map[string]string `json:"extract"`
+	Always   bool              `json:"always"`
+
+	assertions []JSONAssertion
+	extract    map[string][]string
+}
+
+// LoadSyntheticCheck reads and validates a synthetic check file.
+// Environment variables in variable values are expanded, so secrets can
+// stay out of the file.
+func LoadSyntheticCheck(path string) (SyntheticCheck, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SyntheticCheck{}, err
+	}
+
+	var check SyntheticCheck
+	if err := json.Unmarshal(data, &check); err != nil {
+		return SyntheticCheck{}, fmt.Errorf("failed to parse: %v", err)
+	}
+	if check.Name == "" {
+		return SyntheticCheck{}, fmt.Errorf("name is required")
+	}
+	if len(check.Steps) == 0 {
+		return SyntheticCheck{}, fmt.Errorf("at least one step is required")
+	}
+	if check.Budget != "" {
+		if check.budget, err = time.ParseDuration(check.Budget); err != nil || check.budget <= 0 {
+			return SyntheticCheck{}, fmt.Errorf("invalid budget %q", check.Budget)
+		}
+	}
+	for name, value := range check.Variables {
+		check.Variables[name] = os.ExpandEnv(value)
+	}
+
+	for i := range check.Steps {
+		step := &check.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step-%d", i+1)
+		}
+		if step.Method == "" {
+			step.Method = http.MethodGet
+		}
+		step.Method = strings.ToUpper(step.Method)
+		if step.URL == "" {
+			return SyntheticCheck{}, fmt.Errorf("step %s: url is required", step.Name)
+		}
+		for _, value := range step.Assert {
+			assertion, err := ParseJSONAssertion(value)
+			if err != nil {
+				return SyntheticCheck{}, fmt.Errorf("step %s: invalid assertion %q: %v", step.Name, value, err)
+			}
+			step.assertions = append(step.assertions, assertion)
+		}
+		step.extract = map[string][]string{}
+		for variable, source := range step.Extract {
+			if strings.HasPrefix(strings.ToLower(source), "header:") {
+				continue
+			}
+			path, err := parseJSONPath(source)
+			if err != nil {
+				return SyntheticCheck{}, fmt.Errorf("step %s: invalid extract %q: %v", step.Name, source, err)
+			}
+			step.extract[variable] = path
+		}
+	}
+	return check, nil
+}
+
+// expand replaces {{variable}} placeholders. With escape, values are
+// escaped to be embedded in JSON strings.
+func expand(text string, variables map[string]string, escape bool) string {
+	return syntheticVariable.ReplaceAllStringFunc(text, func(match string) string {
+		value, ok := variables[syntheticVariable.FindStringSubmatch(match)[1]]
+		if !ok {
+			return match
+		}
+		if escape {
+			quoted, _ := json.Marshal(value)
+			return string(quoted[1 : len(quoted)-1])
+		}
+		return value
+	})
+}
+
+// run executes the step and adds extracted values to variables.
+func (step SyntheticStep) run(ctx context.Context, client *http.Client, variables map[string]string) error {
+	var body io.Reader
+	if len(step.Body) > 0 {
+		body = strings.NewReader(expand(string(step.Body), variables, true))
+	}
+	req, err := http.NewRequestWithContext(ctx, step.Method, expand(step.URL, variables, false), body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range step.Headers {
+		req.Header.Set(name, expand(value, variables, false))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPCheckBodySize))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	if step.Status != 0 && resp.StatusCode != step.Status {
+		return fmt.Errorf("status %d, expected %d", resp.StatusCode, step.Status)
+	}
+	if step.Status == 0 && resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if step.Contains != "" && !bytes.Contains(data, []byte(expand(step.Contains, variables, false))) {
+		return fmt.Errorf("body does not contain %q", step.Contains)
+	}
+
+	var document interface{}
+	if len(step.assertions) > 0 || len(step.extract) > 0 {
+		if err := json.Unmarshal(data, &document); err != nil {
+			return fmt.Errorf("body is not JSON: %v", err)
+		}
+	}
+	for _, assertion := range step.assertions {
+		if ok, mismatch := assertion.Evaluate(document); !ok {
+			return fmt.Errorf("%s", mismatch)
+		}
+	}
+	for variable, source := range step.Extract {
+		if strings.HasPrefix(strings.ToLower(source), "header:") {
+			variables[variable] = resp.Header.Get(source[len("header:"):])
+			continue
+		}
+		value, found := lookupJSONPath(document, step.extract[variable])
+		if !found {
+			return fmt.Errorf("cannot extract %s: %s not found", variable, source)
+		}
+		if text, ok := value.(string); ok {
+			variables[variable] = text
+		} else {
+			encoded, _ := json.Marshal(value)
+			variables[variable] = string(encoded)
+		}
+	}
+	return nil
+}
+
+func (s *SystemMonitor) checkSynthetic(ctx context.Context) error {
+	for _, check := range s.config.SyntheticChecks {
+		// Every run gets its own cookies, so session cookies set by a
+		// login step are sent by the following steps only
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return err
+		}
+		client := &http.Client{Timeout: s.config.HTTPTimeout, Jar: jar}
+
+		variables := map[string]string{}
+		for name, value := range check.Variables {
+			variables[name] = value
+		}
+
+		var failure, failedURL string
+		start := time.Now()
+		for _, step := range check.Steps {
+			// Cleanup steps marked "always" still run after a failure
+			if failure != "" && !step.Always {
+				continue
+			}
+			stepStart := time.Now()
+			err := step.run(ctx, client, variables)
+			latency := float64(time.Since(stepStart).Microseconds()) / 1000
+			if err != nil {
+				if failure == "" {
+					failure = fmt.Sprintf("Step %s failed: %v", step.Name, err)
+					failedURL = expand(step.URL, variables, false)
+				}
+				continue
+			}
+			s.recordValue(valueName("synthetic", check.Name, step.Name, "latency_ms"), latency)
+		}
+		duration := float64(time.Since(start).Microseconds()) / 1000
+
+		labels := map[string]string{"check": check.Name}
+		status := "pass"
+		value := 0.0
+		cause := fmt.Sprintf("%d steps completed in %.0f ms", len(check.Steps), duration)
+		if failure != "" {
+			status = "fail"
+			value = 1
+			cause = failure
+			s.log.Warn("Synthetic check %s: %s", check.Name, cause)
+			cause = s.tracerouteCause(ctx, cause, failedURL)
+		} else {
+			s.log.Log("Synthetic check %s: %s", check.Name, cause)
+		}
+		s.recordValue(valueName("synthetic", check.Name, "up"), 1-value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "synthetic",
+			Title:     fmt.Sprintf("Synthetic Check %s - %s", check.Name, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("synthetic-%s-%s", check.Name, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+
+		if failure != "" {
+			continue
+		}
+		s.recordValue(valueName("synthetic", check.Name, "duration_ms"), duration)
+		if check.budget <= 0 {
+			continue
+		}
+
+		budget := float64(check.budget.Milliseconds())
+		status = s.getStatus(duration, budget)
+		if status == "fail" {
+			s.log.Warn("Synthetic check %s took %.0f ms, exceeding its budget of %.0f ms", check.Name, duration, budget)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "synthetic",
+			Title:     fmt.Sprintf("Synthetic Check %s Duration - %s", check.Name, s.hostname),
+			Cause:     fmt.Sprintf("%d steps completed in %.0f ms", len(check.Steps), duration),
+			AlertID:   fmt.Sprintf("synthetic-duration-%s-%s", check.Name, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     duration,
+			Limit:     budget,
+			Unit:      UnitMS,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, duration, 0),
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
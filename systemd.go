@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func failedSystemdUnits(ctx context.Context) ([]string, error) {
+	output, err := runCommand(ctx, "systemctl", "list-units", "--state=failed", "--no-legend", "--plain", "--no-pager")
+	if err != nil {
+		return nil, err
+	}
+
+	var units []string
+	for _, line := range strings.Split(string(output), "\n") {
+		// "● nginx.service loaded failed failed A high performance web server"
+		fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "●"))
+		if len(fields) > 0 {
+			units = append(units, fields[0])
+		}
+	}
+	return units, nil
+}
+
+func (s *SystemMonitor) checkSystemdUnits(ctx context.Context) error {
+	units, err := failedSystemdUnits(ctx)
+	if err != nil {
+		return err
+	}
+
+	value := float64(len(units))
+	s.recordValue("systemd.failed_units", value)
+
+	status := s.getStatus(value, 0)
+	cause := "No failed units"
+	if status == "fail" {
+		cause = "Failed units: " + strings.Join(units, ", ")
+		s.log.Warn("%d failed systemd units: %s", len(units), strings.Join(units, ", "))
+	} else {
+		s.log.Log("No failed systemd units")
+	}
+
+	return s.sendMetric(Metric{
+		Name:      "systemd",
+		Title:     fmt.Sprintf("Failed Systemd Units - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("systemd-failed-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     0,
+		Unit:      UnitCount,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tcpTraceWindow is how long bpftrace traces TCP every cycle.
+const tcpTraceWindow = 10 * time.Second
+
+// tcpTraceScript counts retransmits and averages the time from SYN to
+// established per destination, for IPv4 (family 2) and IPv6 (family 10).
+// Both tracepoints exist since Linux 4.16.
+var tcpTraceScript = fmt.Sprintf(`
+tracepoint:tcp:tcp_retransmit_skb /args->family == 2/ { @retransmits[ntop(args->daddr)] = count(); }
+tracepoint:tcp:tcp_retransmit_skb /args->family == 10/ { @retransmits[ntop(args->daddr_v6)] = count(); }
+tracepoint:sock:inet_sock_set_state /args->protocol == 6 && args->newstate == 2/ { @start[args->skaddr] = nsecs; }
+tracepoint:sock:inet_sock_set_state /args->protocol == 6 && args->oldstate == 2 && args->newstate == 1 && @start[args->skaddr] && args->family == 2/ {
+	@connect_us[ntop(args->daddr)] = avg((nsecs - @start[args->skaddr]) / 1000); delete(@start[args->skaddr]);
+}
+tracepoint:sock:inet_sock_set_state /args->protocol == 6 && args->oldstate == 2 && args->newstate == 1 && @start[args->skaddr] && args->family == 10/ {
+	@connect_us[ntop(args->daddr_v6)] = avg((nsecs - @start[args->skaddr]) / 1000); delete(@start[args->skaddr]);
+}
+tracepoint:sock:inet_sock_set_state /args->protocol == 6 && args->oldstate == 2 && args->newstate != 1/ { delete(@start[args->skaddr]); }
+interval:s:%d { clear(@start); exit(); }
+`, int(tcpTraceWindow.Seconds()))
+
+// tcpTrace is what bpftrace saw during one window.
+type tcpTrace struct {
+	Retransmits    map[string]float64
+	ConnectLatency map[string]float64 // ms
+}
+
+// traceTCP runs tcpTraceScript, which needs root or CAP_BPF and
+// CAP_PERFMON.
+func traceTCP(ctx context.Context) (tcpTrace, error) {
+	output, err := runCommand(ctx, "bpftrace", "-f", "json", "-e", tcpTraceScript)
+	if err != nil {
+		return tcpTrace{}, err
+	}
+
+	// {"type": "map", "data": {"@retransmits": {"10.0.0.5": 3}}}
+	trace := tcpTrace{Retransmits: map[string]float64{}, ConnectLatency: map[string]float64{}}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			Type string                        `json:"type"`
+			Data map[string]map[string]float64 `json:"data"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.Type != "map" {
+			continue
+		}
+		for destination, count := range line.Data["@retransmits"] {
+			trace.Retransmits[destination] = count
+		}
+		for destination, microseconds := range line.Data["@connect_us"] {
+			trace.ConnectLatency[destination] = microseconds / 1000
+		}
+	}
+	return trace, scanner.Err()
+}
+
+// tcpSegments reads the segments sent and retransmitted since boot from
+// /proc/net/snmp, where a header line names the values of the next line.
+func tcpSegments() (float64, float64, error) {
+	data, err := os.ReadFile("/proc/net/snmp")
+	if err != nil {
+		return 0, 0, err
+	}
+	var header []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "Tcp:" {
+			continue
+		}
+		if header == nil {
+			header = fields
+			continue
+		}
+		values := map[string]float64{}
+		for i, name := range header {
+			if i < len(fields) {
+				values[name], _ = strconv.ParseFloat(fields[i], 64)
+			}
+		}
+		return values["OutSegs"], values["RetransSegs"], nil
+	}
+	return 0, 0, fmt.Errorf("no TCP counters in /proc/net/snmp")
+}
+
+// topDestinations formats the destinations with the highest values, e.g.
+// "10.0.0.5 (12), 10.0.0.7 (3)".
+func topDestinations(values map[string]float64, format string, count int) string {
+	destinations := make([]string, 0, len(values))
+	for destination := range values {
+		destinations = append(destinations, destination)
+	}
+	sort.Slice(destinations, func(i, j int) bool {
+		if values[destinations[i]] != values[destinations[j]] {
+			return values[destinations[i]] > values[destinations[j]]
+		}
+		return destinations[i] < destinations[j]
+	})
+	if len(destinations) > count {
+		destinations = destinations[:count]
+	}
+	parts := make([]string, len(destinations))
+	for i, destination := range destinations {
+		parts[i] = fmt.Sprintf("%s ("+format+")", destination, values[destination])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkTCP alerts on the share of retransmitted segments since the
+// previous cycle and, with eBPF, on the slowest average connect latency
+// to a destination. With eBPF, the retransmit alert names the
+// destinations with the most retransmits, which interface counters can't.
+func (s *SystemMonitor) checkTCP(ctx context.Context) error {
+	var trace tcpTrace
+	if s.config.EBPF {
+		var err error
+		if trace, err = traceTCP(ctx); err != nil {
+			return fmt.Errorf("failed to trace TCP: %v", err)
+		}
+		for destination, count := range trace.Retransmits {
+			s.recordValue(valueName("tcp", destination, "retransmits"), count)
+		}
+		for destination, latency := range trace.ConnectLatency {
+			s.recordValue(valueName("tcp", destination, "connect_latency_ms"), latency)
+		}
+	}
+
+	if s.config.TCPRetransmitLimit > 0 {
+		sent, retransmitted, err := tcpSegments()
+		if err != nil {
+			return fmt.Errorf("failed to read TCP counters: %v", err)
+		}
+		previous := s.tcpSegments
+		s.tcpSegments = [2]float64{sent, retransmitted}
+
+		// The first cycle only records the counters
+		if previous[0] > 0 && sent > previous[0] && retransmitted >= previous[1] {
+			value := 100 * (retransmitted - previous[1]) / (sent - previous[0])
+			s.recordValue("tcp.retransmit_percent", value)
+
+			status := s.getStatus(value, s.config.TCPRetransmitLimit)
+			cause := fmt.Sprintf("%.2f%% of %.0f TCP segments retransmitted since the previous check", value, sent-previous[0])
+			if len(trace.Retransmits) > 0 {
+				cause += ", most to " + topDestinations(trace.Retransmits, "%.0f", 3)
+			}
+			if status == "fail" {
+				s.log.Warn("%s", cause)
+			} else {
+				s.log.Log("%s", cause)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "tcp",
+				Title:     fmt.Sprintf("TCP Retransmits - %s", s.hostname),
+				Cause:     cause,
+				AlertID:   fmt.Sprintf("tcp-retransmits-%s", s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     value,
+				Limit:     s.config.TCPRetransmitLimit,
+				Unit:      UnitPercent,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(status, value, 0),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.config.TCPConnectLatencyLimit > 0 {
+		value := 0.0
+		for _, latency := range trace.ConnectLatency {
+			if latency > value {
+				value = latency
+			}
+		}
+		s.recordValue("tcp.connect_latency_ms.max", value)
+
+		status := s.getStatus(value, s.config.TCPConnectLatencyLimit)
+		cause := fmt.Sprintf("No TCP connections in the last %s", tcpTraceWindow)
+		if len(trace.ConnectLatency) > 0 {
+			cause = "Slowest average TCP connect: " + topDestinations(trace.ConnectLatency, "%.1f ms", 3)
+		}
+		if status == "fail" {
+			s.log.Warn("%s", cause)
+		} else {
+			s.log.Log("%s", cause)
+		}
+
+		return s.sendMetric(Metric{
+			Name:      "tcp",
+			Title:     fmt.Sprintf("TCP Connect Latency - %s", s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("tcp-connect-%s", s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     s.config.TCPConnectLatencyLimit,
+			Unit:      UnitMS,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+package main
+
+// terminalSize returns the default size of the Windows console, which
+// has no TIOCGWINSZ.
+func terminalSize() (int, int) {
+	return 80, 24
+}
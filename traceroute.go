@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// traceroute runs a bounded traceroute to host and returns the hops as
+// "<hop> <address> <rtt>", with "*" for hops that did not answer.
+func traceroute(ctx context.Context, host string) ([]string, error) {
+	// One probe per hop with a one second wait keeps the worst case at
+	// about 20 seconds, within the command timeout
+	output, err := runCommand(ctx, "traceroute", "-n", "-q", "1", "-w", "1", "-m", "20", host)
+	if err != nil && len(output) == 0 {
+		return nil, err
+	}
+
+	var hops []string
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(line, "traceroute") {
+			continue
+		}
+		hop := fields[0] + " " + fields[1]
+		if len(fields) >= 4 && fields[3] == "ms" {
+			hop += " " + fields[2] + "ms"
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// tracerouteCause appends the network path to the host of rawURL to
+// cause, so responders see where along the way requests get lost.
+func (s *SystemMonitor) tracerouteCause(ctx context.Context, cause, rawURL string) string {
+	if !s.config.Traceroute {
+		return cause
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return cause
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return cause
+	}
+
+	hops, err := traceroute(ctx, host)
+	if err != nil {
+		s.log.Error("Failed to trace route to %s: %v", host, err)
+		return cause
+	}
+	s.log.Warn("Route to %s: %s", host, strings.Join(hops, ", "))
+
+	return fmt.Sprintf("%s. Route to %s: %s", cause, host, strings.Join(hops, ", "))
+}
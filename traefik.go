@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// traefikCounters holds the request counters of one entrypoint.
+type traefikCounters struct {
+	Requests float64
+	Errors   float64
+}
+
+// checkTraefik pings Traefik and computes the 5xx rate of each entrypoint
+// from the request counters of its Prometheus metrics since the previous
+// cycle.
+func (s *SystemMonitor) checkTraefik(ctx context.Context) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	base := strings.TrimSuffix(s.config.TraefikURL, "/")
+
+	pingErr := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/ping", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("ping returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		}
+		return nil
+	}()
+
+	status := "pass"
+	cause := "Traefik ping is OK"
+	if pingErr != nil {
+		status = "fail"
+		cause = fmt.Sprintf("Traefik ping failed: %v", pingErr)
+		s.log.Warn("%s", cause)
+	}
+	if err := s.sendMetric(Metric{
+		Name:      "traefik",
+		Title:     fmt.Sprintf("Traefik Health - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("traefik-ping-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     0,
+		Limit:     0,
+		Unit:      UnitBoolean,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, 0, 0),
+	}); err != nil {
+		return err
+	}
+	if pingErr != nil {
+		return nil
+	}
+
+	samples, err := scrapePrometheus(ctx, client, base+"/metrics")
+	if err != nil {
+		return fmt.Errorf("failed to scrape metrics: %v", err)
+	}
+
+	counters := map[string]traefikCounters{}
+	connections := map[string]float64{}
+	for _, sample := range samples {
+		entrypoint := sample.Labels["entrypoint"]
+		switch sample.Name {
+		case "traefik_entrypoint_requests_total":
+			c := counters[entrypoint]
+			c.Requests += sample.Value
+			if strings.HasPrefix(sample.Labels["code"], "5") {
+				c.Errors += sample.Value
+			}
+			counters[entrypoint] = c
+		case "traefik_entrypoint_open_connections":
+			connections[entrypoint] += sample.Value
+		}
+	}
+
+	previous := s.traefikCounters
+	s.traefikCounters = counters
+
+	entrypoints := make([]string, 0, len(counters))
+	for entrypoint := range counters {
+		entrypoints = append(entrypoints, entrypoint)
+	}
+	sort.Strings(entrypoints)
+
+	for _, entrypoint := range entrypoints {
+		s.recordValue(valueName("traefik", entrypoint, "open_connections"), connections[entrypoint])
+		before, ok := previous[entrypoint]
+		current := counters[entrypoint]
+		// Counters reset when Traefik restarts
+		if !ok || current.Requests < before.Requests {
+			continue
+		}
+
+		requests := current.Requests - before.Requests
+		errors := current.Errors - before.Errors
+		rate := 0.0
+		if requests > 0 {
+			rate = errors / requests * 100
+		}
+		s.recordValue(valueName("traefik", entrypoint, "error_percent"), rate)
+
+		status := s.getStatus(rate, s.config.TraefikErrorRateLimit)
+		cause := fmt.Sprintf("%.0f of %.0f requests failed with 5xx, %.0f open connections", errors, requests, connections[entrypoint])
+		if status == "fail" {
+			s.log.Warn("Traefik entrypoint %s 5xx rate %.2f%% exceeds limit of %.2f%%", entrypoint, rate, s.config.TraefikErrorRateLimit)
+		} else {
+			s.log.Log("Traefik entrypoint %s: %s", entrypoint, cause)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "traefik",
+			Title:     fmt.Sprintf("Traefik 5xx Rate %s - %s", entrypoint, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("traefik-errors-%s-%s", valueName(entrypoint), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     rate,
+			Limit:     s.config.TraefikErrorRateLimit,
+			Unit:      UnitPercent,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, rate, 0),
+			Labels:    map[string]string{"entrypoint": entrypoint},
+		}); err != nil {
+			return err
+		}
+
+		if s.config.TraefikConnectionsLimit <= 0 {
+			continue
+		}
+		value := connections[entrypoint]
+		status = s.getStatus(value, s.config.TraefikConnectionsLimit)
+		if status == "fail" {
+			s.log.Warn("Traefik entrypoint %s has %.0f open connections, limit %.0f", entrypoint, value, s.config.TraefikConnectionsLimit)
+		}
+		if err := s.sendMetric(Metric{
+			Name:      "traefik",
+			Title:     fmt.Sprintf("Traefik Open Connections %s - %s", entrypoint, s.hostname),
+			Cause:     fmt.Sprintf("%.0f open connections", value),
+			AlertID:   fmt.Sprintf("traefik-connections-%s-%s", valueName(entrypoint), s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     s.config.TraefikConnectionsLimit,
+			Unit:      UnitCount,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, value, 0),
+			Labels:    map[string]string{"entrypoint": entrypoint},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TwilioSink sends SMS messages for critical alerts only, so on-call
+// phones are not woken up by warnings or recoveries.
+type TwilioSink struct {
+	httpClient *http.Client
+	accountSID string
+	authToken  string
+	from       string
+	to         []string
+	log        *Logger
+
+	// Recipients already texted about the latest event of each alert, so
+	// redelivering a partly failed SMS doesn't text them again
+	mu     sync.Mutex
+	texted map[string]textedEvent
+}
+
+type textedEvent struct {
+	eventID    string
+	recipients map[string]bool
+}
+
+func NewTwilioSink(accountSID, authToken, from, to string) (*TwilioSink, error) {
+	if authToken == "" {
+		return nil, fmt.Errorf("Twilio auth token is required")
+	}
+	if from == "" {
+		return nil, fmt.Errorf("Twilio from number is required")
+	}
+
+	var recipients []string
+	for _, number := range strings.Split(to, ",") {
+		if number = strings.TrimSpace(number); number != "" {
+			recipients = append(recipients, number)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("at least one Twilio recipient number is required")
+	}
+
+	return &TwilioSink{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		to:         recipients,
+		log:        New(),
+		texted:     map[string]textedEvent{},
+	}, nil
+}
+
+func (t *TwilioSink) Name() string {
+	return "twilio"
+}
+
+// Accepts only critical failures.
+func (t *TwilioSink) Accepts(metric Metric) bool {
+	return metric.Status == "fail" && metric.Severity == SeverityCritical
+}
+
+func (t *TwilioSink) Send(metric Metric) error {
+	endpoint := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", url.PathEscape(t.accountSID))
+	body := formatMetricText(metric)
+
+	var failed []string
+	for _, to := range t.to {
+		if t.wasTexted(metric, to) {
+			continue
+		}
+
+		form := url.Values{}
+		form.Set("From", t.from)
+		form.Set("To", to)
+		form.Set("Body", body)
+
+		req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %v", err)
+		}
+
+		req.SetBasicAuth(t.accountSID, t.authToken)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+		// The other recipients are still texted when one fails
+		if err := deliver(t.httpClient, req, t.Name(), t.log); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", to, err))
+			continue
+		}
+		t.markTexted(metric, to)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to send SMS to %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// wasTexted reports whether the recipient already got the SMS of the
+// event, in an earlier partly failed delivery.
+func (t *TwilioSink) wasTexted(metric Metric, to string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	event, ok := t.texted[metric.AlertID]
+	return ok && event.eventID == metric.EventID && event.recipients[to]
+}
+
+// markTexted remembers that the recipient got the SMS of the event. Only
+// the latest event of each alert is kept.
+func (t *TwilioSink) markTexted(metric Metric, to string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	event, ok := t.texted[metric.AlertID]
+	if !ok || event.eventID != metric.EventID {
+		event = textedEvent{eventID: metric.EventID, recipients: map[string]bool{}}
+		t.texted[metric.AlertID] = event
+	}
+	event.recipients[to] = true
+}
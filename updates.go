@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// pendingUpdates returns the number of pending package updates and how
+// many of them are security updates. It does not refresh the package
+// lists, that is left to the distribution's update timer.
+func pendingUpdates(ctx context.Context) (total, security int, err error) {
+	if _, err := exec.LookPath("apt-get"); err == nil {
+		return pendingAptUpdates(ctx)
+	}
+	if _, err := exec.LookPath("dnf"); err == nil {
+		return pendingDnfUpdates(ctx)
+	}
+	return 0, 0, fmt.Errorf("no supported package manager found (apt-get, dnf)")
+}
+
+// pendingAptUpdates simulates an upgrade, which lists every package as
+// "Inst bash [5.1-6] (5.1-6ubuntu1.1 Ubuntu:22.04/jammy-security [amd64])".
+func pendingAptUpdates(ctx context.Context) (total, security int, err error) {
+	output, err := runCommand(ctx, "apt-get", "-s", "-o", "Debug::NoLocking=true", "upgrade")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if !strings.HasPrefix(line, "Inst ") {
+			continue
+		}
+		total++
+		if strings.Contains(line, "-security") {
+			security++
+		}
+	}
+	return total, security, nil
+}
+
+func pendingDnfUpdates(ctx context.Context) (total, security int, err error) {
+	output, err := runCommand(ctx, "dnf", "-q", "list", "--upgrades")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if len(strings.Fields(line)) == 3 {
+			total++
+		}
+	}
+
+	output, err = runCommand(ctx, "dnf", "-q", "updateinfo", "list", "--security")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if len(strings.Fields(line)) >= 3 {
+			security++
+		}
+	}
+	return total, security, nil
+}
+
+// checkUpdates queries the package manager at most once per
+// UpdatesInterval, as resolving updates is too slow for every cycle.
+func (s *SystemMonitor) checkUpdates(ctx context.Context) error {
+	if time.Since(s.updatesAt) < s.config.UpdatesInterval {
+		return nil
+	}
+	s.updatesAt = time.Now()
+
+	total, security, err := pendingUpdates(ctx)
+	if err != nil {
+		return err
+	}
+	s.log.Log("Pending updates: %d, security: %d", total, security)
+
+	counts := []struct {
+		kind  string
+		title string
+		value float64
+		limit float64
+	}{
+		{"updates", "Pending Updates", float64(total), s.config.UpdatesLimit},
+		{"security-updates", "Pending Security Updates", float64(security), s.config.SecurityUpdatesLimit},
+	}
+	for _, count := range counts {
+		s.recordValue(valueName(count.kind, "pending"), count.value)
+		status := s.getStatus(count.value, count.limit)
+		if status == "fail" {
+			s.log.Warn("%s %.0f exceed limit of %.0f", count.title, count.value, count.limit)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "updates",
+			Title:     fmt.Sprintf("%s - %s", count.title, s.hostname),
+			Cause:     fmt.Sprintf("%d pending updates, %d of them security updates", total, security),
+			AlertID:   fmt.Sprintf("%s-%s", count.kind, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     count.value,
+			Limit:     count.limit,
+			Unit:      UnitCount,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, count.value, 0),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UPS drivers
+const (
+	upsNUT     = "nut"
+	upsApcupsd = "apcupsd"
+)
+
+// UPS is a UPS polled with upsc (Network UPS Tools) or apcaccess
+// (apcupsd). Target is the "<ups>[@<host>[:<port>]]" of upsc or the
+// "<host>:<port>" of apcaccess.
+type UPS struct {
+	Name   string
+	Driver string
+	Target string
+}
+
+// ParseUPS parses "[<name>=]<ups>[@<host>[:<port>]]" for a UPS of NUT,
+// e.g. "rack=eaton@10.0.0.2", or "[<name>=]apcupsd[@<host>[:<port>]]"
+// for apcupsd. The name defaults to the UPS.
+func ParseUPS(value string) (UPS, error) {
+	ups := UPS{Driver: upsNUT}
+	if name, rest, ok := strings.Cut(value, "="); ok {
+		ups.Name = strings.TrimSpace(name)
+		value = rest
+	}
+	value = strings.TrimSpace(value)
+	device, host, remote := strings.Cut(value, "@")
+	if device == "" || remote && host == "" {
+		return UPS{}, fmt.Errorf("expected [<name>=]<ups>[@<host>[:<port>]] or [<name>=]apcupsd[@<host>[:<port>]]")
+	}
+	if _, port, ok := strings.Cut(host, ":"); ok {
+		number, err := strconv.Atoi(port)
+		if err != nil || number < 1 || number > 65535 {
+			return UPS{}, fmt.Errorf("invalid port %q", port)
+		}
+	}
+
+	ups.Target = value
+	if device == upsApcupsd {
+		ups.Driver = upsApcupsd
+		ups.Target = host
+		if host == "" {
+			ups.Target = "localhost"
+		}
+		if !strings.Contains(ups.Target, ":") {
+			ups.Target += ":3551"
+		}
+	}
+	if ups.Name == "" {
+		ups.Name = device
+	}
+	return ups, nil
+}
+
+// upsState is what a UPS reported in one cycle. Charge, load and runtime
+// are -1 when the UPS doesn't report them.
+type upsState struct {
+	Status     string
+	OnBattery  bool
+	LowBattery bool
+	Replace    bool
+	Charge     float64
+	Load       float64
+	Runtime    float64 // seconds
+}
+
+// parseUPSValue parses the number of "100.0 Percent" or "1800", or
+// returns -1.
+func parseUPSValue(value string) float64 {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return -1
+	}
+	number, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return -1
+	}
+	return number
+}
+
+// parseNUTStatus parses the "<variable>: <value>" lines of upsc. The
+// ups.status flags are e.g. "OL CHRG" online and charging, "OB DISCHRG"
+// on battery, "LB" low battery and "RB" replace battery.
+func parseNUTStatus(output []byte) upsState {
+	variables := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			variables[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	state := upsState{
+		Status:  variables["ups.status"],
+		Charge:  parseUPSValue(variables["battery.charge"]),
+		Load:    parseUPSValue(variables["ups.load"]),
+		Runtime: parseUPSValue(variables["battery.runtime"]),
+	}
+	for _, flag := range strings.Fields(state.Status) {
+		switch flag {
+		case "OB":
+			state.OnBattery = true
+		case "LB":
+			state.LowBattery = true
+		case "RB":
+			state.Replace = true
+		}
+	}
+	return state
+}
+
+// parseApcupsdStatus parses the "<KEY> : <value>" lines of apcaccess, e.g.
+// "STATUS   : ONBATT LOWBATT" or "BCHARGE  : 100.0 Percent".
+func parseApcupsdStatus(output []byte) upsState {
+	variables := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			variables[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	state := upsState{
+		Status:  variables["STATUS"],
+		Charge:  parseUPSValue(variables["BCHARGE"]),
+		Load:    parseUPSValue(variables["LOADPCT"]),
+		Runtime: parseUPSValue(variables["TIMELEFT"]),
+	}
+	if state.Runtime >= 0 {
+		state.Runtime *= 60
+	}
+	for _, flag := range strings.Fields(state.Status) {
+		switch flag {
+		case "ONBATT":
+			state.OnBattery = true
+		case "LOWBATT":
+			state.LowBattery = true
+		case "REPLACEBATT":
+			state.Replace = true
+		}
+	}
+	return state
+}
+
+// readUPS polls a UPS through the daemon of its driver.
+func readUPS(ctx context.Context, ups UPS) (upsState, error) {
+	if ups.Driver == upsApcupsd {
+		output, err := runCommand(ctx, "apcaccess", "-h", ups.Target, "status")
+		if err != nil {
+			return upsState{}, err
+		}
+		state := parseApcupsdStatus(output)
+		if state.Status == "" {
+			return upsState{}, fmt.Errorf("apcaccess reported no status")
+		}
+		return state, nil
+	}
+
+	output, err := runCommand(ctx, "upsc", ups.Target)
+	if err != nil {
+		return upsState{}, err
+	}
+	state := parseNUTStatus(output)
+	if state.Status == "" {
+		return upsState{}, fmt.Errorf("upsc reported no ups.status")
+	}
+	return state, nil
+}
+
+// checkUPS alerts when a UPS runs on battery, its charge drops below
+// UPSBatteryLimit or its load exceeds UPSLoadLimit. A UPS that can't be
+// read fails its power check, the host may be about to lose power
+// without warning.
+func (s *SystemMonitor) checkUPS(ctx context.Context) error {
+	for _, ups := range s.config.UPS {
+		labels := map[string]string{"ups": ups.Name, "driver": ups.Driver}
+
+		state, readErr := readUPS(ctx, ups)
+		power, powerValue := "pass", 0.0
+		cause := fmt.Sprintf("UPS %s is online (%s)", ups.Name, state.Status)
+		switch {
+		case readErr != nil:
+			power, powerValue = "fail", 1
+			cause = fmt.Sprintf("Failed to read UPS %s: %v", ups.Name, readErr)
+		case state.OnBattery:
+			power, powerValue = "fail", 1
+			cause = fmt.Sprintf("UPS %s is on battery (%s), %.0f%% charge, %.0f minutes left", ups.Name, state.Status, state.Charge, state.Runtime/60)
+			if state.LowBattery {
+				cause += ", battery low"
+			}
+		case state.Replace:
+			cause = fmt.Sprintf("UPS %s is online (%s), battery needs replacing", ups.Name, state.Status)
+		}
+		if power == "fail" {
+			s.log.Warn("%s", cause)
+		} else {
+			s.log.Log("UPS %s: %s, %.0f%% charge, %.0f%% load, %.0f minutes runtime", ups.Name, state.Status, state.Charge, state.Load, state.Runtime/60)
+		}
+		s.recordValue(valueName("ups", ups.Name, "on_battery"), powerValue)
+
+		if err := s.sendMetric(Metric{
+			Name:      "ups",
+			Title:     fmt.Sprintf("UPS %s Power - %s", ups.Name, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("ups-power-%s-%s", ups.Name, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    power,
+			Value:     powerValue,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(power, powerValue, 0),
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+		if readErr != nil {
+			continue
+		}
+
+		if state.Runtime >= 0 {
+			s.recordValue(valueName("ups", ups.Name, "runtime_seconds"), state.Runtime)
+		}
+
+		type upsCheck struct {
+			kind   string
+			title  string
+			cause  string
+			status string
+			value  float64
+			limit  float64
+		}
+		var checks []upsCheck
+		if state.Charge >= 0 {
+			s.recordValue(valueName("ups", ups.Name, "charge_percent"), state.Charge)
+			// A low charge is a problem, unlike most values
+			status := "pass"
+			if state.Charge < s.config.UPSBatteryLimit || state.LowBattery {
+				status = "fail"
+			}
+			checks = append(checks, upsCheck{
+				kind:   "battery",
+				title:  "Battery",
+				cause:  fmt.Sprintf("UPS %s battery at %.0f%%, %.0f minutes runtime", ups.Name, state.Charge, state.Runtime/60),
+				status: status,
+				value:  state.Charge,
+				limit:  s.config.UPSBatteryLimit,
+			})
+		}
+		if state.Load >= 0 {
+			s.recordValue(valueName("ups", ups.Name, "load_percent"), state.Load)
+			checks = append(checks, upsCheck{
+				kind:   "load",
+				title:  "Load",
+				cause:  fmt.Sprintf("UPS %s load at %.0f%% of its capacity", ups.Name, state.Load),
+				status: s.getStatus(state.Load, s.config.UPSLoadLimit),
+				value:  state.Load,
+				limit:  s.config.UPSLoadLimit,
+			})
+		}
+
+		for _, check := range checks {
+			if check.status == "fail" {
+				s.log.Warn("%s", check.cause)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "ups",
+				Title:     fmt.Sprintf("UPS %s %s - %s", ups.Name, check.title, s.hostname),
+				Cause:     check.cause,
+				AlertID:   fmt.Sprintf("ups-%s-%s-%s", check.kind, ups.Name, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    check.status,
+				Value:     check.value,
+				Limit:     check.limit,
+				Unit:      UnitPercent,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(check.status, check.value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
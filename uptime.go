@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// uptimeWindows are the rolling windows availability is calculated over.
+var uptimeWindows = []struct {
+	name   string
+	length time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+type uptimeInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// uptimeRecord is the pass/fail history of an alert: the current state
+// and the failures of the longest window.
+type uptimeRecord struct {
+	FirstSeen time.Time        `json:"first_seen"`
+	Failing   bool             `json:"failing"`
+	Since     time.Time        `json:"since"`
+	Failures  []uptimeInterval `json:"failures"`
+}
+
+// UptimeStatus is the availability of an alert for the API, in percent
+// per window.
+type UptimeStatus struct {
+	AlertID      string             `json:"alert_id"`
+	Failing      bool               `json:"failing"`
+	Since        time.Time          `json:"since"`
+	Availability map[string]float64 `json:"availability"`
+}
+
+// uptimeTracker records since when each alert passes or fails, to
+// calculate availability over rolling windows. With a path, records are
+// kept across restarts.
+type uptimeTracker struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]*uptimeRecord
+}
+
+func newUptimeTracker(path string) *uptimeTracker {
+	return &uptimeTracker{
+		path:    path,
+		records: map[string]*uptimeRecord{},
+	}
+}
+
+// Observe records the status of a metric.
+func (t *uptimeTracker) Observe(metric Metric, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failing := metric.Status == "fail"
+	record, ok := t.records[metric.AlertID]
+	if !ok {
+		t.records[metric.AlertID] = &uptimeRecord{FirstSeen: at, Failing: failing, Since: at}
+		return
+	}
+	if failing == record.Failing {
+		return
+	}
+	if record.Failing {
+		record.Failures = append(record.Failures, uptimeInterval{Start: record.Since, End: at})
+	}
+	record.Failing = failing
+	record.Since = at
+
+	// Failures that ended before the longest window no longer count
+	cutoff := at.Add(-uptimeWindows[len(uptimeWindows)-1].length)
+	for len(record.Failures) > 0 && record.Failures[0].End.Before(cutoff) {
+		record.Failures = record.Failures[1:]
+	}
+}
+
+// availability returns the percentage of the window the alert passed,
+// counting from when it was first seen if that is later.
+func (r *uptimeRecord) availability(window time.Duration, now time.Time) float64 {
+	start := now.Add(-window)
+	if r.FirstSeen.After(start) {
+		start = r.FirstSeen
+	}
+	observed := now.Sub(start)
+	if observed <= 0 {
+		return 100
+	}
+
+	failures := r.Failures
+	if r.Failing {
+		failures = append(failures[:len(failures):len(failures)], uptimeInterval{Start: r.Since, End: now})
+	}
+	var down time.Duration
+	for _, failure := range failures {
+		from, to := failure.Start, failure.End
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(from) {
+			down += to.Sub(from)
+		}
+	}
+	return 100 * (1 - float64(down)/float64(observed))
+}
+
+// List returns the availability of every alert, least available over the
+// longest window first.
+func (t *uptimeTracker) List(now time.Time) []UptimeStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := make([]UptimeStatus, 0, len(t.records))
+	for id, record := range t.records {
+		status := UptimeStatus{
+			AlertID:      id,
+			Failing:      record.Failing,
+			Since:        record.Since,
+			Availability: map[string]float64{},
+		}
+		for _, window := range uptimeWindows {
+			status.Availability[window.name] = record.availability(window.length, now)
+		}
+		list = append(list, status)
+	}
+
+	longest := uptimeWindows[len(uptimeWindows)-1].name
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Availability[longest] != list[j].Availability[longest] {
+			return list[i].Availability[longest] < list[j].Availability[longest]
+		}
+		return list[i].AlertID < list[j].AlertID
+	})
+	return list
+}
+
+// Load restores the records saved by Save.
+func (t *uptimeTracker) Load() error {
+	if t.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return err
+	}
+
+	records := map[string]*uptimeRecord{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, record := range records {
+		if record != nil {
+			t.records[id] = record
+		}
+	}
+	return nil
+}
+
+// Save writes the records to the uptime file.
+func (t *uptimeTracker) Save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	data, err := json.Marshal(t.records)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(t.path, data)
+}
+
+// GET /uptime
+func (a *APIServer) handleUptime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, a.monitor.uptime.List(time.Now()))
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type wireguardPeer struct {
+	Interface  string
+	PublicKey  string
+	Endpoint   string
+	AllowedIPs string
+	Handshake  time.Time
+	Received   float64
+	Sent       float64
+	Keepalive  bool
+}
+
+// Name identifies the peer by interface and the start of its public key,
+// like "wg show" abbreviates keys.
+func (p wireguardPeer) Name() string {
+	key := p.PublicKey
+	if len(key) > 8 {
+		key = key[:8]
+	}
+	return p.Interface + "-" + key
+}
+
+// wireguardPeers parses "wg show all dump". Interface lines have 5 fields,
+// peer lines 9: interface, public key, preshared key, endpoint, allowed
+// ips, latest handshake, received, sent and persistent keepalive.
+func wireguardPeers(ctx context.Context) ([]wireguardPeer, error) {
+	output, err := runCommand(ctx, "wg", "show", "all", "dump")
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []wireguardPeer
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 9 {
+			continue
+		}
+		peer := wireguardPeer{
+			Interface:  fields[0],
+			PublicKey:  fields[1],
+			Endpoint:   fields[3],
+			AllowedIPs: fields[4],
+			Keepalive:  fields[8] != "off" && fields[8] != "0",
+		}
+		if handshake, _ := strconv.ParseInt(fields[5], 10, 64); handshake > 0 {
+			peer.Handshake = time.Unix(handshake, 0)
+		}
+		peer.Received, _ = strconv.ParseFloat(fields[6], 64)
+		peer.Sent, _ = strconv.ParseFloat(fields[7], 64)
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
+// checkWireGuard alerts when a peer with persistent keepalive has not
+// completed a handshake within WireGuardHandshakeLimit, or sent traffic
+// during a cycle without receiving any. Peers without keepalive, such as
+// road warrior clients, are expected to go idle and are only logged.
+func (s *SystemMonitor) checkWireGuard(ctx context.Context) error {
+	peers, err := wireguardPeers(ctx)
+	if err != nil {
+		return err
+	}
+
+	previous := s.wireguardCounters
+	s.wireguardCounters = map[string][2]float64{}
+
+	for _, peer := range peers {
+		name := peer.Name()
+		s.wireguardCounters[name] = [2]float64{peer.Received, peer.Sent}
+
+		age := -1.0
+		if !peer.Handshake.IsZero() {
+			age = time.Since(peer.Handshake).Seconds()
+			s.recordValue(valueName("wireguard", name, "handshake_age_seconds"), age)
+		}
+		if !peer.Keepalive {
+			s.log.Log("WireGuard peer %s (%s): last handshake %s", name, peer.AllowedIPs, formatHandshake(peer.Handshake))
+			continue
+		}
+		labels := map[string]string{"interface": peer.Interface, "peer": peer.PublicKey, "endpoint": peer.Endpoint}
+
+		type check struct {
+			kind   string
+			title  string
+			cause  string
+			status string
+			value  float64
+			limit  float64
+			unit   string
+		}
+		limit := s.config.WireGuardHandshakeLimit.Seconds()
+		status := "pass"
+		if age < 0 || age > limit {
+			status = "fail"
+		}
+		checks := []check{
+			{"handshake", "Handshake", fmt.Sprintf("Last handshake with %s (%s): %s", peer.Endpoint, peer.AllowedIPs, formatHandshake(peer.Handshake)), status, age, limit, UnitSeconds},
+		}
+
+		// Sending without receiving means the other end is gone, even if
+		// the last handshake is still recent
+		if counters, ok := previous[name]; ok && peer.Received >= counters[0] && peer.Sent >= counters[1] {
+			received := peer.Received - counters[0]
+			sent := peer.Sent - counters[1]
+			status := "pass"
+			if sent > 0 && received == 0 {
+				status = "fail"
+			}
+			checks = append(checks, check{"traffic", "Traffic", fmt.Sprintf("Received %.0f bytes and sent %.0f bytes since the previous check", received, sent), status, received, 0, UnitBytes})
+		}
+
+		for _, check := range checks {
+			if check.status == "fail" {
+				s.log.Warn("WireGuard peer %s: %s", name, check.cause)
+			} else {
+				s.log.Log("WireGuard peer %s: %s", name, check.cause)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "wireguard",
+				Title:     fmt.Sprintf("WireGuard Peer %s %s - %s", name, check.title, s.hostname),
+				Cause:     check.cause,
+				AlertID:   fmt.Sprintf("wireguard-%s-%s-%s", check.kind, name, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    check.status,
+				Value:     check.value,
+				Limit:     check.limit,
+				Unit:      check.unit,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(check.status, check.value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatHandshake(handshake time.Time) string {
+	if handshake.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s ago", time.Since(handshake).Round(time.Second))
+}
+
+// openVPNState asks the OpenVPN management interface, a TCP address or
+// unix socket, for the connection state, e.g.
+// "1700000000,CONNECTED,SUCCESS,10.8.0.2,203.0.113.1,1194,,".
+func openVPNState(address string) (string, string, error) {
+	network := "tcp"
+	if strings.HasPrefix(address, "/") {
+		network = "unix"
+	}
+	conn, err := net.DialTimeout(network, address, 5*time.Second)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to connect to management interface: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return "", "", err
+	}
+	if _, err := conn.Write([]byte("state\n")); err != nil {
+		return "", "", fmt.Errorf("failed to write to management interface: %v", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Real-time notifications such as the greeting start with ">"
+		if strings.HasPrefix(line, ">") || line == "" {
+			continue
+		}
+		if line == "END" || strings.HasPrefix(line, "ERROR") {
+			break
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) >= 4 {
+			return fields[1], fields[3], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", fmt.Errorf("failed to read from management interface: %v", err)
+	}
+	return "", "", fmt.Errorf("no state returned by management interface")
+}
+
+func (s *SystemMonitor) checkOpenVPN(ctx context.Context) error {
+	state, address, err := openVPNState(s.config.OpenVPNManagement)
+	if err != nil {
+		return err
+	}
+
+	status := "pass"
+	value := 0.0
+	cause := fmt.Sprintf("OpenVPN is %s with address %s", state, address)
+	if state != "CONNECTED" {
+		status = "fail"
+		value = 1
+		cause = fmt.Sprintf("OpenVPN is %s", state)
+		s.log.Warn("%s", cause)
+	} else {
+		s.log.Log("%s", cause)
+	}
+	s.recordValue("openvpn.connected", 1-value)
+
+	return s.sendMetric(Metric{
+		Name:      "openvpn",
+		Title:     fmt.Sprintf("OpenVPN Tunnel - %s", s.hostname),
+		Cause:     cause,
+		AlertID:   fmt.Sprintf("openvpn-%s", s.hostname),
+		Timestamp: time.Now().Unix(),
+		Status:    status,
+		Value:     value,
+		Limit:     0,
+		Unit:      UnitBoolean,
+		Type:      TypeGauge,
+		Severity:  s.getSeverity(status, value, 0),
+	})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webServerStatus is what nginx stub_status and Apache mod_status have in
+// common. Workers is the share of busy workers, Apache only.
+type webServerStatus struct {
+	Connections float64
+	Requests    float64
+	Workers     float64
+	HasWorkers  bool
+}
+
+type requestSample struct {
+	requests float64
+	at       time.Time
+}
+
+func fetchStatusPage(ctx context.Context, client *http.Client, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "Appwrite Resource Monitoring")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status from %s: %s", url, resp.Status)
+	}
+	return string(body), nil
+}
+
+// parseNginxStatus parses the stub_status page:
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func parseNginxStatus(page string) (*webServerStatus, error) {
+	lines := strings.Split(strings.TrimSpace(page), "\n")
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "Active connections:") {
+		return nil, fmt.Errorf("unexpected stub_status page")
+	}
+
+	connections, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(lines[0], "Active connections:")), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid active connections: %v", err)
+	}
+	counters := strings.Fields(lines[2])
+	if len(counters) != 3 {
+		return nil, fmt.Errorf("unexpected stub_status counters %q", lines[2])
+	}
+	requests, err := strconv.ParseFloat(counters[2], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request counter: %v", err)
+	}
+
+	return &webServerStatus{Connections: connections, Requests: requests}, nil
+}
+
+// parseApacheStatus parses the machine readable mod_status page
+// (server-status?auto) with "Key: value" lines.
+func parseApacheStatus(page string) (*webServerStatus, error) {
+	values := map[string]float64{}
+	for _, line := range strings.Split(page, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if number, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			values[strings.TrimSpace(key)] = number
+		}
+	}
+
+	busy, ok := values["BusyWorkers"]
+	if !ok {
+		return nil, fmt.Errorf("unexpected server-status page, is ?auto missing?")
+	}
+	status := &webServerStatus{
+		Requests:    values["Total Accesses"],
+		Connections: busy,
+		HasWorkers:  true,
+	}
+	// ConnsTotal is only reported by the event MPM
+	if connections, ok := values["ConnsTotal"]; ok {
+		status.Connections = connections
+	}
+	if total := busy + values["IdleWorkers"]; total > 0 {
+		status.Workers = busy / total * 100
+	}
+	return status, nil
+}
+
+func (s *SystemMonitor) checkWebServers(ctx context.Context) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if s.requestSamples == nil {
+		s.requestSamples = map[string]requestSample{}
+	}
+
+	servers := []struct {
+		name  string
+		title string
+		url   string
+		parse func(string) (*webServerStatus, error)
+	}{
+		{"nginx", "Nginx", s.config.NginxStatusURL, parseNginxStatus},
+		{"apache", "Apache", s.config.ApacheStatusURL, parseApacheStatus},
+	}
+
+	for _, server := range servers {
+		if server.url == "" {
+			continue
+		}
+
+		page, err := fetchStatusPage(ctx, client, server.url)
+		var status *webServerStatus
+		if err == nil {
+			status, err = server.parse(page)
+		}
+		if err != nil {
+			s.log.Warn("%s status page failed: %v", server.title, err)
+			if err := s.sendMetric(Metric{
+				Name:      server.name,
+				Title:     fmt.Sprintf("%s Status - %s", server.title, s.hostname),
+				Cause:     fmt.Sprintf("Failed to read status page: %v", err),
+				AlertID:   fmt.Sprintf("%s-status-%s", server.name, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    "fail",
+				Unit:      UnitBoolean,
+				Type:      TypeGauge,
+				Severity:  SeverityCritical,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The request counter resets on restart
+		now := time.Now()
+		rate := 0.0
+		if previous, ok := s.requestSamples[server.name]; ok && status.Requests >= previous.requests {
+			rate = (status.Requests - previous.requests) / now.Sub(previous.at).Seconds()
+		}
+		s.requestSamples[server.name] = requestSample{requests: status.Requests, at: now}
+
+		s.recordValue(valueName(server.name, "connections"), status.Connections)
+		s.recordValue(valueName(server.name, "requests_per_second"), rate)
+		cause := fmt.Sprintf("%.0f active connections, %.1f requests/s", status.Connections, rate)
+		if status.HasWorkers {
+			s.recordValue(valueName(server.name, "workers_percent"), status.Workers)
+			cause += fmt.Sprintf(", %.0f%% of workers busy", status.Workers)
+		}
+		s.log.Log("%s: %s", server.title, cause)
+
+		type check struct {
+			kind  string
+			title string
+			value float64
+			limit float64
+			unit  string
+		}
+		checks := []check{
+			{"connections", "Connections", status.Connections, s.config.WebConnectionsLimit, UnitCount},
+			{"requests", "Request Rate", rate, s.config.WebRequestRateLimit, UnitPerSecond},
+		}
+		if status.HasWorkers {
+			checks = append(checks, check{"workers", "Worker Saturation", status.Workers, s.config.WebWorkersLimit, UnitPercent})
+		}
+
+		for _, check := range checks {
+			if check.limit <= 0 {
+				continue
+			}
+			result := s.getStatus(check.value, check.limit)
+			if result == "fail" {
+				s.log.Warn("%s %s %.2f exceeds limit of %.2f", server.title, check.kind, check.value, check.limit)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      server.name,
+				Title:     fmt.Sprintf("%s %s - %s", server.title, check.title, s.hostname),
+				Cause:     cause,
+				AlertID:   fmt.Sprintf("%s-%s-%s", server.name, check.kind, s.hostname),
+				Timestamp: now.Unix(),
+				Status:    result,
+				Value:     check.value,
+				Limit:     check.limit,
+				Unit:      check.unit,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(result, check.value, 0),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
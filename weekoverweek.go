@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WeekOverWeek compares the values matching Pattern with the same hour
+// last week and alerts when they are Factor times higher or lower.
+type WeekOverWeek struct {
+	Pattern string
+	Factor  float64
+}
+
+// ParseWeekOverWeek parses "<value>[:<factor>]" where value may contain
+// glob patterns, e.g. "http.*.latency_ms:5".
+func ParseWeekOverWeek(value string, defaultFactor float64) (WeekOverWeek, error) {
+	pattern, factor, found := strings.Cut(value, ":")
+	comparison := WeekOverWeek{Pattern: strings.TrimSpace(pattern), Factor: defaultFactor}
+	if comparison.Pattern == "" {
+		return WeekOverWeek{}, fmt.Errorf("expected <value>[:<factor>]")
+	}
+	if _, err := path.Match(comparison.Pattern, ""); err != nil {
+		return WeekOverWeek{}, fmt.Errorf("invalid pattern %q: %v", comparison.Pattern, err)
+	}
+	if found {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(factor), 64)
+		if err != nil || parsed <= 1 {
+			return WeekOverWeek{}, fmt.Errorf("invalid factor %q, expected a number greater than 1", factor)
+		}
+		comparison.Factor = parsed
+	}
+	return comparison, nil
+}
+
+// weekAgoAverages returns the average of every value during the same
+// local hour last week, which is not 168 hours ago across daylight saving
+// changes. The history is read once per hour.
+func (s *SystemMonitor) weekAgoAverages(now time.Time) (map[string]float64, error) {
+	weekAgo := now.AddDate(0, 0, -7)
+	from := time.Date(weekAgo.Year(), weekAgo.Month(), weekAgo.Day(), weekAgo.Hour(), 0, 0, 0, weekAgo.Location())
+	if s.weekAgo != nil && s.weekAgoFrom.Equal(from) {
+		return s.weekAgo, nil
+	}
+
+	entries, err := readHistory(s.config.HistoryFile, from, from.Add(time.Hour), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for _, entry := range entries {
+		for name, value := range entry.Values {
+			sums[name] += value
+			counts[name]++
+		}
+	}
+	averages := map[string]float64{}
+	for name, sum := range sums {
+		averages[name] = sum / float64(counts[name])
+	}
+
+	s.weekAgo = averages
+	s.weekAgoFrom = from
+	return averages, nil
+}
+
+// checkWeekOverWeek alerts on values deviating by more than their factor
+// from the same hour last week, catching regressions of new releases.
+// Values without history a week ago, or with an average of zero or
+// less, are skipped.
+func (s *SystemMonitor) checkWeekOverWeek(ctx context.Context) error {
+	weekAgo, err := s.weekAgoAverages(s.now())
+	if err != nil {
+		return err
+	}
+	values := s.collectedValues()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checked := map[string]bool{}
+	for _, comparison := range s.config.WeekOverWeek {
+		for _, name := range names {
+			if checked[name] {
+				continue
+			}
+			if ok, _ := path.Match(comparison.Pattern, name); !ok {
+				continue
+			}
+			baseline, found := weekAgo[name]
+			if !found || baseline <= 0 {
+				continue
+			}
+			checked[name] = true
+
+			current := values[name]
+			ratio := current / baseline
+
+			status := "pass"
+			severity := SeverityInfo
+			direction := "higher"
+			deviation := ratio
+			if ratio < 1 && ratio > 0 {
+				direction = "lower"
+				deviation = 1 / ratio
+			}
+			if deviation >= comparison.Factor || ratio <= 0 {
+				status = "fail"
+				severity = SeverityWarning
+			}
+			cause := fmt.Sprintf("%s is %.2f, %.1fx %s than %.2f at the same hour last week", name, current, deviation, direction, baseline)
+			if ratio <= 0 {
+				cause = fmt.Sprintf("%s is %.2f, compared to %.2f at the same hour last week", name, current, baseline)
+			}
+			if status == "fail" {
+				s.log.Warn("%s", cause)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "week-over-week",
+				Title:     fmt.Sprintf("Week-over-Week %s - %s", name, s.hostname),
+				Cause:     cause,
+				AlertID:   fmt.Sprintf("wow-%s-%s", valueName(name), s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     deviation,
+				Limit:     comparison.Factor,
+				Unit:      UnitRatio,
+				Type:      TypeGauge,
+				Severity:  severity,
+				Labels:    map[string]string{"value": name},
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
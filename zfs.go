@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type zfsPool struct {
+	Name          string
+	Health        string
+	Capacity      float64
+	Fragmentation float64
+}
+
+func listZFSPools(ctx context.Context) ([]zfsPool, error) {
+	output, err := runCommand(ctx, "zpool", "list", "-H", "-p", "-o", "name,health,capacity,fragmentation")
+	if err != nil {
+		return nil, err
+	}
+
+	var pools []zfsPool
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		pool := zfsPool{Name: fields[0], Health: fields[1]}
+		pool.Capacity, _ = strconv.ParseFloat(strings.TrimSuffix(fields[2], "%"), 64)
+		// Fragmentation is "-" for pools without spacemap histograms
+		pool.Fragmentation, _ = strconv.ParseFloat(strings.TrimSuffix(fields[3], "%"), 64)
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+// zfsScrub is the last scan of a pool in "zpool status". State is
+// "finished", "in progress", "paused" or "canceled", and empty when the
+// pool was never scrubbed or the last scan was a resilver.
+type zfsScrub struct {
+	State string
+	// When the scrub finished, started, was paused or canceled
+	At time.Time
+}
+
+// zfsScanStates are the scan lines of a scrub, followed by a time, e.g.
+// "scan: scrub repaired 0B in 00:01:23 with 0 errors on Sun Oct 11 00:25:24 2026"
+// or "scan: scrub in progress since Sun Oct 11 00:24:01 2026".
+var zfsScanStates = []struct {
+	prefix    string
+	separator string
+	state     string
+}{
+	{"scrub repaired", " on ", "finished"},
+	{"scrub in progress", " since ", "in progress"},
+	{"scrub paused", " since ", "paused"},
+	{"scrub canceled", " on ", "canceled"},
+}
+
+// lastZFSScrub parses the last scrub of a pool from "zpool status".
+func lastZFSScrub(ctx context.Context, pool string) (zfsScrub, error) {
+	output, err := runCommand(ctx, "zpool", "status", pool)
+	if err != nil {
+		return zfsScrub{}, err
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		scan := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "scan:"))
+		for _, state := range zfsScanStates {
+			if !strings.HasPrefix(scan, state.prefix) {
+				continue
+			}
+			index := strings.LastIndex(scan, state.separator)
+			if index < 0 {
+				continue
+			}
+			at, err := time.ParseInLocation("Mon Jan _2 15:04:05 2006", strings.TrimSpace(scan[index+len(state.separator):]), time.Local)
+			if err != nil {
+				return zfsScrub{}, fmt.Errorf("failed to parse scrub time: %v", err)
+			}
+			return zfsScrub{State: state.state, At: at}, nil
+		}
+	}
+	return zfsScrub{}, nil
+}
+
+func (s *SystemMonitor) checkZFS(ctx context.Context) error {
+	pools, err := listZFSPools(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		labels := map[string]string{"pool": pool.Name}
+
+		// Health
+		status := "pass"
+		severity := SeverityInfo
+		value := 0.0
+		if pool.Health != "ONLINE" {
+			status = "fail"
+			severity = SeverityCritical
+			value = 1
+			if pool.Health == "DEGRADED" {
+				severity = SeverityWarning
+			}
+			s.log.Warn("ZFS pool %s is %s", pool.Name, pool.Health)
+		} else {
+			s.log.Log("ZFS pool %s: %s, Capacity: %.0f%%, Fragmentation: %.0f%%", pool.Name, pool.Health, pool.Capacity, pool.Fragmentation)
+		}
+		s.recordValue(valueName("zfs", pool.Name, "healthy"), 1-value)
+
+		if err := s.sendMetric(Metric{
+			Name:      "zfs",
+			Title:     fmt.Sprintf("ZFS Pool %s Health - %s", pool.Name, s.hostname),
+			Cause:     fmt.Sprintf("Pool state: %s", pool.Health),
+			AlertID:   fmt.Sprintf("zfs-health-%s-%s", pool.Name, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     value,
+			Limit:     0,
+			Unit:      UnitBoolean,
+			Type:      TypeGauge,
+			Severity:  severity,
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+
+		// Capacity and fragmentation
+		usages := []struct {
+			kind  string
+			title string
+			value float64
+			limit float64
+		}{
+			{"capacity", "Capacity", pool.Capacity, s.config.ZFSCapacityLimit},
+			{"fragmentation", "Fragmentation", pool.Fragmentation, s.config.ZFSFragmentationLimit},
+		}
+		for _, usage := range usages {
+			s.recordValue(valueName("zfs", pool.Name, usage.kind+"_percent"), usage.value)
+			status := s.getStatus(usage.value, usage.limit)
+			if status == "fail" {
+				s.log.Warn("ZFS pool %s %s %.0f%% exceeds limit of %.0f%%", pool.Name, usage.kind, usage.value, usage.limit)
+			}
+
+			if err := s.sendMetric(Metric{
+				Name:      "zfs",
+				Title:     fmt.Sprintf("ZFS Pool %s %s - %s", pool.Name, usage.title, s.hostname),
+				Cause:     "ZFS monitoring check",
+				AlertID:   fmt.Sprintf("zfs-%s-%s-%s", usage.kind, pool.Name, s.hostname),
+				Timestamp: time.Now().Unix(),
+				Status:    status,
+				Value:     usage.value,
+				Limit:     usage.limit,
+				Unit:      UnitPercent,
+				Type:      TypeGauge,
+				Severity:  s.getSeverity(status, usage.value, 0),
+				Labels:    labels,
+			}); err != nil {
+				return err
+			}
+		}
+
+		// Last scrub
+		if s.config.ZFSScrubMaxAge <= 0 {
+			continue
+		}
+		scrub, err := lastZFSScrub(ctx, pool.Name)
+		if err != nil {
+			s.log.Error("Failed to get last scrub of ZFS pool %s: %v", pool.Name, err)
+			continue
+		}
+		// "zpool status" only shows the current scan, so the last completed
+		// scrub is remembered while the next one runs
+		if s.zfsScrubs == nil {
+			s.zfsScrubs = map[string]time.Time{}
+		}
+		if scrub.State == "finished" {
+			s.zfsScrubs[pool.Name] = scrub.At
+		}
+		scrubbed := s.zfsScrubs[pool.Name]
+
+		cause := "Pool has never been scrubbed"
+		age := 0.0
+		status = "fail"
+		switch {
+		case !scrubbed.IsZero():
+			cause = fmt.Sprintf("Last scrub finished at %s", scrubbed.Format("2006-01-02 15:04:05"))
+			if scrub.State != "finished" && scrub.State != "" {
+				cause += fmt.Sprintf(", scrub %s since %s", scrub.State, scrub.At.Format("2006-01-02 15:04:05"))
+			}
+			age = time.Since(scrubbed).Hours() / 24
+			status = s.getStatus(age, s.config.ZFSScrubMaxAge.Hours()/24)
+			s.recordValue(valueName("zfs", pool.Name, "scrub_age_days"), age)
+		case scrub.State == "in progress" || scrub.State == "paused":
+			cause = fmt.Sprintf("Scrub %s since %s", scrub.State, scrub.At.Format("2006-01-02 15:04:05"))
+			status = "pass"
+		case scrub.State == "canceled":
+			cause = fmt.Sprintf("Last scrub was canceled at %s", scrub.At.Format("2006-01-02 15:04:05"))
+		}
+		if status == "fail" {
+			s.log.Warn("ZFS pool %s: %s", pool.Name, cause)
+		}
+
+		if err := s.sendMetric(Metric{
+			Name:      "zfs",
+			Title:     fmt.Sprintf("ZFS Pool %s Scrub Age - %s", pool.Name, s.hostname),
+			Cause:     cause,
+			AlertID:   fmt.Sprintf("zfs-scrub-%s-%s", pool.Name, s.hostname),
+			Timestamp: time.Now().Unix(),
+			Status:    status,
+			Value:     age,
+			Limit:     s.config.ZFSScrubMaxAge.Hours() / 24,
+			Unit:      UnitDays,
+			Type:      TypeGauge,
+			Severity:  s.getSeverity(status, age, 0),
+			Labels:    labels,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}